@@ -0,0 +1,105 @@
+// inventory-ansible is an Ansible dynamic inventory script backed by the
+// inventory.  Settings may be given as flags or, since Ansible runs the
+// script with only --list or --host, as environment variables.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+	"github.com/spf13/viper"
+)
+
+const DefaultCacheTTL = 5 * time.Minute
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("inventory-ansible", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	list := fs.Bool("list", false, "print every group and host")
+	host := fs.String("host", "", "print the variables of a single host")
+	env := environment()
+	profile := fs.String("profile", env.GetString("profile"), "configuration profile to use")
+	networks := fs.String("networks", env.GetString("networks"), "comma separated list of networks to choose ansible_host from, in order of preference")
+	filter := fs.String("filter", env.GetString("filter"), "only include nodes matching role=,tag=,system= or host= terms")
+	cacheFile := fs.String("cache", env.GetString("cache"), "file to cache the inventory in, named after a hash of the profile, filter and networks, disabled if empty")
+	cacheTTL := fs.Duration("cache-ttl", env.GetDuration("cache_ttl"), "time the cached inventory is used for")
+	refresh := fs.Bool("refresh", false, "ignore the cached inventory")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *list == (*host != "") {
+		fmt.Fprintln(stderr, "inventory-ansible: exactly one of --list or --host is required")
+		return 2
+	}
+
+	nodeFilter, err := export.ParseNodeFilter(*filter)
+	if err != nil {
+		fmt.Fprintf(stderr, "inventory-ansible: %v\n", err)
+		return 2
+	}
+
+	if *refresh {
+		*cacheTTL = 0
+	}
+	data, err := export.CachedFile(export.CachePath(*cacheFile, *profile, *filter, *networks), *cacheTTL, func() ([]byte, error) {
+		inv, err := client.NewInventoryApiDefaultConfig(*profile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to inventory: %v", err)
+		}
+		snapshot, err := export.LoadSnapshot(inv)
+		if err != nil {
+			return nil, err
+		}
+		opts := &export.AnsibleOptions{Filter: nodeFilter}
+		if *networks != "" {
+			opts.Networks = strings.Split(*networks, ",")
+		}
+		return export.GenerateAnsible(snapshot, opts)
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "inventory-ansible: %v\n", err)
+		return 1
+	}
+
+	if *list {
+		stdout.Write(data)
+		return 0
+	}
+
+	inventory := &export.AnsibleInventory{}
+	if err := json.Unmarshal(data, inventory); err != nil {
+		fmt.Fprintf(stderr, "inventory-ansible: unable to read inventory: %v\n", err)
+		return 1
+	}
+	vars, err := json.MarshalIndent(inventory.Host(*host), "", "  ")
+	if err != nil {
+		fmt.Fprintf(stderr, "inventory-ansible: unable to marshal host vars: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s\n", vars)
+	return 0
+}
+
+// environment returns the flag defaults, read from INVENTORY_ANSIBLE_
+// prefixed environment variables
+func environment() *viper.Viper {
+	env := viper.New()
+	env.SetEnvPrefix("inventory_ansible")
+	env.AllowEmptyEnv(true)
+	env.AutomaticEnv()
+	env.BindEnv("profile", "INVENTORY_PROFILE")
+	env.SetDefault("cache", export.DefaultCacheFile("ansible.json"))
+	env.SetDefault("cache_ttl", DefaultCacheTTL)
+	return env
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+)
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"--list", "--host", "node-000"}, {"--list", "--filter", "colour=blue"}} {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		if code := run(args, stdout, stderr); code != 2 {
+			t.Errorf("expected usage error for %v, got %d: %s", args, code, stderr)
+		}
+	}
+}
+
+func TestRunHostFromCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ansible")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "ansible.json")
+	ioutil.WriteFile(export.CachePath(filename, "", "", ""), []byte(`{"_meta": {"hostvars": {"node-000": {"ansible_host": "10.0.0.10"}}}, "all": {"children": []}}`), 0600)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"--host", "node-000", "--cache", filename}, stdout, stderr); code != 0 {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if stdout.String() != "{\n  \"ansible_host\": \"10.0.0.10\"\n}\n" {
+		t.Errorf("got unexpected host vars: %s", stdout)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"--host", "missing", "--cache", filename}, stdout, stderr); code != 0 || stdout.String() != "{}\n" {
+		t.Errorf("expected empty vars for unknown host, got %d: %s", code, stdout)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/dns"
+)
+
+func main() {
+	profile := flag.String("profile", "", "inventory configuration profile")
+	listen := flag.String("listen", "127.0.0.1:5353", "address to answer udp and tcp queries on")
+	zones := flag.String("zones", "", "comma separated list of zones to serve, derived from the networks if unset")
+	ttl := flag.Duration("ttl", dns.DefaultTTL, "ttl of answers and negative responses")
+	refresh := flag.Duration("refresh", dns.DefaultRefreshInterval, "time between reloads of the inventory")
+	nameServer := flag.String("ns", "", "primary name server for SOA records")
+	hostmaster := flag.String("hostmaster", "", "responsible mailbox for SOA records")
+	flag.Parse()
+
+	inv, err := client.NewInventoryApiDefaultConfig(*profile)
+	if err != nil {
+		log.Fatalf("unable to connect to inventory: %v", err)
+	}
+
+	server := dns.NewServer(inv)
+	if *zones != "" {
+		server.Zones = strings.Split(*zones, ",")
+	}
+	server.TTL = *ttl
+	server.RefreshInterval = *refresh
+	server.NameServer = *nameServer
+	server.Hostmaster = *hostmaster
+	server.OnRefresh = func(err error) {
+		if err != nil {
+			log.Printf("unable to refresh inventory, serving previous data: %v", err)
+			return
+		}
+		logReservationsErr(server.View())
+	}
+
+	if err := server.Refresh(); err != nil {
+		log.Fatalf("unable to load inventory: %v", err)
+	}
+	logReservationsErr(server.View())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	go server.Run(ctx)
+	log.Printf("serving dns on %s", *listen)
+	if err := server.ListenAndServe(ctx, *listen); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// logReservationsErr reports a view loaded without some of its reservations
+func logReservationsErr(v *dns.View) {
+	if v != nil && v.ReservationsErr != nil {
+		log.Printf("serving without some reservations: %v", v.ReservationsErr)
+	}
+}
@@ -0,0 +1,139 @@
+// inventory-enc is a Puppet or Salt external node classifier backed by the
+// inventory.  It is run with a node's hostname or certificate name and
+// prints the node's classes, environment and parameters as YAML.  Node
+// configs are cached between runs, so classifying every node doesn't fetch
+// them all each time.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultMappingFile = "/etc/inventory/enc.yml"
+	DefaultCacheTTL    = 5 * time.Minute
+)
+
+var newInventoryApi = client.NewInventoryApiDefaultConfig
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("inventory-enc", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	env := environment()
+	profile := fs.String("profile", env.GetString("profile"), "configuration profile to use")
+	mapping := fs.String("mapping", env.GetString("mapping"), "class mapping file, ignored if the default doesn't exist")
+	format := fs.String("format", env.GetString("format"), "output format, puppet or salt")
+	cacheFile := fs.String("cache", env.GetString("cache"), "file to cache node configs in, named after a hash of the profile, disabled if empty")
+	cacheTTL := fs.Duration("cache-ttl", env.GetDuration("cache_ttl"), "time the cached node configs are used for")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: inventory-enc [flags] <hostname>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "puppet" && *format != "salt" {
+		fmt.Fprintf(stderr, "inventory-enc: unknown format %q\n", *format)
+		return 2
+	}
+
+	config := &export.ClassifierConfig{}
+	if _, err := os.Stat(*mapping); err == nil || *mapping != DefaultMappingFile {
+		config, err = export.LoadClassifierConfig(*mapping)
+		if err != nil {
+			fmt.Fprintf(stderr, "inventory-enc: %v\n", err)
+			return 1
+		}
+	}
+
+	// a node missing from the cache may have been added since it was written
+	filename := export.CachePath(*cacheFile, *profile)
+	var node *types.InventoryNode
+	for _, ttl := range []time.Duration{*cacheTTL, 0} {
+		nodes, err := loadNodes(*profile, filename, ttl)
+		if err != nil {
+			fmt.Fprintf(stderr, "inventory-enc: %v\n", err)
+			return 1
+		}
+		if node = export.FindNode(nodes, fs.Arg(0)); node != nil || ttl <= 0 {
+			break
+		}
+	}
+	if node == nil {
+		fmt.Fprintf(stderr, "inventory-enc: node %s not found\n", fs.Arg(0))
+		return 1
+	}
+
+	classification := config.Classify(node)
+	var data []byte
+	var err error
+	if *format == "salt" {
+		data, err = classification.Salt()
+	} else {
+		data, err = classification.Puppet()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "inventory-enc: unable to render classification: %v\n", err)
+		return 1
+	}
+	stdout.Write(data)
+	return 0
+}
+
+// loadNodes returns every node config, from the cache file if it was
+// written within ttl
+func loadNodes(profile, filename string, ttl time.Duration) ([]*types.InventoryNode, error) {
+	data, err := export.CachedFile(filename, ttl, func() ([]byte, error) {
+		inv, err := newInventoryApi(profile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to inventory: %v", err)
+		}
+		nodes, err := inv.NodeConfig().GetAll()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get node configs: %v", err)
+		}
+		return json.Marshal(nodes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*types.InventoryNode{}
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to read node configs: %v", err)
+	}
+	return nodes, nil
+}
+
+// environment returns the flag defaults, read from INVENTORY_ENC_ prefixed
+// environment variables
+func environment() *viper.Viper {
+	env := viper.New()
+	env.SetEnvPrefix("inventory_enc")
+	env.AllowEmptyEnv(true)
+	env.AutomaticEnv()
+	env.BindEnv("profile", "INVENTORY_PROFILE")
+	env.SetDefault("mapping", DefaultMappingFile)
+	env.SetDefault("format", "puppet")
+	env.SetDefault("cache", export.DefaultCacheFile("enc.json"))
+	env.SetDefault("cache_ttl", DefaultCacheTTL)
+	return env
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const testBaseUrl = "https://inventory.api.local/v0/"
+
+func init() {
+	// tests that use the cache give it a file
+	os.Setenv("INVENTORY_ENC_CACHE", "")
+	newInventoryApi = func(string) (*client.InventoryApi, error) {
+		baseUrl, _ := url.Parse(testBaseUrl)
+		return client.NewInventoryApi(baseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}), nil
+	}
+}
+
+func TestRun(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("nodeconfig").
+		Persist().
+		Reply(http.StatusOK).
+		BodyString(`[{"Hostname": "node-000", "InventoryID": "test-000", "Role": "worker", "Metadata": {"rack": "a1"}}]`)
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"-mapping", DefaultMappingFile, "node-000.example.com"}, stdout, stderr); code != 0 {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if output := stdout.String(); !strings.HasPrefix(output, "classes:\n  role::worker: {}\nparameters:\n") || !strings.Contains(output, "  rack: a1\n") {
+		t.Errorf("got unexpected output:\n%s", output)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"-format", "salt", "node-000"}, stdout, stderr); code != 0 || !strings.HasPrefix(stdout.String(), "classes:\n- role::worker\n") {
+		t.Errorf("got unexpected salt output %d:\n%s", code, stdout)
+	}
+
+	if code := run([]string{"node-001"}, stdout, stderr); code != 1 {
+		t.Errorf("expected failure for unknown node, got %d", code)
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "enc")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "enc.json")
+
+	// node configs are fetched once for both nodes, and again when a node
+	// is missing from the cache
+	gock.New(testBaseUrl).
+		Get("nodeconfig").
+		Reply(http.StatusOK).
+		BodyString(`[{"Hostname": "node-000", "InventoryID": "test-000", "Role": "worker"}, {"Hostname": "node-001", "InventoryID": "test-001", "Role": "login"}]`)
+	gock.New(testBaseUrl).
+		Get("nodeconfig").
+		Reply(http.StatusOK).
+		BodyString(`[{"Hostname": "node-002", "InventoryID": "test-002", "Role": "worker"}]`)
+
+	for _, host := range []string{"node-000", "node-001", "node-002"} {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		if code := run([]string{"-cache", cache, host}, stdout, stderr); code != 0 {
+			t.Fatalf("got exit code %d for %s: %s", code, host, stderr)
+		}
+	}
+	if !gock.IsDone() {
+		t.Errorf("node configs not reloaded for a missing node")
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{{}, {"a", "b"}, {"-format", "chef", "node-000"}} {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		if code := run(args, stdout, stderr); code != 2 {
+			t.Errorf("expected usage error for %v, got %d", args, code)
+		}
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := run([]string{"-mapping", "/nonexistent/enc.yml", "node-000"}, stdout, stderr); code != 1 {
+		t.Errorf("expected failure for a missing mapping file, got %d", code)
+	}
+}
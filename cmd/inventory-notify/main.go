@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/notify"
+)
+
+func main() {
+	configFile := flag.String("config", "/etc/inventory/notify.yml", "notifier configuration file")
+	flag.Parse()
+
+	cfg, err := notify.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inv, err := client.NewInventoryApiDefaultConfig(cfg.Profile)
+	if err != nil {
+		log.Fatalf("unable to connect to inventory: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	events := cfg.Watcher(inv).Start(ctx)
+	cfg.Notifier().Run(ctx, events)
+}
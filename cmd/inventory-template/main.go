@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/render"
+)
+
+// templateFlags collects repeated -template flags
+type templateFlags []*render.Template
+
+func (t *templateFlags) String() string {
+	specs := []string{}
+	for _, tmpl := range *t {
+		specs = append(specs, tmpl.Source+":"+tmpl.Destination)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (t *templateFlags) Set(spec string) error {
+	tmpl, err := render.ParseTemplate(spec)
+	if err != nil {
+		return err
+	}
+	*t = append(*t, tmpl)
+	return nil
+}
+
+func main() {
+	configFile := flag.String("config", "/etc/inventory/template.yml", "template configuration file, ignored if missing when -template is given")
+	profile := flag.String("profile", "", "inventory configuration profile, overrides the configuration file")
+	interval := flag.Duration("interval", 0, "time between polls of the inventory, overrides the configuration file")
+	once := flag.Bool("once", false, "render every template once and exit")
+	templates := templateFlags{}
+	flag.Var(&templates, "template", "source:destination[:command] template to render, may be repeated")
+	flag.Parse()
+
+	cfg := &render.Config{Interval: render.DefaultInterval}
+	if _, err := os.Stat(*configFile); err == nil || len(templates) == 0 {
+		if cfg, err = render.LoadConfig(*configFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	cfg.Templates = append(cfg.Templates, templates...)
+	if *profile != "" {
+		cfg.Profile = *profile
+	}
+	if *interval > 0 {
+		cfg.Interval = *interval
+	}
+	if len(cfg.Templates) == 0 {
+		log.Fatal("no templates configured")
+	}
+
+	inv, err := client.NewInventoryApiDefaultConfig(cfg.Profile)
+	if err != nil {
+		log.Fatalf("unable to connect to inventory: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	renderer := cfg.Renderer(inv)
+	renderer.OnRender = func(t *render.Template, changed bool, err error) {
+		if err != nil {
+			log.Printf("unable to render %s: %v", t.Destination, err)
+		} else if changed {
+			log.Printf("%s updated", t.Destination)
+		}
+	}
+	renderer.OnRefresh = func(err error) {
+		if err != nil {
+			log.Printf("unable to refresh templates: %v", err)
+		}
+	}
+
+	err = renderer.Refresh(ctx)
+	if *once {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("unable to refresh templates: %v", err)
+	}
+	renderer.Run(ctx)
+}
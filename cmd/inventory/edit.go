@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/printer"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// editOps adapts an object client for interactive editing
+type editOps struct {
+	New      func() interface{}
+	Get      func(inv *client.InventoryApi, id string) (interface{}, error)
+	Update   func(inv *client.InventoryApi, obj interface{}) error
+	ID       func(obj interface{}) string
+	Validate func(obj interface{}) error
+}
+
+func init() {
+	registerResource(&resource{
+		Name:        "edit",
+		Description: "edit objects in $EDITOR",
+		Commands: []*command{
+			editCommand("node", &editOps{
+				New: func() interface{} { return types.NewNode() },
+				Get: func(inv *client.InventoryApi, id string) (interface{}, error) { return inv.Node().Get(id) },
+				Update: func(inv *client.InventoryApi, obj interface{}) error {
+					node := obj.(*types.Node)
+					node.LastUpdated = time.Time{}
+					return inv.Node().Update(node)
+				},
+				ID:       func(obj interface{}) string { return obj.(*types.Node).ID() },
+				Validate: validateNode,
+			}),
+			editCommand("network", &editOps{
+				New: func() interface{} { return types.NewNetwork() },
+				Get: func(inv *client.InventoryApi, id string) (interface{}, error) { return inv.Network().Get(id) },
+				Update: func(inv *client.InventoryApi, obj interface{}) error {
+					network := obj.(*types.Network)
+					network.LastUpdated = time.Time{}
+					return inv.Network().Update(network)
+				},
+				ID:       func(obj interface{}) string { return obj.(*types.Network).ID() },
+				Validate: validateNetwork,
+			}),
+			editCommand("system", &editOps{
+				New: func() interface{} { return types.NewSystem() },
+				Get: func(inv *client.InventoryApi, id string) (interface{}, error) { return inv.System().Get(id) },
+				Update: func(inv *client.InventoryApi, obj interface{}) error {
+					system := obj.(*types.System)
+					system.LastUpdated = time.Time{}
+					return inv.System().Update(system)
+				},
+				ID:       func(obj interface{}) string { return obj.(*types.System).ID() },
+				Validate: validateSystem,
+			}),
+			editCommand("ipam", &editOps{
+				New: func() interface{} { return &types.IPReservation{} },
+				Get: func(inv *client.InventoryApi, id string) (interface{}, error) {
+					ip, err := parseIPArg(id)
+					if err != nil {
+						return nil, err
+					}
+					return inv.IPAM().GetIPReservation(ip)
+				},
+				Update: func(inv *client.InventoryApi, obj interface{}) error {
+					_, err := inv.IPAM().UpdateIPReservation(obj.(*types.IPReservation))
+					return err
+				},
+				ID: func(obj interface{}) string {
+					r := obj.(*types.IPReservation)
+					if r.IP == nil {
+						return ""
+					}
+					return r.IP.IP.String()
+				},
+				Validate: func(obj interface{}) error {
+					if !obj.(*types.IPReservation).Validate() {
+						return fmt.Errorf("the ip address is not valid within its subnet")
+					}
+					return nil
+				},
+			}),
+		},
+	})
+}
+
+func validateNode(obj interface{}) error {
+	node := obj.(*types.Node)
+	if node.InventoryID == "" {
+		return fmt.Errorf("the node must have an InventoryID")
+	}
+
+	seen := map[string]string{}
+	for name, iface := range node.Networks {
+		if iface == nil {
+			return fmt.Errorf("network interface %s is empty", name)
+		}
+		for _, mac := range iface.NICs {
+			if len(mac) == 0 {
+				return fmt.Errorf("network interface %s has an empty mac address", name)
+			}
+			if other, ok := seen[mac.String()]; ok {
+				return fmt.Errorf("mac %s is used by network interfaces %s and %s", mac, other, name)
+			}
+			seen[mac.String()] = name
+		}
+	}
+	return nil
+}
+
+func validateSystem(obj interface{}) error {
+	system := obj.(*types.System)
+	if system.Name == "" {
+		return fmt.Errorf("the system must have a Name")
+	}
+	for name, env := range system.Environments {
+		if env == nil {
+			return fmt.Errorf("environment %s is empty", name)
+		}
+		if env.IPXEUrl != "" {
+			if u, err := url.Parse(env.IPXEUrl); err != nil || !u.IsAbs() {
+				return fmt.Errorf("environment %s has an invalid IPXEUrl: %s", name, env.IPXEUrl)
+			}
+		}
+		for logical, physical := range env.Networks {
+			if physical == "" {
+				return fmt.Errorf("environment %s maps network %s to an empty network name", name, logical)
+			}
+		}
+	}
+	for _, role := range system.Roles {
+		if role == "" {
+			return fmt.Errorf("roles must not be empty")
+		}
+	}
+	return nil
+}
+
+func validateNetwork(obj interface{}) error {
+	network := obj.(*types.Network)
+	if network.Name == "" {
+		return fmt.Errorf("the network must have a Name")
+	}
+	for _, subnet := range network.Subnets {
+		if subnet.Cidr == nil {
+			return fmt.Errorf("all subnets must have a cidr")
+		}
+		if subnet.Gateway != nil && !subnet.Cidr.Contains(subnet.Gateway) {
+			return fmt.Errorf("gateway %s is not within subnet %s", subnet.Gateway, subnet.Cidr)
+		}
+	}
+	return nil
+}
+
+// runEditor opens path in the user's editor, it is replaced in tests
+var runEditor = func(c *cmdContext, path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor may include arguments, the path is passed separately so it
+	// isn't interpreted by the shell
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = c.Stderr
+	return cmd.Run()
+}
+
+const editHeader = `# Please edit the object below.  These comment lines at the top of the file
+# are ignored and an empty file aborts the edit.  The object is only updated
+# if it hasn't been changed on the server since it was fetched.
+`
+
+func editCommand(kind string, ops *editOps) *command {
+	return &command{
+		Name:        kind,
+		Args:        "<id>",
+		Description: fmt.Sprintf("edit a %s", kind),
+		Run: func(c *cmdContext, args []string) error {
+			fs := newFlagSet(c, "edit "+kind, "<id>")
+			if err := parseFlags(fs, args, 1); err != nil {
+				return err
+			}
+
+			inv, err := c.Inventory()
+			if err != nil {
+				return err
+			}
+			return editObject(c, inv, kind, fs.Arg(0), ops)
+		},
+	}
+}
+
+// editObject runs the fetch, edit, validate, update loop.  The editor is
+// re-opened with the error annotated whenever validation or the update fails.
+// If the edit is abandoned after a failure that failure is returned.
+func editObject(c *cmdContext, inv *client.InventoryApi, kind, id string, ops *editOps) error {
+	original, err := ops.Get(inv, id)
+	if err != nil {
+		return err
+	}
+
+	originalYAML, err := printer.ToYAML(original)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile("", fmt.Sprintf("inventory-%s-*.yaml", kind))
+	if err != nil {
+		return err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	content := originalYAML
+	var editErr error
+	for {
+		err = ioutil.WriteFile(path, annotate(content, editErr), 0600)
+		if err != nil {
+			return err
+		}
+
+		err = runEditor(c, path)
+		if err != nil {
+			return fmt.Errorf("editor failed: %v", err)
+		}
+
+		edited, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		content = stripHeader(edited)
+
+		if len(bytes.TrimSpace(content)) == 0 {
+			fmt.Fprintf(c.Stderr, "edit cancelled, the file was empty\n")
+			return editErr
+		}
+
+		if bytes.Equal(content, originalYAML) {
+			fmt.Fprintf(c.Stderr, "edit cancelled, no changes made\n")
+			return nil
+		}
+
+		obj := ops.New()
+		editErr = printer.FromYAML(content, obj)
+		if editErr == nil && ops.ID(obj) != ops.ID(original) {
+			editErr = fmt.Errorf("the id of the object can't be changed from '%s'", ops.ID(original))
+		}
+		if editErr == nil {
+			editErr = ops.Validate(obj)
+		}
+		if editErr != nil {
+			fmt.Fprintf(c.Stderr, "%s %s is invalid: %v\n", kind, id, editErr)
+			continue
+		}
+
+		current, err := ops.Get(inv, id)
+		if err != nil {
+			return err
+		}
+		if !sameObject(current, original) {
+			original = current
+			originalYAML, err = printer.ToYAML(current)
+			if err != nil {
+				return err
+			}
+			editErr = fmt.Errorf("the %s was changed on the server while you were editing it.  Save again to overwrite those changes with your version", kind)
+			fmt.Fprintf(c.Stderr, "%s %s: %v\n", kind, id, editErr)
+			continue
+		}
+
+		if err := writeDiff(c.Stdout, string(originalYAML), string(content)); err != nil {
+			return err
+		}
+
+		editErr = ops.Update(inv, obj)
+		if editErr != nil {
+			fmt.Fprintf(c.Stderr, "unable to update %s %s: %v\n", kind, id, editErr)
+			continue
+		}
+
+		fmt.Fprintf(c.Stderr, "%s %s updated\n", kind, id)
+		return nil
+	}
+}
+
+// sameObject compares the JSON representation of two objects
+func sameObject(a, b interface{}) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	if aErr != nil || bErr != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(aJSON, bJSON)
+}
+
+// annotate prepends the edit instructions and any error to content
+func annotate(content []byte, err error) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(editHeader)
+	if err != nil {
+		buf.WriteString("#\n")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(buf, "# Error: %s\n", line)
+		}
+	}
+	buf.WriteString("#\n")
+	buf.Write(content)
+	return buf.Bytes()
+}
+
+// stripHeader removes the comment lines written by annotate from the top of
+// content.  Later lines are left alone since they may be inside a block scalar.
+func stripHeader(content []byte) []byte {
+	for len(content) > 0 && content[0] == '#' {
+		end := bytes.IndexByte(content, '\n')
+		if end < 0 {
+			return nil
+		}
+		content = content[end+1:]
+	}
+	return content
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/printer"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+// fakeEditor replaces the editor with one that applies each edit in turn,
+// recording the content it was shown.
+func fakeEditor(t *testing.T, edits ...func(string) string) *[]string {
+	shown := &[]string{}
+	runEditor = func(_ *cmdContext, path string) error {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		*shown = append(*shown, string(data))
+
+		if len(*shown) > len(edits) {
+			t.Fatalf("editor opened too many times")
+		}
+		return ioutil.WriteFile(path, []byte(edits[len(*shown)-1](string(data))), 0600)
+	}
+	return shown
+}
+
+func TestEditNetwork(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).Get("network/testnet").Times(2).
+		Reply(http.StatusOK).
+		BodyString(`{"Name": "testnet", "MTU": 1500}`)
+	gock.New(testBaseUrl).Put("network/testnet").
+		BodyString(`{"Name":"testnet","MTU":9000,"Subnets":null,"Domain":"","Metadata":null,"LastUpdated":"0001-01-01T00:00:00Z"}`).
+		Reply(http.StatusOK)
+
+	fakeEditor(t, func(s string) string { return strings.Replace(s, "MTU: 1500", "MTU: 9000", 1) })
+
+	code, stdout, stderr := runTest("", "edit", "network", "testnet")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, "-MTU: 1500\n+MTU: 9000") {
+		t.Errorf("diff not shown: %s", stdout)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("network not updated")
+	}
+}
+
+func TestEditNoChanges(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).Get("system/tsys").
+		Reply(http.StatusOK).
+		BodyString(`{"Name": "tsys"}`)
+
+	fakeEditor(t, func(s string) string { return s })
+
+	code, _, stderr := runTest("", "edit", "system", "tsys")
+	if code != exitOK || !strings.Contains(stderr, "no changes") {
+		t.Errorf("expected edit to be cancelled, got %d: %s", code, stderr)
+	}
+}
+
+func TestEditInvalidReopensEditor(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).Get("node/test-000").
+		Reply(http.StatusOK).
+		BodyString(`{"InventoryID": "test-000", "Role": "worker"}`)
+
+	shown := fakeEditor(t,
+		func(s string) string { return strings.Replace(s, "InventoryID: test-000", "InventoryID: test-001", 1) },
+		func(s string) string { return "" },
+	)
+
+	code, _, stderr := runTest("", "edit", "node", "test-000")
+	if code != exitError || !strings.Contains(stderr, "empty") {
+		t.Errorf("expected edit to be cancelled, got %d: %s", code, stderr)
+	}
+
+	if len(*shown) != 2 || !strings.Contains((*shown)[1], "# Error: the id of the object can't be changed") {
+		t.Errorf("editor not re-opened with error: %v", *shown)
+	}
+
+	if !strings.Contains((*shown)[1], "InventoryID: test-001") {
+		t.Errorf("edits not preserved when re-opening editor: %s", (*shown)[1])
+	}
+}
+
+func TestEditConflict(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).Get("network/testnet").
+		Reply(http.StatusOK).
+		BodyString(`{"Name": "testnet", "MTU": 1500}`)
+	gock.New(testBaseUrl).Get("network/testnet").
+		Reply(http.StatusOK).
+		BodyString(`{"Name": "testnet", "MTU": 1500, "Domain": "example.com"}`)
+
+	shown := fakeEditor(t,
+		func(s string) string { return strings.Replace(s, "MTU: 1500", "MTU: 9000", 1) },
+		func(s string) string { return "" },
+	)
+
+	code, _, _ := runTest("", "edit", "network", "testnet")
+	if code != exitError {
+		t.Errorf("got exit code %d", code)
+	}
+
+	if len(*shown) != 2 || !strings.Contains((*shown)[1], "changed on the server") {
+		t.Errorf("editor not re-opened with conflict error: %v", *shown)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	diff := diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	expected := []string{" a", "-b", "+x", " c", "+d"}
+	if strings.Join(diff, "|") != strings.Join(expected, "|") {
+		t.Errorf("got wrong diff: %v", diff)
+	}
+}
+
+// realEditor is the editor used outside of tests, saved before fakeEditor
+// replaces it
+var realEditor = runEditor
+
+func TestRunEditorQuotesPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "edit")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("VISUAL", "touch")
+	defer os.Unsetenv("VISUAL")
+
+	path := filepath.Join(dir, "a b;touch c.yaml")
+	c := &cmdContext{Stderr: ioutil.Discard}
+	if err := realEditor(c, path); err != nil {
+		t.Fatalf("unable to run editor: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("editor not run on the full path: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Errorf("path was split by the shell")
+	}
+}
+
+func TestStripHeader(t *testing.T) {
+	content := annotate([]byte("Name: tsys\nMetadata:\n  script: |\n    #!/bin/sh\n    # a comment\n"), fmt.Errorf("oops"))
+	expected := "Name: tsys\nMetadata:\n  script: |\n    #!/bin/sh\n    # a comment\n"
+	if got := string(stripHeader(content)); got != expected {
+		t.Errorf("got unexpected content: %q", got)
+	}
+}
+
+func TestEditValidation(t *testing.T) {
+	for _, tc := range []struct {
+		validate func(interface{}) error
+		yaml     string
+		valid    bool
+	}{
+		{validateNode, "InventoryID: test-000\nNetworks:\n  prov:\n    nics: ['00:01:02:03:04:05']\n", true},
+		{validateNode, "Role: worker\n", false},
+		{validateNode, "InventoryID: test-000\nNetworks:\n  prov:\n    nics: ['00:01:02:03:04:05']\n  bmc:\n    nics: ['00:01:02:03:04:05']\n", false},
+		{validateNode, "InventoryID: test-000\nNetworks:\n  prov:\n", false},
+		{validateSystem, "Name: tsys\nEnvironments:\n  prod:\n    IPXEUrl: http://boot.local/ipxe\n", true},
+		{validateSystem, "Roles: [worker]\n", false},
+		{validateSystem, "Name: tsys\nEnvironments:\n  prod:\n    IPXEUrl: boot\n", false},
+		{validateNetwork, "Name: prov\nSubnets:\n  - Cidr: 10.0.0.0/24\n    Gateway: 10.0.0.1\n", true},
+		{validateNetwork, "Subnets:\n  - Cidr: 10.0.0.0/24\n", false},
+		{validateNetwork, "Name: prov\nSubnets:\n  - Cidr: 10.0.0.0/24\n    Gateway: 10.0.1.1\n", false},
+	} {
+		var obj interface{}
+		switch {
+		case strings.Contains(tc.yaml, "Subnets"):
+			obj = types.NewNetwork()
+		case strings.Contains(tc.yaml, "Environments") || strings.Contains(tc.yaml, "Roles"):
+			obj = types.NewSystem()
+		default:
+			obj = types.NewNode()
+		}
+		if err := printer.FromYAML([]byte(tc.yaml), obj); err != nil {
+			t.Fatalf("unable to parse %s: %v", tc.yaml, err)
+		}
+		if err := tc.validate(obj); (err == nil) != tc.valid {
+			t.Errorf("expected valid to be %t for %s, got %v", tc.valid, tc.yaml, err)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+)
+
+func init() {
+	registerResource(&resource{
+		Name:        "generate",
+		Description: "generate configuration files from the inventory",
+		Commands: []*command{
+			generateCommand("dhcpd", "isc dhcpd subnets and host stanzas", "[-networks list] [-next-server ip] [-filename file] [-reservations]", dhcpFlags(export.GenerateDhcpd)),
+			generateCommand("kea", "kea dhcp4 subnets and reservations", "[-networks list] [-next-server ip] [-filename file] [-reservations]", dhcpFlags(export.GenerateKea)),
+			generateCommand("dnsmasq", "dnsmasq dhcp-host options", "[-networks list] [-hostsfile] [-reservations]", dnsmasqFlags(export.GenerateDnsmasqDHCP)),
+			generateCommand("dnsmasq-hosts", "dnsmasq addn-hosts file", "[-networks list] [-reservations]", dnsmasqFlags(export.GenerateDnsmasqHosts)),
+			generateCommand("hosts", "/etc/hosts fragment with per network aliases", "[-networks list] [-primary network] [-suffixes network=suffix,...] [-filter expr]", hostsFlags),
+			generateCommand("ssh-config", "ssh client configuration", "[-networks list] [-user name] [-identity file] [-suffixes network=suffix,...] [-jump system=host,...] [-filter expr]", sshFlags),
+			generateCommand("ansible", "ansible inventory in --list format", "[-networks list] [-filter expr]", ansibleFlags),
+			generateCommand("prometheus", "prometheus file_sd target groups", "[-networks list] [-exporters name=port,...] [-roles role=exporter+exporter,...] [-filter expr]", prometheusFlags),
+			generateCommand("slurm", "slurm.conf node and partition definitions", "-system name [-default-partition role] [-filter expr]", slurmFlags),
+			generateCommand("genders", "genders file for pdsh", "[-filter expr]", clusterFlags(export.GenerateGenders)),
+			generateCommand("clush", "clustershell group file", "[-filter expr]", clusterFlags(export.GenerateClushGroups)),
+			{
+				Name:        "zones",
+				Args:        "-ns name [-hostmaster email] [-nameservers list] [-networks list] [-reservations] [-dir dir]",
+				Description: "forward and reverse dns zone files",
+				Run:         generateZones,
+			},
+		},
+	})
+}
+
+// generator renders a snapshot of the inventory, loading anything else it
+// needs from inv.  Output rendered without the reservations of skipped
+// subnets is returned along with a *client.SkippedSubnetsError.
+type generator func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error)
+
+// generateCommand returns a command that writes the output of the generator
+// returned by setup to stdout, or atomically to the file given with -f.
+// setup registers any generator specific flags.
+func generateCommand(name, description, args string, setup func(fs *flag.FlagSet) generator) *command {
+	args = "[-f file] " + args
+	return &command{
+		Name:        name,
+		Args:        args,
+		Description: description,
+		Run: func(c *cmdContext, cmdArgs []string) error {
+			fs := newFlagSet(c, "generate "+name, args)
+			filename := fs.String("f", "-", "file to write, - for stdout")
+			generate := setup(fs)
+			if err := parseFlags(fs, cmdArgs, 0); err != nil {
+				return err
+			}
+
+			inv, err := c.Inventory()
+			if err != nil {
+				return err
+			}
+
+			snapshot, err := export.LoadSnapshot(inv)
+			if err != nil {
+				return err
+			}
+
+			data, err := generate(inv, snapshot)
+			if client.SkippedSubnets(err) != nil {
+				fmt.Fprintf(c.Stderr, "warning: %v\n", err)
+			} else if err != nil {
+				return err
+			}
+
+			if *filename == "-" {
+				_, err = c.Stdout.Write(data)
+				return err
+			}
+
+			changed, err := export.WriteFileAtomic(*filename, data, 0644)
+			if err != nil {
+				return err
+			}
+			if changed {
+				fmt.Fprintf(c.Stdout, "%s updated\n", *filename)
+			} else {
+				fmt.Fprintf(c.Stdout, "%s unchanged\n", *filename)
+			}
+			return nil
+		},
+	}
+}
+
+// splitList splits a comma separated flag value, returning nil if it is empty
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// splitMap splits a comma separated list of key=value pairs
+func splitMap(s string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, pair := range splitList(s) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, usagef("invalid key=value pair: %s", pair)
+		}
+		m[parts[0]] = parts[1]
+	}
+	return m, nil
+}
+
+// filterFlag registers the -filter flag, returning a function that parses it
+func filterFlag(fs *flag.FlagSet) func() (*export.NodeFilter, error) {
+	filter := fs.String("filter", "", "only include nodes matching role=,tag=,system= or host= terms")
+	return func() (*export.NodeFilter, error) {
+		f, err := export.ParseNodeFilter(*filter)
+		if err != nil {
+			return nil, usagef("%v", err)
+		}
+		return f, nil
+	}
+}
+
+func hostsFlags(fs *flag.FlagSet) generator {
+	opts := &export.HostsOptions{}
+	networks := fs.String("networks", "", "comma separated list of networks to include, all if unset")
+	fs.StringVar(&opts.Primary, "primary", "", "network whose addresses are also given the plain hostname")
+	suffixes := fs.String("suffixes", "", "comma separated network=suffix alias overrides")
+	filter := filterFlag(fs)
+	return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+		var err error
+		opts.Networks = splitList(*networks)
+		if opts.Suffixes, err = splitMap(*suffixes); err != nil {
+			return nil, err
+		}
+		if opts.Filter, err = filter(); err != nil {
+			return nil, err
+		}
+		return export.GenerateHosts(s, opts)
+	}
+}
+
+func sshFlags(fs *flag.FlagSet) generator {
+	opts := &export.SSHOptions{}
+	networks := fs.String("networks", "", "comma separated list of networks to connect over in order of preference, first ipv4 address if unset")
+	fs.StringVar(&opts.User, "user", "", "user to connect as")
+	fs.StringVar(&opts.IdentityFile, "identity", "", "identity file to connect with")
+	suffixes := fs.String("suffixes", "", "comma separated network=suffix alias overrides")
+	jumpHosts := fs.String("jump", "", "comma separated system=host jump hosts, * for any other system")
+	filter := filterFlag(fs)
+	return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+		var err error
+		opts.Networks = splitList(*networks)
+		if opts.Suffixes, err = splitMap(*suffixes); err != nil {
+			return nil, err
+		}
+		if opts.JumpHosts, err = splitMap(*jumpHosts); err != nil {
+			return nil, err
+		}
+		if opts.Filter, err = filter(); err != nil {
+			return nil, err
+		}
+		return export.GenerateSSHConfig(s, opts)
+	}
+}
+
+func ansibleFlags(fs *flag.FlagSet) generator {
+	opts := &export.AnsibleOptions{}
+	networks := fs.String("networks", "", "comma separated list of networks to choose ansible_host from in order of preference, first ipv4 address if unset")
+	filter := filterFlag(fs)
+	return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+		var err error
+		opts.Networks = splitList(*networks)
+		if opts.Filter, err = filter(); err != nil {
+			return nil, err
+		}
+		return export.GenerateAnsible(s, opts)
+	}
+}
+
+func prometheusFlags(fs *flag.FlagSet) generator {
+	opts := &export.PrometheusOptions{}
+	networks := fs.String("networks", "", "comma separated list of networks to scrape over in order of preference, first ipv4 address if unset")
+	exporters := fs.String("exporters", "", "comma separated name=port exporters, node=9100 if unset")
+	roles := fs.String("roles", "", "comma separated role=exporter+exporter mappings, * for any other role, every exporter if unset")
+	filter := filterFlag(fs)
+	return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+		var err error
+		opts.Networks = splitList(*networks)
+		if opts.Filter, err = filter(); err != nil {
+			return nil, err
+		}
+
+		ports, err := splitMap(*exporters)
+		if err != nil {
+			return nil, err
+		}
+		opts.Exporters = map[string]int{}
+		for name, port := range ports {
+			if opts.Exporters[name], err = strconv.Atoi(port); err != nil {
+				return nil, usagef("invalid port for exporter %s: %s", name, port)
+			}
+		}
+
+		roleExporters, err := splitMap(*roles)
+		if err != nil {
+			return nil, err
+		}
+		opts.Roles = map[string][]string{}
+		for role, names := range roleExporters {
+			opts.Roles[role] = strings.Split(names, "+")
+		}
+		return export.GeneratePrometheus(s, opts)
+	}
+}
+
+func slurmFlags(fs *flag.FlagSet) generator {
+	opts := &export.SlurmOptions{}
+	fs.StringVar(&opts.System, "system", "", "system to generate the slurm cluster for")
+	fs.StringVar(&opts.DefaultPartition, "default-partition", "", "role whose partition is the default")
+	filter := filterFlag(fs)
+	return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+		var err error
+		if opts.System == "" {
+			return nil, usagef("-system is required")
+		}
+		if opts.Filter, err = filter(); err != nil {
+			return nil, err
+		}
+		return export.GenerateSlurm(s, opts)
+	}
+}
+
+func clusterFlags(f func(*export.Snapshot, *export.ClusterOptions) ([]byte, error)) func(fs *flag.FlagSet) generator {
+	return func(fs *flag.FlagSet) generator {
+		opts := &export.ClusterOptions{}
+		filter := filterFlag(fs)
+		return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+			var err error
+			if opts.Filter, err = filter(); err != nil {
+				return nil, err
+			}
+			return f(s, opts)
+		}
+	}
+}
+
+func dhcpFlags(f func(*export.Snapshot, *export.DHCPOptions) ([]byte, error)) func(fs *flag.FlagSet) generator {
+	return func(fs *flag.FlagSet) generator {
+		opts := &export.DHCPOptions{}
+		networks := fs.String("networks", "", "comma separated list of networks to include, all if unset")
+		fs.StringVar(&opts.NextServer, "next-server", "", "tftp server for network booting")
+		fs.StringVar(&opts.Filename, "filename", "", "boot file for network booting")
+		reservations := fs.Bool("reservations", false, "include static reservations that don't belong to a node")
+		return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+			opts.Networks = splitList(*networks)
+			var skipped error
+			if *reservations {
+				if skipped = s.LoadReservations(inv); skipped != nil && client.SkippedSubnets(skipped) == nil {
+					return nil, skipped
+				}
+			}
+			data, err := f(s, opts)
+			if err != nil {
+				return nil, err
+			}
+			return data, skipped
+		}
+	}
+}
+
+func dnsmasqFlags(f func(*export.Snapshot, *export.DnsmasqOptions) ([]byte, error)) func(fs *flag.FlagSet) generator {
+	return func(fs *flag.FlagSet) generator {
+		opts := &export.DnsmasqOptions{}
+		networks := fs.String("networks", "", "comma separated list of networks to include, all if unset")
+		fs.BoolVar(&opts.HostsFile, "hostsfile", false, "write a dhcp-hostsfile rather than dhcp-host options")
+		reservations := fs.Bool("reservations", false, "include static reservations that don't belong to a node")
+		return func(inv *client.InventoryApi, s *export.Snapshot) ([]byte, error) {
+			opts.Networks = splitList(*networks)
+			var skipped error
+			if *reservations {
+				if skipped = s.LoadReservations(inv); skipped != nil && client.SkippedSubnets(skipped) == nil {
+					return nil, skipped
+				}
+			}
+			data, err := f(s, opts)
+			if err != nil {
+				return nil, err
+			}
+			return data, skipped
+		}
+	}
+}
+
+// generateZones writes each zone to its own file in -dir, keeping the SOA
+// serial of zones that haven't changed, or every zone to stdout
+func generateZones(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "generate zones", "-ns name [-hostmaster email] [-nameservers list] [-networks list] [-reservations] [-dir dir]")
+	opts := &export.ZoneOptions{}
+	fs.StringVar(&opts.PrimaryNS, "ns", "", "primary name server for the SOA records")
+	fs.StringVar(&opts.Hostmaster, "hostmaster", "", "responsible mailbox for the SOA records")
+	nameServers := fs.String("nameservers", "", "comma separated list of NS records, the primary name server if unset")
+	networks := fs.String("networks", "", "comma separated list of networks to include, all if unset")
+	reservations := fs.Bool("reservations", false, "include named static reservations that don't belong to a node")
+	dir := fs.String("dir", "", "directory to write zone files to, stdout if unset")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+	if opts.PrimaryNS == "" {
+		return usagef("-ns is required")
+	}
+	opts.NameServers = splitList(*nameServers)
+	opts.Networks = splitList(*networks)
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := export.LoadSnapshot(inv)
+	if err != nil {
+		return err
+	}
+	if *reservations {
+		if err := snapshot.LoadReservations(inv); client.SkippedSubnets(err) != nil {
+			fmt.Fprintf(c.Stderr, "warning: %v\n", err)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	zones, err := export.BuildZones(snapshot, opts)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, z := range zones {
+		if *dir == "" {
+			if _, err := c.Stdout.Write(z.Render(export.NextSerial(0, now))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		filename := filepath.Join(*dir, z.Filename())
+		changed, err := export.WriteZoneFile(filename, z, now)
+		if err != nil {
+			return err
+		}
+		if changed {
+			fmt.Fprintf(c.Stdout, "%s updated\n", filename)
+		} else {
+			fmt.Fprintf(c.Stdout, "%s unchanged\n", filename)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const testNodeConfigs = `[{
+	"Hostname": "node-000",
+	"InventoryID": "test-000",
+	"Role": "worker",
+	"System": {"Name": "tsys"},
+	"Networks": {
+		"provisioning": {
+			"Network": {"Name": "prov", "Domain": "prov.example.com", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24", "Gateway": "10.0.0.1"}]},
+			"Interface": {"NICs": ["00:01:02:03:04:05"]},
+			"Config": {"IP": ["10.0.0.10/24"]}
+		}
+	}
+}]`
+
+const testNetworks = `[{"Name": "prov", "Domain": "prov.example.com", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24", "Gateway": "10.0.0.1"}]}]`
+
+func mockSnapshot() {
+	gock.New(testBaseUrl).
+		Get("nodeconfig").
+		Persist().
+		Reply(http.StatusOK).
+		BodyString(testNodeConfigs)
+	gock.New(testBaseUrl).
+		Get("network").
+		Persist().
+		Reply(http.StatusOK).
+		BodyString(testNetworks)
+}
+
+func TestGenerateToFile(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	mockSnapshot()
+
+	dir, err := ioutil.TempDir("", "generate")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "dhcpd.conf")
+
+	for _, expected := range []string{"updated", "unchanged"} {
+		code, stdout, stderr := runTest("", "generate", "dhcpd", "-f", filename, "-filename", "pxelinux.0")
+		if code != exitOK {
+			t.Fatalf("got exit code %d: %s", code, stderr)
+		}
+		if stdout != filename+" "+expected+"\n" {
+			t.Errorf("got unexpected output: %s", stdout)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(filename)
+	if !strings.Contains(string(data), "fixed-address 10.0.0.10;") {
+		t.Errorf("host not written to file:\n%s", data)
+	}
+}
+
+func TestGenerateDHCPSkippedSubnets(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	// the server can't list reservations, so the ipv6 subnet is skipped but
+	// the reservations in the ipv4 subnet are still written
+	network := `{"Name": "prov", "Domain": "prov.example.com", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24", "Gateway": "10.0.0.1"}, {"Name": "prov6", "Cidr": "2001:db8::/64"}]}`
+	gock.New(testBaseUrl).Get("nodeconfig").Persist().Reply(http.StatusOK).BodyString(testNodeConfigs)
+	gock.New(testBaseUrl).Get("network$").Persist().Reply(http.StatusOK).BodyString("[" + network + "]")
+	gock.New(testBaseUrl).Get("network/prov$").Persist().Reply(http.StatusOK).BodyString(network)
+	gock.New(testBaseUrl).Get("ipam/ip$").Persist().Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+	gock.New(testBaseUrl).Get("ipam/ip/10.0.0.21$").Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:99", "ip": "10.0.0.21/24", "HostInformation": "printer"}`)
+	gock.New(testBaseUrl).Get("ipam/ip/").Persist().Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+
+	code, stdout, stderr := runTest("", "generate", "dhcpd", "-reservations")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "fixed-address 10.0.0.21;") {
+		t.Errorf("reservation missing from output: %s", stdout)
+	}
+	if !strings.Contains(stderr, "warning:") || !strings.Contains(stderr, "2001:db8::/64") {
+		t.Errorf("skipped subnet not reported: %s", stderr)
+	}
+}
+
+func TestGenerateZones(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	mockSnapshot()
+
+	dir, err := ioutil.TempDir("", "zones")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	code, stdout, stderr := runTest("", "generate", "zones", "-ns", "ns1.example.com", "-dir", dir)
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "prov.example.com.zone updated\n") || !strings.Contains(stdout, "0.0.10.in-addr.arpa.zone updated\n") {
+		t.Errorf("got unexpected output: %s", stdout)
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "0.0.10.in-addr.arpa.zone"))
+	if !strings.Contains(string(data), "10\tIN\tPTR\tnode-000.prov.example.com.\n") {
+		t.Errorf("ptr record not written to zone:\n%s", data)
+	}
+
+	if code, _, _ := runTest("", "generate", "zones"); code != exitUsage {
+		t.Errorf("expected usage error without -ns, got %d", code)
+	}
+}
+
+func TestGenerateSSHConfig(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	mockSnapshot()
+
+	code, stdout, stderr := runTest("", "generate", "ssh-config", "-user", "root", "-jump", "*=bastion", "-filter", "role=worker")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "Host node-000 node-000.prov.example.com\n    HostName 10.0.0.10\n    User root\n    ProxyJump bastion\n") {
+		t.Errorf("got unexpected output: %s", stdout)
+	}
+
+	for _, args := range [][]string{{"-filter", "colour=blue"}, {"-jump", "bastion"}} {
+		if code, _, _ := runTest("", append([]string{"generate", "ssh-config"}, args...)...); code != exitUsage {
+			t.Errorf("expected usage error for %v, got %d", args, code)
+		}
+	}
+}
+
+func TestGeneratePrometheus(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	mockSnapshot()
+
+	code, stdout, stderr := runTest("", "generate", "prometheus", "-exporters", "node=9100,ipmi=9290", "-roles", "worker=node+ipmi")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"10.0.0.10:9290"`) || !strings.Contains(stdout, `"10.0.0.10:9100"`) {
+		t.Errorf("got unexpected output: %s", stdout)
+	}
+
+	if code, _, _ := runTest("", "generate", "prometheus", "-exporters", "node=http"); code != exitUsage {
+		t.Errorf("expected usage error for an invalid port, got %d", code)
+	}
+}
+
+func TestGenerateSlurm(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	mockSnapshot()
+
+	code, stdout, stderr := runTest("", "generate", "slurm", "-system", "tsys", "-default-partition", "worker")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "PartitionName=worker Nodes=node-000 Default=YES State=UP") {
+		t.Errorf("got unexpected output: %s", stdout)
+	}
+
+	if code, _, _ := runTest("", "generate", "slurm"); code != exitUsage {
+		t.Errorf("expected usage error without -system, got %d", code)
+	}
+}
+
+func TestGenerateClush(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	mockSnapshot()
+
+	code, stdout, stderr := runTest("", "generate", "clush", "-filter", "role=worker")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "all: node-000\n") {
+		t.Errorf("got unexpected output: %s", stdout)
+	}
+}
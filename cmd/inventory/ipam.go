@@ -0,0 +1,544 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func init() {
+	registerResource(&resource{
+		Name:        "ipam",
+		Description: "manage ip reservations",
+		Commands: []*command{
+			{Name: "get", Args: "<ip>", Description: "show the reservation for an ip", Run: ipamGet},
+			{Name: "list", Args: "-mac <mac> | -subnet <cidr> | -network <name> | -host <hostname>", Description: "list reservations", Run: ipamList},
+			{Name: "reserve", Args: "[-subnet cidr] [-mac mac] [-ttl duration] [-on-conflict fail|next|dynamic] [ip]", Description: "reserve an ip address", Run: ipamReserve},
+			{Name: "reserve-dual", Args: "-mac mac -v4 cidr -v6 cidr [-slaac] [-ttl duration]", Description: "reserve a pair of ipv4 and ipv6 addresses", Run: ipamReserveDual},
+			{Name: "provision", Args: "[-ttl duration] [-subnet cidr] [-mac mac] [ip]", Description: "reserve an ip address until committed or the ttl expires", Run: ipamProvision},
+			{Name: "commit", Args: "<ip>", Description: "make a provisioning lease permanent", Run: ipamCommit},
+			{Name: "sweep", Args: "[-networks list] [-grace d]", Description: "delete expired provisioning leases", Run: ipamSweep},
+			{Name: "update", Args: "[-f file]", Description: "replace an existing reservation", Run: ipamUpdate},
+			{Name: "renew", Args: "[-duration d] <ip>", Description: "extend a reservation", Run: ipamRenew},
+			{Name: "release", Args: "<ip>", Description: "release a reservation", Run: ipamRelease},
+			{Name: "gc", Args: "[-delete] [-networks list] [-expired-grace d] [-orphan-grace d] [-exclude list]", Description: "report or delete orphaned and expired reservations", Run: ipamGC},
+			{Name: "reconcile", Args: "[-fix] [-delete-extra]", Description: "compare node nics with reservations", Run: ipamReconcile},
+			{Name: "usage", Args: "[-threshold pct] [-next n] [network...]", Description: "report subnet utilization", Run: ipamUsage},
+		},
+	})
+}
+
+func parseIPArg(s string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, usagef("invalid ip address: %s", s)
+	}
+	return ip, nil
+}
+
+func ipamGet(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam get", "<ip>")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	ip, err := parseIPArg(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	reservation, err := inv.IPAM().GetIPReservation(ip)
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, reservation)
+}
+
+func ipamList(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam list", "-mac <mac> | -subnet <cidr> | -network <name> | -host <hostname>")
+	output := addOutputFlags(fs)
+	macString := fs.String("mac", "", "list reservations for this mac address")
+	subnetString := fs.String("subnet", "", "list reservations within this subnet")
+	network := fs.String("network", "", "list reservations in the subnets of this network")
+	host := fs.String("host", "", "list reservations belonging to this hostname")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	selectors := 0
+	for _, s := range []string{*macString, *subnetString, *network, *host} {
+		if s != "" {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		fs.Usage()
+		return usagef("exactly one of -mac, -subnet, -network or -host is required")
+	}
+
+	var mac net.HardwareAddr
+	var subnet *net.IPNet
+	var err error
+	switch {
+	case *macString != "":
+		mac, err = net.ParseMAC(*macString)
+		if err != nil {
+			return usagef("invalid mac address: %v", err)
+		}
+	case *subnetString != "":
+		_, subnet, err = net.ParseCIDR(*subnetString)
+		if err != nil {
+			return usagef("invalid subnet: %v", err)
+		}
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	var reservations types.IPReservationList
+	switch {
+	case mac != nil:
+		reservations, err = inv.IPAM().GetIPReservationsByMAC(mac)
+	case subnet != nil:
+		reservations, err = inv.IPAM().GetIPReservationsBySubnet(subnet)
+	case *network != "":
+		reservations, err = inv.IPAM().GetIPReservationsByNetwork(*network)
+	default:
+		reservations, err = inv.IPAM().GetIPReservationsByHostname(*host)
+	}
+	if skipped := client.SkippedSubnets(err); skipped != nil {
+		fmt.Fprintf(c.Stderr, "warning: %v\n", err)
+	} else if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, reservations)
+}
+
+func ipamReserve(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam reserve", "[-subnet cidr] [-mac mac] [-ttl duration] [-on-conflict fail|next|dynamic] [ip]")
+	output := addOutputFlags(fs)
+	request := &types.IpamIpRequest{}
+	fs.StringVar(&request.Subnet, "subnet", "", "subnet to allocate an address from")
+	fs.StringVar(&request.HwAddress, "mac", "", "mac address the reservation belongs to")
+	fs.StringVar(&request.TTL, "ttl", "", "lifetime of the reservation, static if unset")
+	onConflict := fs.String("on-conflict", "fail", "if the ip is taken: fail, try the next free address, or allocate dynamically")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return usagef("expected at most one ip address")
+	}
+
+	var ip net.IP
+	if fs.NArg() == 1 {
+		var err error
+		ip, err = parseIPArg(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+	} else if request.Subnet == "" {
+		fs.Usage()
+		return usagef("a subnet or ip address is required")
+	}
+
+	policy, err := client.ParseConflictPolicy(*onConflict)
+	if err != nil {
+		return usagef("%v", err)
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	reservation, err := inv.IPAM().CreateIPReservationWithPolicy(request, ip, policy)
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, reservation)
+}
+
+func ipamProvision(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam provision", "[-ttl duration] [-subnet cidr] [-mac mac] [ip]")
+	output := addOutputFlags(fs)
+	request := &types.IpamIpRequest{}
+	fs.StringVar(&request.Subnet, "subnet", "", "subnet to allocate an address from")
+	fs.StringVar(&request.HwAddress, "mac", "", "mac address the reservation belongs to")
+	ttl := fs.Duration("ttl", 30*time.Minute, "time allowed to commit the lease")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return usagef("expected at most one ip address")
+	}
+
+	var ip net.IP
+	if fs.NArg() == 1 {
+		var err error
+		ip, err = parseIPArg(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+	} else if request.Subnet == "" {
+		fs.Usage()
+		return usagef("a subnet or ip address is required")
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	lease, err := inv.IPAM().NewProvisioningLease(request, ip, *ttl)
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, lease.Reservation)
+}
+
+func ipamCommit(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam commit", "<ip>")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	ip, err := parseIPArg(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	reservation, err := inv.IPAM().GetIPReservation(ip)
+	if err != nil {
+		return err
+	}
+	if !client.IsProvisioningLease(reservation) {
+		return fmt.Errorf("%s is not an uncommitted provisioning lease", ip)
+	}
+
+	lease := &client.ProvisioningLease{IPAM: inv.IPAM(), Reservation: reservation}
+	committed, err := lease.Commit()
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, committed)
+}
+
+func ipamSweep(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam sweep", "[-networks list] [-grace d]")
+	networks := fs.String("networks", "", "comma separated list of networks to sweep, all if unset")
+	grace := fs.Duration("grace", client.DefaultSweepGrace, "keep expired leases for this long")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	sweeper := inv.IPAM().NewLeaseSweeper()
+	sweeper.Grace = *grace
+	if *networks != "" {
+		sweeper.Networks = strings.Split(*networks, ",")
+	}
+
+	deleted, sweepErr := sweeper.Sweep()
+	if err := p.Print(c.Stdout, deleted); err != nil {
+		return err
+	}
+	return sweepErr
+}
+
+func ipamReserveDual(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam reserve-dual", "-mac mac -v4 cidr -v6 cidr [-slaac] [-ttl duration]")
+	output := addOutputFlags(fs)
+	macString := fs.String("mac", "", "mac address the reservations belong to")
+	v4String := fs.String("v4", "", "ipv4 subnet to allocate an address from")
+	v6String := fs.String("v6", "", "ipv6 subnet to allocate an address from")
+	slaac := fs.Bool("slaac", false, "reserve the eui-64 address of the mac in the ipv6 subnet")
+	ttl := fs.String("ttl", "", "lifetime of the reservations, static if unset")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *macString == "" || *v4String == "" || *v6String == "" {
+		fs.Usage()
+		return usagef("-mac, -v4 and -v6 are required")
+	}
+
+	request := &client.DualStackRequest{TTL: *ttl, SLAAC: *slaac}
+	var err error
+	if request.MAC, err = net.ParseMAC(*macString); err != nil {
+		return usagef("invalid mac address: %v", err)
+	}
+	if _, request.V4Subnet, err = net.ParseCIDR(*v4String); err != nil {
+		return usagef("invalid ipv4 subnet: %v", err)
+	}
+	if _, request.V6Subnet, err = net.ParseCIDR(*v6String); err != nil {
+		return usagef("invalid ipv6 subnet: %v", err)
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	v4, v6, err := inv.IPAM().ReserveDualStack(request)
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, types.IPReservationList{v4, v6})
+}
+
+func ipamUpdate(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam update", "[-f file]")
+	output := addOutputFlags(fs)
+	filename := fs.String("f", "-", "file containing the reservation, - for stdin")
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	reservation := &types.IPReservation{}
+	if err := readObject(c, *filename, reservation); err != nil {
+		return err
+	}
+
+	if reservation.IP == nil {
+		return usagef("the reservation must include an ip address")
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	updated, err := inv.IPAM().UpdateIPReservation(reservation)
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, updated)
+}
+
+func ipamRelease(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam release", "<ip>")
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	ip, err := parseIPArg(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	return inv.IPAM().Release(ip)
+}
+
+func ipamRenew(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam renew", "[-duration d] <ip>")
+	duration := fs.Duration("duration", client.DefaultLeaseDuration, "new lifetime of the reservation, starting now")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	ip, err := parseIPArg(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	reservation, err := inv.IPAM().Renew(ip, *duration)
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, reservation)
+}
+
+func ipamUsage(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam usage", "[-threshold pct] [-next n] [network...]")
+	threshold := fs.Float64("threshold", client.DefaultUtilizationThreshold, "flag subnets using more than this percentage of addresses")
+	nextFree := fs.Int("next", 5, "number of free addresses to list per subnet")
+	output := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	report, err := inv.IPAM().Utilization(*threshold, *nextFree, fs.Args()...)
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, report)
+}
+
+func ipamGC(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam gc", "[-delete] [-networks list] [-expired-grace d] [-orphan-grace d] [-exclude list]")
+	collect := fs.Bool("delete", false, "delete the reservations found, rather than only reporting them")
+	networks := fs.String("networks", "", "comma separated list of networks to collect, all if unset")
+	expiredGrace := fs.Duration("expired-grace", client.DefaultGCExpiredGrace, "keep expired reservations for this long after they end")
+	orphanGrace := fs.Duration("orphan-grace", client.DefaultGCOrphanGrace, "keep orphaned reservations for this long after they start")
+	exclude := fs.String("exclude", "", "comma separated list of ips, cidrs or macs to never delete")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	gc := inv.IPAM().NewGarbageCollector()
+	gc.ExpiredGrace = *expiredGrace
+	gc.OrphanGrace = *orphanGrace
+	if *networks != "" {
+		gc.Networks = strings.Split(*networks, ",")
+	}
+	if *exclude != "" {
+		gc.Exclude = strings.Split(*exclude, ",")
+	}
+
+	report, err := gc.Plan()
+	if err != nil {
+		return err
+	}
+
+	var collectErr error
+	if *collect {
+		collectErr = gc.Collect(report)
+	}
+
+	if err := p.Print(c.Stdout, report); err != nil {
+		return err
+	}
+	return collectErr
+}
+
+func ipamReconcile(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "ipam reconcile", "[-fix] [-delete-extra]")
+	fix := fs.Bool("fix", false, "create missing reservations and replace mismatched ones")
+	deleteExtra := fs.Bool("delete-extra", false, "with -fix, also delete extra reservations")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	reconciler := inv.IPAM().NewReconciler()
+	reconciler.DeleteExtra = *deleteExtra
+
+	report, err := reconciler.Plan()
+	if err != nil {
+		return err
+	}
+
+	var applyErr error
+	if *fix {
+		applyErr = reconciler.Apply(report)
+	}
+
+	if err := p.Print(c.Stdout, report); err != nil {
+		return err
+	}
+	return applyErr
+}
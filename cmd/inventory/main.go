@@ -0,0 +1,175 @@
+// Command inventory performs everyday operations against the inventory API.
+//
+// Usage:
+//
+//	inventory [-profile name] <resource> <action> [flags] [args]
+//
+// Exit status is 0 on success, 1 on error, 2 on a usage error, 3 if the
+// requested object wasn't found and 4 on a conflict with an existing object.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+)
+
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitConflict = 4
+)
+
+// newInventoryApi is replaced in tests
+var newInventoryApi = client.NewInventoryApiDefaultConfig
+
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+type cmdContext struct {
+	Profile string
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+
+	inventory *client.InventoryApi
+}
+
+// Inventory connects to the inventory API using the selected profile
+func (c *cmdContext) Inventory() (*client.InventoryApi, error) {
+	if c.inventory != nil {
+		return c.inventory, nil
+	}
+	inv, err := newInventoryApi(c.Profile)
+	if err != nil {
+		return nil, err
+	}
+	c.inventory = inv
+	return inv, nil
+}
+
+type command struct {
+	Name        string
+	Args        string
+	Description string
+	Run         func(c *cmdContext, args []string) error
+}
+
+type resource struct {
+	Name        string
+	Description string
+	Commands    []*command
+}
+
+func (r *resource) command(name string) *command {
+	for _, cmd := range r.Commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+var resources = map[string]*resource{}
+
+func registerResource(r *resource) {
+	resources[r.Name] = r
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	profile := fs.String("profile", os.Getenv("INVENTORY_PROFILE"), "configuration profile to use (default \"default\")")
+	fs.Usage = func() { usage(stderr, fs) }
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if fs.NArg() < 1 {
+		usage(stderr, fs)
+		return exitUsage
+	}
+
+	r, ok := resources[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "inventory: unknown resource %q\n", fs.Arg(0))
+		usage(stderr, fs)
+		return exitUsage
+	}
+
+	if fs.NArg() < 2 {
+		resourceUsage(stderr, r)
+		return exitUsage
+	}
+
+	cmd := r.command(fs.Arg(1))
+	if cmd == nil {
+		fmt.Fprintf(stderr, "inventory: unknown %s action %q\n", r.Name, fs.Arg(1))
+		resourceUsage(stderr, r)
+		return exitUsage
+	}
+
+	c := &cmdContext{Profile: *profile, Stdin: stdin, Stdout: stdout, Stderr: stderr}
+	err := cmd.Run(c, fs.Args()[2:])
+	if err != nil && err != flag.ErrHelp {
+		fmt.Fprintf(stderr, "inventory %s %s: %v\n", r.Name, cmd.Name, err)
+	}
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	if _, ok := err.(*usageError); ok || err == flag.ErrHelp {
+		return exitUsage
+	}
+
+	switch {
+	case err == nil:
+		return exitOK
+	case client.IsNotFound(err):
+		return exitNotFound
+	case client.IsConflict(err):
+		return exitConflict
+	default:
+		return exitError
+	}
+}
+
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: inventory [flags] <resource> <action> [args]\n\nResources:\n")
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %s\n", name, resources[name].Description)
+	}
+	fmt.Fprintf(w, "\nFlags:\n")
+	fs.PrintDefaults()
+}
+
+func resourceUsage(w io.Writer, r *resource) {
+	fmt.Fprintf(w, "Usage: inventory %s <action> [args]\n\nActions:\n", r.Name)
+	for _, cmd := range r.Commands {
+		fmt.Fprintf(w, "  %-30s %s\n", fmt.Sprintf("%s %s", cmd.Name, cmd.Args), cmd.Description)
+	}
+}
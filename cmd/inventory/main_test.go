@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+const testBaseUrl = "https://inventory.api.local/v0/"
+
+func init() {
+	newInventoryApi = func(string) (*client.InventoryApi, error) {
+		baseUrl, _ := url.Parse(testBaseUrl)
+		return client.NewInventoryApi(baseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}), nil
+	}
+}
+
+func runTest(stdin string, args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := run(args, strings.NewReader(stdin), stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestNodeGet(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("node/test-000").
+		Reply(http.StatusOK).
+		BodyString(`{"InventoryID": "test-000"}`)
+
+	code, stdout, stderr := runTest("", "node", "get", "-o", "json", "test-000")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, `"InventoryID": "test-000"`) {
+		t.Errorf("node not printed: %s", stdout)
+	}
+}
+
+func TestNodeGetNotFound(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("node/test-000").
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "object not found"}`)
+
+	code, _, _ := runTest("", "node", "get", "test-000")
+	if code != exitNotFound {
+		t.Errorf("got wrong exit code: expected %d, got %d", exitNotFound, code)
+	}
+}
+
+func TestNodeCreateConflict(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Post("node").
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "An object with that id already exists."}`)
+
+	code, _, stderr := runTest(`{"InventoryID": "test-000"}`, "node", "create")
+	if code != exitConflict {
+		t.Errorf("got wrong exit code: expected %d, got %d", exitConflict, code)
+	}
+
+	if !strings.Contains(stderr, "already exists") {
+		t.Errorf("error not reported: %s", stderr)
+	}
+}
+
+func TestNetworkCreateFromYAML(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Post("network").
+		BodyString(`{"Name":"testnet","MTU":9000,"Subnets":null,"Domain":"","Metadata":null,"LastUpdated":"0001-01-01T00:00:00Z"}`).
+		Reply(http.StatusCreated)
+
+	code, _, stderr := runTest("Name: testnet\nMTU: 9000\n", "network", "create")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+}
+
+func TestNodeListColumns(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("node").
+		Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Role": "worker"}, {"InventoryID": "test-001", "Role": "login"}]`)
+
+	code, stdout, stderr := runTest("", "node", "list", "-o", "csv", "-columns", "inventoryid,role", "-no-headers")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if stdout != "test-000,worker\ntest-001,login\n" {
+		t.Errorf("got unexpected output: %s", stdout)
+	}
+}
+
+func TestIPAMReserve(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Post("ipam/ip").
+		BodyString(`{"subnet":"10.0.0.0/24","mac":"00:01:02:03:04:05","ttl":"","metadata":null}`).
+		Reply(http.StatusCreated).
+		BodyString(`{"ip": "10.0.0.2/24", "mac": "00:01:02:03:04:05", "start": null, "end": null}`)
+
+	code, stdout, stderr := runTest("", "ipam", "reserve", "-subnet", "10.0.0.0/24", "-mac", "00:01:02:03:04:05")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, "10.0.0.2/24   00:01:02:03:04:05") {
+		t.Errorf("reservation not printed: %s", stdout)
+	}
+}
+
+func TestIPAMListBySubnet(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.0.0.0/24").
+		Reply(http.StatusOK).
+		BodyString(`[{"ip": "10.0.0.2/24", "mac": "00:01:02:03:04:05", "start": null, "end": null}]`)
+
+	code, stdout, stderr := runTest("", "ipam", "list", "-subnet", "10.0.0.0/24")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, "10.0.0.2/24") {
+		t.Errorf("reservation not printed: %s", stdout)
+	}
+}
+
+func TestIPAMGCReportOnly(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("ipam/ip").
+		MatchParam("network", "prov").
+		Reply(http.StatusOK).
+		BodyString(`[{"ip": "10.0.0.2/24", "mac": "00:01:02:03:04:05", "start": null, "end": null}]`)
+	gock.New(testBaseUrl).
+		Get("node").
+		Reply(http.StatusOK).
+		BodyString(`[]`)
+
+	code, stdout, stderr := runTest("", "ipam", "gc", "-networks", "prov")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, "orphaned") || !strings.Contains(stdout, "pending") {
+		t.Errorf("candidate not printed: %s", stdout)
+	}
+}
+
+func TestIPAMGCDelete(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("ipam/ip").
+		MatchParam("network", "prov").
+		Reply(http.StatusOK).
+		BodyString(`[{"ip": "10.0.0.2/24", "mac": "00:01:02:03:04:05", "start": null, "end": null}]`)
+	gock.New(testBaseUrl).
+		Get("node").
+		Reply(http.StatusOK).
+		BodyString(`[]`)
+	gock.New(testBaseUrl).
+		Delete("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK)
+
+	code, stdout, stderr := runTest("", "ipam", "gc", "-delete", "-networks", "prov")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("orphaned reservation not deleted")
+	}
+	if !strings.Contains(stdout, "deleted") {
+		t.Errorf("deletion not printed: %s", stdout)
+	}
+}
+
+func TestNetworkPlan(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	gock.New(testBaseUrl).
+		Get("network").
+		Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24"}]}]`)
+
+	code, stdout, stderr := runTest("", "network", "plan", "-supernets", "10.0.0.0/16", "-sizes", "/23", "-o", "jsonpath={.Subnets[0].Cidr}", "mgmt")
+	if code != exitOK {
+		t.Fatalf("got exit code %d: %s", code, stderr)
+	}
+
+	if stdout != "10.0.2.0/23" {
+		t.Errorf("got unexpected subnet: %s", stdout)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"bogus"},
+		{"node"},
+		{"node", "bogus"},
+		{"node", "get"},
+		{"ipam", "release", "not-an-ip"},
+		{"ipam", "reserve"},
+		{"ipam", "reserve", "-on-conflict", "bogus", "10.0.0.1"},
+		{"ipam", "reserve-dual", "-mac", "00:01:02:03:04:05", "-v4", "10.0.0.0/24"},
+		{"network", "plan", "mgmt"},
+		{"ipam", "commit"},
+		{"ipam", "list"},
+		{"ipam", "list", "-mac", "00:01:02:03:04:05", "-host", "node-000"},
+		{"node", "list", "-o", "xml"},
+	} {
+		code, _, _ := runTest("", args...)
+		if code != exitUsage {
+			t.Errorf("got wrong exit code for %v: expected %d, got %d", args, exitUsage, code)
+		}
+	}
+}
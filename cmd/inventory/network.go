@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func init() {
+	registerResource(&resource{
+		Name:        "network",
+		Description: "manage networks",
+		Commands: append(crudCommands("network", &objectOps{
+			New: func() interface{} { return types.NewNetwork() },
+			Get: func(inv *client.InventoryApi, id string) (interface{}, error) {
+				return inv.Network().Get(id)
+			},
+			List: func(inv *client.InventoryApi) (interface{}, error) {
+				return inv.Network().GetAll()
+			},
+			Create: func(inv *client.InventoryApi, obj interface{}) error {
+				return inv.Network().Create(obj.(*types.Network))
+			},
+			Update: func(inv *client.InventoryApi, obj interface{}) error {
+				return inv.Network().Update(obj.(*types.Network))
+			},
+			Delete: func(inv *client.InventoryApi, id string) error {
+				return inv.Network().Delete(&types.Network{Name: id})
+			},
+		}), &command{
+			Name:        "plan",
+			Args:        "-supernets list -sizes list [-create] <name>",
+			Description: "carve free subnets for a new network",
+			Run:         networkPlan,
+		}),
+	})
+}
+
+func networkPlan(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "network plan", "-supernets list -sizes list [-create] <name>")
+	supernetList := fs.String("supernets", "", "comma separated list of cidrs to carve subnets from")
+	sizeList := fs.String("sizes", "24", "comma separated list of prefix lengths, optionally preceded by v4/ or v6/, one subnet is created for each")
+	domain := fs.String("domain", "", "dns domain of the network")
+	mtu := fs.Uint("mtu", 1500, "mtu of the network")
+	static := fs.String("static", "", "static allocation method for each subnet")
+	dynamic := fs.String("dynamic", "", "dynamic allocation method for each subnet")
+	create := fs.Bool("create", false, "create the network rather than just printing it")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 1); err != nil {
+		return err
+	}
+
+	if *supernetList == "" {
+		fs.Usage()
+		return usagef("at least one supernet is required")
+	}
+
+	supernets := []*net.IPNet{}
+	for _, s := range strings.Split(*supernetList, ",") {
+		_, supernet, err := net.ParseCIDR(strings.TrimSpace(s))
+		if err != nil {
+			return usagef("invalid supernet: %v", err)
+		}
+		supernets = append(supernets, supernet)
+	}
+
+	plan := &client.NetworkPlan{
+		Name:                    fs.Arg(0),
+		Domain:                  *domain,
+		MTU:                     *mtu,
+		StaticAllocationMethod:  *static,
+		DynamicAllocationMethod: *dynamic,
+	}
+	for _, s := range strings.Split(*sizeList, ",") {
+		size, err := client.ParseSubnetSize(s)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		plan.Sizes = append(plan.Sizes, size)
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	planner, err := inv.NewSubnetPlanner(supernets...)
+	if err != nil {
+		return err
+	}
+
+	network, err := planner.PlanNetwork(plan)
+	if err != nil {
+		return err
+	}
+
+	if *create {
+		if err := inv.Network().Create(network); err != nil {
+			return err
+		}
+	}
+	return p.Print(c.Stdout, network)
+}
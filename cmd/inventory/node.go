@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func init() {
+	registerResource(&resource{
+		Name:        "node",
+		Description: "manage nodes",
+		Commands: crudCommands("node", &objectOps{
+			New: func() interface{} { return types.NewNode() },
+			Get: func(inv *client.InventoryApi, id string) (interface{}, error) {
+				return inv.Node().Get(id)
+			},
+			List: func(inv *client.InventoryApi) (interface{}, error) {
+				return inv.Node().GetAll()
+			},
+			Create: func(inv *client.InventoryApi, obj interface{}) error {
+				return inv.Node().Create(obj.(*types.Node))
+			},
+			Update: func(inv *client.InventoryApi, obj interface{}) error {
+				return inv.Node().Update(obj.(*types.Node))
+			},
+			Delete: func(inv *client.InventoryApi, id string) error {
+				return inv.Node().Delete(&types.Node{InventoryID: id})
+			},
+		}),
+	})
+}
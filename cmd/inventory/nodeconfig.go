@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net"
+)
+
+func init() {
+	registerResource(&resource{
+		Name:        "nodeconfig",
+		Description: "show the rendered configuration of nodes",
+		Commands: []*command{
+			{Name: "get", Args: "<id> | -mac <mac>", Description: "show a node's configuration", Run: nodeConfigGet},
+			{Name: "list", Description: "list the configuration of all nodes", Run: nodeConfigList},
+		},
+	})
+}
+
+func nodeConfigGet(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "nodeconfig get", "<id> | -mac <mac>")
+	macString := fs.String("mac", "", "look up the node by the mac address of one of its nics")
+	output := addOutputFlags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	nargs := 1
+	if *macString != "" {
+		nargs = 0
+	}
+	if err := checkArgs(fs, nargs); err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	if *macString != "" {
+		mac, err := net.ParseMAC(*macString)
+		if err != nil {
+			return usagef("invalid mac address: %v", err)
+		}
+		node, err := inv.NodeConfig().GetByMac(mac)
+		if err != nil {
+			return err
+		}
+		return p.Print(c.Stdout, node)
+	}
+
+	node, err := inv.NodeConfig().Get(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, node)
+}
+
+func nodeConfigList(c *cmdContext, args []string) error {
+	fs := newFlagSet(c, "nodeconfig list", "")
+	output := addOutputFlags(fs)
+	if err := parseFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	p, err := output.printer()
+	if err != nil {
+		return err
+	}
+
+	inv, err := c.Inventory()
+	if err != nil {
+		return err
+	}
+
+	nodes, err := inv.NodeConfig().GetAll()
+	if err != nil {
+		return err
+	}
+	return p.Print(c.Stdout, nodes)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func init() {
+	registerResource(&resource{
+		Name:        "system",
+		Description: "manage systems",
+		Commands: crudCommands("system", &objectOps{
+			New: func() interface{} { return types.NewSystem() },
+			Get: func(inv *client.InventoryApi, id string) (interface{}, error) {
+				return inv.System().Get(id)
+			},
+			List: func(inv *client.InventoryApi) (interface{}, error) {
+				return inv.System().GetAll()
+			},
+			Create: func(inv *client.InventoryApi, obj interface{}) error {
+				return inv.System().Create(obj.(*types.System))
+			},
+			Update: func(inv *client.InventoryApi, obj interface{}) error {
+				return inv.System().Update(obj.(*types.System))
+			},
+			Delete: func(inv *client.InventoryApi, id string) error {
+				return inv.System().Delete(&types.System{Name: id})
+			},
+		}),
+	})
+}
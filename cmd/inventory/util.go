@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/printer"
+)
+
+// newFlagSet returns a flag set for an action, writing usage to stderr
+func newFlagSet(c *cmdContext, name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.Stderr, "Usage: inventory %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args, requiring exactly nargs positional arguments
+func parseFlags(fs *flag.FlagSet, args []string, nargs int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return checkArgs(fs, nargs)
+}
+
+// checkArgs requires exactly nargs positional arguments after parsing
+func checkArgs(fs *flag.FlagSet, nargs int) error {
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return usagef("expected %d argument(s), got %d", nargs, fs.NArg())
+	}
+	return nil
+}
+
+// readObject decodes a YAML or JSON object from filename, or stdin if filename is "-"
+func readObject(c *cmdContext, filename string, obj interface{}) error {
+	var r io.Reader = c.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	err = printer.FromYAML(data, obj)
+	if err != nil {
+		return fmt.Errorf("unable to parse object: %v", err)
+	}
+	return nil
+}
+
+// outputFlags holds the flags controlling how objects are printed
+type outputFlags struct {
+	format    string
+	columns   string
+	noHeaders bool
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	o := &outputFlags{}
+	fs.StringVar(&o.format, "o", printer.FormatTable, fmt.Sprintf("output format, one of: %s", strings.Join(printer.Formats, ", ")))
+	fs.StringVar(&o.columns, "columns", "", "comma separated list of columns to show in table and csv output")
+	fs.BoolVar(&o.noHeaders, "no-headers", false, "omit headers from table and csv output")
+	return o
+}
+
+// printer returns the printer selected by the flags
+func (o *outputFlags) printer() (printer.Printer, error) {
+	opts := &printer.Options{Format: o.format, NoHeaders: o.noHeaders}
+	if o.columns != "" {
+		opts.Columns = strings.Split(o.columns, ",")
+	}
+
+	p, err := printer.New(opts)
+	if err != nil {
+		return nil, usagef("%v", err)
+	}
+	return p, nil
+}
+
+// objectOps adapts an inventory object client for use by crudCommands
+type objectOps struct {
+	New    func() interface{}
+	Get    func(inv *client.InventoryApi, id string) (interface{}, error)
+	List   func(inv *client.InventoryApi) (interface{}, error)
+	Create func(inv *client.InventoryApi, obj interface{}) error
+	Update func(inv *client.InventoryApi, obj interface{}) error
+	Delete func(inv *client.InventoryApi, id string) error
+}
+
+// crudCommands builds get, list, create, update and delete actions for a resource
+func crudCommands(kind string, ops *objectOps) []*command {
+	return []*command{
+		{
+			Name:        "get",
+			Args:        "<id>",
+			Description: fmt.Sprintf("show a %s", kind),
+			Run: func(c *cmdContext, args []string) error {
+				fs := newFlagSet(c, kind+" get", "<id>")
+				output := addOutputFlags(fs)
+				if err := parseFlags(fs, args, 1); err != nil {
+					return err
+				}
+				p, err := output.printer()
+				if err != nil {
+					return err
+				}
+
+				inv, err := c.Inventory()
+				if err != nil {
+					return err
+				}
+				obj, err := ops.Get(inv, fs.Arg(0))
+				if err != nil {
+					return err
+				}
+				return p.Print(c.Stdout, obj)
+			},
+		},
+		{
+			Name:        "list",
+			Description: fmt.Sprintf("list all %ss", kind),
+			Run: func(c *cmdContext, args []string) error {
+				fs := newFlagSet(c, kind+" list", "")
+				output := addOutputFlags(fs)
+				if err := parseFlags(fs, args, 0); err != nil {
+					return err
+				}
+				p, err := output.printer()
+				if err != nil {
+					return err
+				}
+
+				inv, err := c.Inventory()
+				if err != nil {
+					return err
+				}
+				objs, err := ops.List(inv)
+				if err != nil {
+					return err
+				}
+				return p.Print(c.Stdout, objs)
+			},
+		},
+		{
+			Name:        "create",
+			Args:        "[-f file]",
+			Description: fmt.Sprintf("create a %s", kind),
+			Run:         writeCommand(kind, "create", ops.New, ops.Create),
+		},
+		{
+			Name:        "update",
+			Args:        "[-f file]",
+			Description: fmt.Sprintf("replace an existing %s", kind),
+			Run:         writeCommand(kind, "update", ops.New, ops.Update),
+		},
+		{
+			Name:        "delete",
+			Args:        "<id>",
+			Description: fmt.Sprintf("delete a %s", kind),
+			Run: func(c *cmdContext, args []string) error {
+				fs := newFlagSet(c, kind+" delete", "<id>")
+				if err := parseFlags(fs, args, 1); err != nil {
+					return err
+				}
+				inv, err := c.Inventory()
+				if err != nil {
+					return err
+				}
+				return ops.Delete(inv, fs.Arg(0))
+			},
+		},
+	}
+}
+
+func writeCommand(kind, action string, newObj func() interface{}, write func(*client.InventoryApi, interface{}) error) func(*cmdContext, []string) error {
+	return func(c *cmdContext, args []string) error {
+		fs := newFlagSet(c, kind+" "+action, "[-f file]")
+		filename := fs.String("f", "-", "file containing the object, - for stdin")
+		if err := parseFlags(fs, args, 0); err != nil {
+			return err
+		}
+
+		obj := newObj()
+		if err := readObject(c, *filename, obj); err != nil {
+			return err
+		}
+
+		inv, err := c.Inventory()
+		if err != nil {
+			return err
+		}
+		return write(inv, obj)
+	}
+}
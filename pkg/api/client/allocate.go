@@ -0,0 +1,74 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// AllocationError is returned by AllocateSet when one of the requests fails
+type AllocationError struct {
+	// Index is the position of the failed request
+	Index   int
+	Request *types.IpamIpRequest
+	Err     error
+
+	// Orphaned lists reservations made earlier in the set that could not be
+	// released during rollback, along with the reasons in RollbackErrors.
+	Orphaned       types.IPReservationList
+	RollbackErrors []error
+}
+
+func (e *AllocationError) Error() string {
+	msg := fmt.Sprintf("unable to allocate address %d (subnet %q): %v", e.Index, e.Request.Subnet, e.Err)
+	if len(e.Orphaned) == 0 {
+		return msg
+	}
+
+	orphaned := make([]string, 0, len(e.Orphaned))
+	for idx, r := range e.Orphaned {
+		orphaned = append(orphaned, fmt.Sprintf("%s (%v)", r.IP, e.RollbackErrors[idx]))
+	}
+	return fmt.Sprintf("%s; unable to release: %s", msg, strings.Join(orphaned, ", "))
+}
+
+// AllocateSet reserves an address for each request, in order.  If any
+// allocation fails, every reservation already made by this call is deleted
+// and an *AllocationError is returned.
+func (i *IPAM) AllocateSet(requests ...*types.IpamIpRequest) (types.IPReservationList, error) {
+	return i.allocate(requests, nil)
+}
+
+// allocate reserves each request, using the corresponding entry of ips if it
+// is non-nil, rolling back on failure
+func (i *IPAM) allocate(requests []*types.IpamIpRequest, ips []net.IP) (types.IPReservationList, error) {
+	reservations := make(types.IPReservationList, 0, len(requests))
+	for idx, request := range requests {
+		var ip net.IP
+		if idx < len(ips) {
+			ip = ips[idx]
+		}
+
+		reservation, err := i.CreateIPReservation(request, ip)
+		if err != nil {
+			allocErr := &AllocationError{Index: idx, Request: request, Err: err}
+			i.rollback(reservations, allocErr)
+			return nil, allocErr
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, nil
+}
+
+// rollback deletes reservations in reverse order, recording any that remain
+func (i *IPAM) rollback(reservations types.IPReservationList, allocErr *AllocationError) {
+	for idx := len(reservations) - 1; idx >= 0; idx-- {
+		err := i.DeleteIPReservation(reservations[idx])
+		if err != nil && !IsNotFound(err) {
+			allocErr.Orphaned = append(allocErr.Orphaned, reservations[idx])
+			allocErr.RollbackErrors = append(allocErr.RollbackErrors, err)
+		}
+	}
+}
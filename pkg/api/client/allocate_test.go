@@ -0,0 +1,98 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestAllocateSet(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip").
+		BodyString(`"subnet":"10.0.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip").
+		BodyString(`"subnet":"10.1.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:06","ip": "10.1.0.2/24"}`)
+
+	reservations, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().AllocateSet(
+		&types.IpamIpRequest{Subnet: "10.0.0.0/24", HwAddress: "00:01:02:03:04:05"},
+		&types.IpamIpRequest{Subnet: "10.1.0.0/24", HwAddress: "00:01:02:03:04:06"},
+	)
+	if err != nil {
+		t.Fatalf("unable to allocate set: %v", err)
+	}
+
+	if len(reservations) != 2 || reservations[1].IP.String() != "10.1.0.2/24" {
+		t.Errorf("got unexpected reservations: %v", reservations)
+	}
+}
+
+func TestAllocateSetRollback(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip").
+		BodyString(`"subnet":"10.0.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip").
+		BodyString(`"subnet":"10.1.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:06","ip": "10.1.0.2/24"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip").
+		BodyString(`"subnet":"10.2.0.0/24"`).
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "address in use"}`)
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.1.0.2").
+		Reply(http.StatusOK)
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.2").
+		Reply(http.StatusInternalServerError).
+		BodyString(`{"status": "Internal Server Error", "error": "oops"}`)
+
+	reservations, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().AllocateSet(
+		&types.IpamIpRequest{Subnet: "10.0.0.0/24", HwAddress: "00:01:02:03:04:05"},
+		&types.IpamIpRequest{Subnet: "10.1.0.0/24", HwAddress: "00:01:02:03:04:06"},
+		&types.IpamIpRequest{Subnet: "10.2.0.0/24", HwAddress: "00:01:02:03:04:07"},
+	)
+	if reservations != nil {
+		t.Errorf("expected no reservations, got %v", reservations)
+	}
+
+	allocErr, ok := err.(*AllocationError)
+	if !ok {
+		t.Fatalf("expected an allocation error, got %v", err)
+	}
+
+	if allocErr.Index != 2 || !IsConflict(err) {
+		t.Errorf("got unexpected allocation error: %v", err)
+	}
+
+	if len(allocErr.Orphaned) != 1 || allocErr.Orphaned[0].IP.String() != "10.0.0.2/24" {
+		t.Errorf("expected 10.0.0.2 to be orphaned: %v", allocErr.Orphaned)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("not all reservations were released")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/PolarGeospatialCenter/inventory/pkg/lambdautils"
 	"gopkg.in/resty.v1"
@@ -13,6 +14,38 @@ var (
 	ErrConflict = errors.New("conflict")
 )
 
+// ApiError is returned when the API responds with an unsuccessful status code
+type ApiError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// IsNotFound returns true if err indicates that the requested object doesn't exist
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*ApiError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict returns true if err indicates a conflict with an existing object
+func IsConflict(err error) bool {
+	if err == ErrConflict {
+		return true
+	}
+	switch e := err.(type) {
+	case *ConflictError:
+		return true
+	case *AllocationError:
+		return IsConflict(e.Err)
+	}
+	apiErr, ok := err.(*ApiError)
+	return ok && apiErr.StatusCode == http.StatusConflict
+}
+
 func UnmarshalApiResponse(r *resty.Response, obj interface{}) error {
 	success := r.StatusCode() >= 200 && r.StatusCode() < 300
 
@@ -25,9 +58,13 @@ func UnmarshalApiResponse(r *resty.Response, obj interface{}) error {
 		errorResponse := &lambdautils.ErrorResponse{}
 		err := json.Unmarshal(r.Body(), errorResponse)
 		if err != nil {
-			return fmt.Errorf("unable to unmarshal error response: %v", err)
+			return &ApiError{
+				StatusCode: r.StatusCode(),
+				Status:     http.StatusText(r.StatusCode()),
+				Message:    fmt.Sprintf("unable to unmarshal error response: %v", err),
+			}
 		}
-		return fmt.Errorf("%s: %s", errorResponse.Status, errorResponse.ErrorMessage)
+		return &ApiError{StatusCode: r.StatusCode(), Status: errorResponse.Status, Message: errorResponse.ErrorMessage}
 	}
 	return nil
 }
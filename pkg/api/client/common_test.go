@@ -0,0 +1,52 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestApiErrorNotFound(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("node/test-000").
+		Reply(http.StatusNotFound).BodyString(`{"status": "Not Found", "error": "object not found"}`)
+
+	_, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).Node().Get("test-000")
+	if !IsNotFound(err) {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+
+	if err.Error() != "Not Found: object not found" {
+		t.Errorf("got unexpected error message: %v", err)
+	}
+}
+
+func TestApiErrorConflictWithoutBody(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("network").
+		Reply(http.StatusConflict)
+
+	err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).Network().Create(&types.Network{Name: "testnet"})
+	if !IsConflict(err) {
+		t.Errorf("expected conflict error, got: %v", err)
+	}
+
+	if IsNotFound(err) {
+		t.Errorf("conflict reported as not found")
+	}
+}
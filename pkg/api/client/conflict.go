@@ -0,0 +1,174 @@
+package client
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/cidr"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// ConflictError describes why a reservation was rejected as a conflict
+type ConflictError struct {
+	IP net.IP
+
+	// Holder is the existing reservation of IP, or nil if it isn't reserved or
+	// couldn't be retrieved
+	Holder *types.IPReservation
+
+	// Existing is set when the requested mac already has a reservation in the
+	// subnet, in which case no other address in the subnet can be reserved
+	// for it either
+	Existing *types.IPReservation
+}
+
+func (e *ConflictError) Error() string {
+	if e.Existing != nil {
+		return fmt.Sprintf("%s: %s already has a reservation for %s", ErrConflict, e.Existing.MAC, e.Existing.IP.IP)
+	}
+	if e.Holder == nil {
+		return fmt.Sprintf("%s: %s is already reserved", ErrConflict, e.IP)
+	}
+
+	holder := e.Holder.MAC.String()
+	if e.Holder.HostInformation != "" {
+		holder = fmt.Sprintf("%s (%s)", e.Holder.HostInformation, holder)
+	}
+	return fmt.Sprintf("%s: %s is already reserved by %s", ErrConflict, e.IP, holder)
+}
+
+// Conflict looks up why reserving ip for the request returned ErrConflict:
+// either the address is held by another reservation, or the requested mac
+// already has a reservation in the same subnet.
+func (i *IPAM) Conflict(new *types.IpamIpRequest, ip net.IP) *ConflictError {
+	conflict := &ConflictError{IP: ip}
+	if holder, err := i.GetIPReservation(ip); err == nil {
+		conflict.Holder = holder
+	}
+
+	mac, err := net.ParseMAC(new.HwAddress)
+	if err != nil {
+		return conflict
+	}
+	reservations, err := i.GetIPReservationsByMAC(mac)
+	if err != nil {
+		return conflict
+	}
+	for _, r := range reservations {
+		if r.IP != nil && (&net.IPNet{IP: r.IP.IP.Mask(r.IP.Mask), Mask: r.IP.Mask}).Contains(ip) {
+			conflict.Existing = r
+			break
+		}
+	}
+	return conflict
+}
+
+// ConflictPolicy determines what CreateIPReservationWithPolicy does when the
+// requested address is already reserved
+type ConflictPolicy int
+
+const (
+	// ConflictFail returns a *ConflictError
+	ConflictFail ConflictPolicy = iota
+
+	// ConflictNextFree tries each following address in the subnet, wrapping
+	// around to the start, until one is reserved or MaxConflictRetries is reached.
+	ConflictNextFree
+
+	// ConflictDynamic lets the server choose an address from the subnet
+	ConflictDynamic
+)
+
+// MaxConflictRetries limits the addresses tried by ConflictNextFree
+var MaxConflictRetries = 64
+
+// ParseConflictPolicy converts "fail", "next" or "dynamic" to a ConflictPolicy
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch s {
+	case "", "fail":
+		return ConflictFail, nil
+	case "next":
+		return ConflictNextFree, nil
+	case "dynamic":
+		return ConflictDynamic, nil
+	}
+	return ConflictFail, fmt.Errorf("unknown conflict policy: %s", s)
+}
+
+// CreateIPReservationWithPolicy reserves ip, applying policy if it is already
+// reserved.  The subnet is taken from the request if set, otherwise from the
+// conflicting reservation.  On a conflict a *ConflictError describing it is
+// returned if no alternative can be reserved, or immediately if the mac
+// already has a reservation in the subnet, since no alternative could be.
+func (i *IPAM) CreateIPReservationWithPolicy(new *types.IpamIpRequest, ip net.IP, policy ConflictPolicy) (*types.IPReservation, error) {
+	reservation, err := i.CreateIPReservation(new, ip)
+	if err != ErrConflict || ip == nil {
+		return reservation, err
+	}
+
+	conflict := i.Conflict(new, ip)
+	if policy == ConflictFail || conflict.Existing != nil {
+		return nil, conflict
+	}
+
+	subnet, err := conflictSubnet(new, conflict)
+	if err != nil {
+		return nil, err
+	}
+	if subnet == nil {
+		return nil, conflict
+	}
+
+	switch policy {
+	case ConflictNextFree:
+		return i.createNextFree(new, ip, subnet, conflict)
+	case ConflictDynamic:
+		dynamic := *new
+		dynamic.Subnet = subnet.String()
+		reservation, err := i.CreateIPReservation(&dynamic, nil)
+		if err == ErrConflict {
+			return nil, conflict
+		}
+		return reservation, err
+	}
+	return nil, fmt.Errorf("unknown conflict policy: %d", policy)
+}
+
+func conflictSubnet(new *types.IpamIpRequest, conflict *ConflictError) (*net.IPNet, error) {
+	if new.Subnet != "" {
+		_, subnet, err := net.ParseCIDR(new.Subnet)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse requested subnet: %v", err)
+		}
+		return subnet, nil
+	}
+	if conflict.Holder != nil && conflict.Holder.IP != nil {
+		return &net.IPNet{IP: conflict.Holder.IP.IP.Mask(conflict.Holder.IP.Mask), Mask: conflict.Holder.IP.Mask}, nil
+	}
+	return nil, nil
+}
+
+func (i *IPAM) createNextFree(new *types.IpamIpRequest, ip net.IP, subnet *net.IPNet, conflict *ConflictError) (*types.IPReservation, error) {
+	first, last := cidr.HostRange(subnet)
+	if first == nil {
+		return nil, conflict
+	}
+
+	candidate := ip
+	for attempt := 0; attempt < MaxConflictRetries; attempt++ {
+		if cidr.Compare(candidate, last) >= 0 || !subnet.Contains(candidate) {
+			candidate = first
+		} else {
+			candidate = cidr.Next(candidate)
+		}
+		if candidate.Equal(ip) {
+			break
+		}
+
+		reservation, err := i.CreateIPReservation(new, candidate)
+		if err != ErrConflict {
+			return reservation, err
+		}
+	}
+	return nil, conflict
+}
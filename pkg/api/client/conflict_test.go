@@ -0,0 +1,182 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestIPReservationCreateConflict(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.5").
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "address in use"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.5").
+		Reply(http.StatusOK).
+		BodyString(`{"HostInformation": "node-000", "mac": "00:01:02:03:04:05","ip": "10.0.0.5/24"}`)
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("mac", "00:01:02:03:04:06").
+		Reply(http.StatusOK).
+		BodyString(`[]`)
+
+	request := &types.IpamIpRequest{HwAddress: "00:01:02:03:04:06"}
+	ipam := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM()
+	_, err := ipam.CreateIPReservation(request, net.ParseIP("10.0.0.5"))
+	if err != ErrConflict {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+
+	conflict := ipam.Conflict(request, net.ParseIP("10.0.0.5"))
+	err = conflict
+	if !IsConflict(err) {
+		t.Errorf("conflict error not recognized by IsConflict")
+	}
+
+	if conflict.Existing != nil {
+		t.Errorf("unexpected existing reservation for the mac: %v", conflict.Existing)
+	}
+
+	if conflict.Holder == nil || conflict.Holder.MAC.String() != "00:01:02:03:04:05" {
+		t.Errorf("holder not set correctly: %v", conflict.Holder)
+	}
+
+	if !strings.Contains(err.Error(), "node-000 (00:01:02:03:04:05)") {
+		t.Errorf("holder missing from error message: %v", err)
+	}
+}
+
+func TestIPReservationCreateNextFree(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.5").
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "address in use"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.5").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.5/29"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("mac", "00:01:02:03:04:06").
+		Reply(http.StatusOK).
+		BodyString(`[]`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.6").
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "address in use"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.1").
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:06","ip": "10.0.0.1/29"}`)
+
+	request := &types.IpamIpRequest{HwAddress: "00:01:02:03:04:06"}
+	reservation, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().CreateIPReservationWithPolicy(request, net.ParseIP("10.0.0.5"), ConflictNextFree)
+	if err != nil {
+		t.Fatalf("unable to reserve next free address: %v", err)
+	}
+
+	if !reservation.IP.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("got unexpected address: %s", reservation.IP)
+	}
+}
+
+func TestIPReservationCreateDynamicFallback(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.5").
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "address in use"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.5").
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("mac", "00:01:02:03:04:06").
+		Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:06","ip": "10.1.0.9/24"}]`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip$").
+		BodyString(`"subnet":"10.0.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:06","ip": "10.0.0.17/24"}`)
+
+	request := &types.IpamIpRequest{Subnet: "10.0.0.0/24", HwAddress: "00:01:02:03:04:06"}
+	reservation, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().CreateIPReservationWithPolicy(request, net.ParseIP("10.0.0.5"), ConflictDynamic)
+	if err != nil {
+		t.Fatalf("unable to fall back to dynamic allocation: %v", err)
+	}
+
+	if !reservation.IP.IP.Equal(net.ParseIP("10.0.0.17")) {
+		t.Errorf("got unexpected address: %s", reservation.IP)
+	}
+}
+
+func TestIPReservationCreateMACConflict(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	// the mac already has a reservation in the subnet, so retrying other
+	// addresses can't succeed
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.5").
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "a reservation for this mac already exists in this subnet"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.5").
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("mac", "00:01:02:03:04:06").
+		Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:06","ip": "10.0.0.2/29"}]`)
+
+	request := &types.IpamIpRequest{HwAddress: "00:01:02:03:04:06"}
+	_, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().CreateIPReservationWithPolicy(request, net.ParseIP("10.0.0.5"), ConflictNextFree)
+
+	conflict, ok := err.(*ConflictError)
+	if !ok {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+
+	if conflict.Existing == nil || !conflict.Existing.IP.IP.Equal(net.ParseIP("10.0.0.2")) {
+		t.Errorf("existing reservation not set correctly: %v", conflict.Existing)
+	}
+
+	if !strings.Contains(err.Error(), "00:01:02:03:04:06 already has a reservation for 10.0.0.2") {
+		t.Errorf("existing reservation missing from error message: %v", err)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("not all expected requests were made: %d pending", len(gock.Pending()))
+	}
+}
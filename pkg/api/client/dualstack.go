@@ -0,0 +1,54 @@
+package client
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/cidr"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// DualStackRequest describes a pair of IPv4 and IPv6 reservations for one MAC
+type DualStackRequest struct {
+	MAC      net.HardwareAddr
+	V4Subnet *net.IPNet
+	V6Subnet *net.IPNet
+	TTL      string
+	Metadata types.Metadata
+
+	// SLAAC reserves the EUI-64 address of MAC within V6Subnet rather than
+	// letting the server choose one
+	SLAAC bool
+}
+
+// ReserveDualStack reserves an IPv4 and an IPv6 address for the request's MAC.
+// If either reservation fails neither is kept and an *AllocationError is
+// returned.
+func (i *IPAM) ReserveDualStack(req *DualStackRequest) (*types.IPReservation, *types.IPReservation, error) {
+	if req.V4Subnet == nil || !cidr.IsV4(req.V4Subnet) {
+		return nil, nil, fmt.Errorf("unable to reserve dual stack addresses: an ipv4 subnet is required")
+	}
+	if req.V6Subnet == nil || cidr.IsV4(req.V6Subnet) {
+		return nil, nil, fmt.Errorf("unable to reserve dual stack addresses: an ipv6 subnet is required")
+	}
+
+	ips := []net.IP{nil, nil}
+	if req.SLAAC {
+		ip, err := cidr.EUI64(req.V6Subnet, req.MAC)
+		if err != nil {
+			return nil, nil, err
+		}
+		ips[1] = ip
+	}
+
+	requests := []*types.IpamIpRequest{
+		{Subnet: req.V4Subnet.String(), HwAddress: req.MAC.String(), TTL: req.TTL, Metadata: req.Metadata},
+		{Subnet: req.V6Subnet.String(), HwAddress: req.MAC.String(), TTL: req.TTL, Metadata: req.Metadata},
+	}
+
+	reservations, err := i.allocate(requests, ips)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reservations[0], reservations[1], nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestIPReservationGetIPv6(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/2001:db8::10$").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "2001:db8::10/64"}`)
+
+	reservation, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservation(net.ParseIP("2001:db8::10"))
+	if err != nil {
+		t.Fatalf("unable to get ip reservation: %v", err)
+	}
+
+	if reservation.IP.String() != "2001:db8::10/64" {
+		t.Errorf("got unexpected reservation: %s", reservation.IP)
+	}
+}
+
+func TestReserveDualStack(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip$").
+		BodyString(`"subnet":"10.0.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:25:96:12:34:56","ip": "10.0.0.2/24"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/2001:db8::225:96ff:fe12:3456$").
+		BodyString(`"subnet":"2001:db8::/64"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:25:96:12:34:56","ip": "2001:db8::225:96ff:fe12:3456/64"}`)
+
+	mac, _ := net.ParseMAC("00:25:96:12:34:56")
+	_, v4Subnet, _ := net.ParseCIDR("10.0.0.0/24")
+	_, v6Subnet, _ := net.ParseCIDR("2001:db8::/64")
+
+	v4, v6, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().ReserveDualStack(&DualStackRequest{MAC: mac, V4Subnet: v4Subnet, V6Subnet: v6Subnet, SLAAC: true})
+	if err != nil {
+		t.Fatalf("unable to reserve dual stack addresses: %v", err)
+	}
+
+	if v4.IP.IP.String() != "10.0.0.2" || v6.IP.IP.String() != "2001:db8::225:96ff:fe12:3456" {
+		t.Errorf("got unexpected reservations: %s %s", v4.IP, v6.IP)
+	}
+}
+
+func TestReserveDualStackRollback(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip$").
+		BodyString(`"subnet":"10.0.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:25:96:12:34:56","ip": "10.0.0.2/24"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip$").
+		BodyString(`"subnet":"2001:db8::/64"`).
+		Reply(http.StatusBadRequest).
+		BodyString(`{"status": "Bad Request", "error": "dynamic allocation disabled"}`)
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK)
+
+	mac, _ := net.ParseMAC("00:25:96:12:34:56")
+	_, v4Subnet, _ := net.ParseCIDR("10.0.0.0/24")
+	_, v6Subnet, _ := net.ParseCIDR("2001:db8::/64")
+
+	_, _, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().ReserveDualStack(&DualStackRequest{MAC: mac, V4Subnet: v4Subnet, V6Subnet: v6Subnet})
+	if _, ok := err.(*AllocationError); !ok {
+		t.Fatalf("expected an allocation error, got %v", err)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("ipv4 reservation not released")
+	}
+}
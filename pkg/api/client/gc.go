@@ -0,0 +1,213 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// GCReason explains why a reservation is eligible for garbage collection
+type GCReason string
+
+const (
+	// GCOrphaned reservations belong to a MAC that isn't on any node
+	GCOrphaned GCReason = "orphaned"
+
+	// GCExpired reservations have an End time in the past
+	GCExpired GCReason = "expired"
+)
+
+const (
+	DefaultGCExpiredGrace = 24 * time.Hour
+	DefaultGCOrphanGrace  = time.Hour
+)
+
+// GCCandidate is a reservation selected for deletion
+type GCCandidate struct {
+	Reservation *types.IPReservation `json:"reservation"`
+	Reason      GCReason             `json:"reason"`
+
+	// Deleted and Error record the result of Collect
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// GCReport lists the reservations examined by a GarbageCollector
+type GCReport struct {
+	Scanned    int            `json:"scanned"`
+	Excluded   int            `json:"excluded"`
+	Candidates []*GCCandidate `json:"candidates"`
+
+	// Skipped lists subnets whose reservations couldn't be listed
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// GarbageCollector finds and deletes reservations that are expired or whose
+// MAC no longer belongs to any node
+type GarbageCollector struct {
+	IPAM *IPAM
+
+	// Networks limits collection to the named networks, or all networks if empty
+	Networks []string
+
+	// ExpiredGrace is how long after its End time an expired reservation is kept
+	ExpiredGrace time.Duration
+
+	// OrphanGrace is how long after its Start time an orphaned reservation is
+	// kept, allowing time for a new node to be registered.  Orphans without a
+	// Start time are always eligible.
+	OrphanGrace time.Duration
+
+	// Exclude lists IP addresses, CIDRs or MAC addresses that are never collected
+	Exclude []string
+}
+
+// NewGarbageCollector returns a garbage collector with default grace periods
+func (i *IPAM) NewGarbageCollector() *GarbageCollector {
+	return &GarbageCollector{IPAM: i, ExpiredGrace: DefaultGCExpiredGrace, OrphanGrace: DefaultGCOrphanGrace}
+}
+
+type gcExclusions struct {
+	ips  []net.IP
+	nets []*net.IPNet
+	macs []net.HardwareAddr
+}
+
+func parseGCExclusions(exclude []string) (*gcExclusions, error) {
+	e := &gcExclusions{}
+	for _, entry := range exclude {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			e.ips = append(e.ips, ip)
+		} else if _, n, err := net.ParseCIDR(entry); err == nil {
+			e.nets = append(e.nets, n)
+		} else if mac, err := net.ParseMAC(entry); err == nil {
+			e.macs = append(e.macs, mac)
+		} else {
+			return nil, fmt.Errorf("invalid exclusion, expected an ip, cidr or mac address: %s", entry)
+		}
+	}
+	return e, nil
+}
+
+func (e *gcExclusions) matches(r *types.IPReservation) bool {
+	for _, mac := range e.macs {
+		if r.MAC.String() == mac.String() {
+			return true
+		}
+	}
+	if r.IP == nil {
+		return false
+	}
+	for _, ip := range e.ips {
+		if ip.Equal(r.IP.IP) {
+			return true
+		}
+	}
+	for _, n := range e.nets {
+		if n.Contains(r.IP.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify examines reservations against the nodes at time now, returning a
+// report of those eligible for collection.  Reservations without a MAC
+// address are never considered orphaned.
+func (g *GarbageCollector) Classify(reservations types.IPReservationList, nodes []*types.Node, now time.Time) (*GCReport, error) {
+	exclusions, err := parseGCExclusions(g.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	macs := map[string]bool{}
+	for _, node := range nodes {
+		for _, iface := range node.Networks {
+			if iface == nil {
+				continue
+			}
+			for _, mac := range iface.NICs {
+				macs[mac.String()] = true
+			}
+		}
+	}
+
+	report := &GCReport{Candidates: []*GCCandidate{}}
+	for _, r := range reservations {
+		report.Scanned++
+		if exclusions.matches(r) {
+			report.Excluded++
+			continue
+		}
+
+		switch {
+		case r.End != nil && r.End.Add(g.ExpiredGrace).Before(now):
+			report.Candidates = append(report.Candidates, &GCCandidate{Reservation: r, Reason: GCExpired})
+		case len(r.MAC) > 0 && !macs[r.MAC.String()] && (r.Start == nil || r.Start.Add(g.OrphanGrace).Before(now)):
+			report.Candidates = append(report.Candidates, &GCCandidate{Reservation: r, Reason: GCOrphaned})
+		}
+	}
+	return report, nil
+}
+
+// Plan lists reservations and nodes and reports what would be collected
+// without deleting anything.  Subnets whose reservations can't be listed are
+// recorded in the report and otherwise ignored.
+func (g *GarbageCollector) Plan() (*GCReport, error) {
+	reservations, err := g.IPAM.getIPReservationsByNetworks(g.Networks)
+	skipped := SkippedSubnets(err)
+	if err != nil && skipped == nil {
+		return nil, err
+	}
+
+	nodes, err := g.IPAM.Inventory.Node().GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := g.Classify(reservations, nodes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, subnet := range skipped {
+		report.Skipped = append(report.Skipped, subnet.String())
+	}
+	return report, nil
+}
+
+// Collect deletes every candidate in the report, recording the outcome of
+// each.  Reservations that have already been deleted are treated as
+// collected.  An error is returned if any deletion failed.
+func (g *GarbageCollector) Collect(report *GCReport) error {
+	failed := 0
+	for _, c := range report.Candidates {
+		err := g.IPAM.DeleteIPReservation(c.Reservation)
+		if err != nil && !IsNotFound(err) {
+			c.Error = err.Error()
+			failed++
+			continue
+		}
+		c.Deleted = true
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("unable to delete %d of %d reservations", failed, len(report.Candidates))
+	}
+	return nil
+}
+
+// Run plans and collects in one step
+func (g *GarbageCollector) Run() (*GCReport, error) {
+	report, err := g.Plan()
+	if err != nil {
+		return nil, err
+	}
+	return report, g.Collect(report)
+}
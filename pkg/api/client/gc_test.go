@@ -0,0 +1,106 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestGCClassify(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Minute)
+	old := now.Add(-48 * time.Hour)
+
+	reservation := func(ip, mac string, start, end *time.Time) *types.IPReservation {
+		r := testReservation(ip, end)
+		r.MAC, _ = net.ParseMAC(mac)
+		r.Start = start
+		return r
+	}
+
+	reservations := types.IPReservationList{
+		reservation("10.0.0.2/24", "00:01:02:03:04:05", &old, nil),
+		reservation("10.0.0.3/24", "00:01:02:03:04:05", &old, &old),
+		reservation("10.0.0.4/24", "00:01:02:03:04:05", &old, &recent),
+		reservation("10.0.0.5/24", "00:01:02:03:04:06", &old, nil),
+		reservation("10.0.0.6/24", "00:01:02:03:04:07", &recent, nil),
+		reservation("10.0.0.7/24", "00:01:02:03:04:08", nil, nil),
+		reservation("10.0.1.2/24", "00:01:02:03:04:09", nil, nil),
+		reservation("10.0.0.8/24", "", &old, nil),
+	}
+
+	mac, _ := net.ParseMAC("00:01:02:03:04:05")
+	nodes := []*types.Node{{InventoryID: "test-000", Networks: types.NICInfoMap{"prov": &types.NetworkInterface{NICs: []net.HardwareAddr{mac}}}}}
+
+	gc := (&IPAM{}).NewGarbageCollector()
+	gc.Exclude = []string{"10.0.1.0/24"}
+	report, err := gc.Classify(reservations, nodes, now)
+	if err != nil {
+		t.Fatalf("unable to classify reservations: %v", err)
+	}
+
+	expected := map[string]GCReason{"10.0.0.3": GCExpired, "10.0.0.5": GCOrphaned, "10.0.0.7": GCOrphaned}
+	if len(report.Candidates) != len(expected) {
+		t.Fatalf("got unexpected candidates: %v", report.Candidates)
+	}
+	for _, c := range report.Candidates {
+		if expected[c.Reservation.IP.IP.String()] != c.Reason {
+			t.Errorf("got unexpected candidate %s: %s", c.Reservation.IP, c.Reason)
+		}
+	}
+
+	if report.Scanned != 8 || report.Excluded != 1 {
+		t.Errorf("got unexpected counts: scanned %d, excluded %d", report.Scanned, report.Excluded)
+	}
+
+	gc.Exclude = []string{"bogus"}
+	if _, err := gc.Classify(reservations, nodes, now); err == nil {
+		t.Errorf("expected an error for an invalid exclusion")
+	}
+}
+
+func TestGCRun(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("network", "prov").
+		Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24"},{"mac": "00:01:02:03:04:06","ip": "10.0.0.3/24"},{"mac": "00:01:02:03:04:07","ip": "10.0.0.4/24"}]`)
+	gock.New(testBaseUrl.String()).
+		Get("node").
+		Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Networks": {"prov": {"NICs": ["00:01:02:03:04:05"]}}}]`)
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.3").
+		Reply(http.StatusOK)
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.4").
+		Reply(http.StatusInternalServerError).
+		BodyString(`{"status": "Internal Server Error", "error": "oops"}`)
+
+	gc := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().NewGarbageCollector()
+	gc.Networks = []string{"prov"}
+	report, err := gc.Run()
+	if err == nil {
+		t.Errorf("expected an error for the failed deletion")
+	}
+
+	if report == nil || len(report.Candidates) != 2 {
+		t.Fatalf("got unexpected report: %v", report)
+	}
+
+	if !report.Candidates[0].Deleted || report.Candidates[1].Deleted || report.Candidates[1].Error == "" {
+		t.Errorf("deletion results not recorded correctly: %+v %+v", report.Candidates[0], report.Candidates[1])
+	}
+}
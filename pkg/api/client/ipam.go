@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 
 	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
 )
@@ -12,10 +13,16 @@ type IPAM struct {
 	Inventory *InventoryApi
 }
 
+// ipamIPPath returns the endpoint path for a single address.  IPv4-mapped
+// IPv6 addresses are written in their IPv4 form.
+func ipamIPPath(ip net.IP) string {
+	return "/ipam/ip/" + url.PathEscape(ip.String())
+}
+
 func (i *IPAM) GetIPReservation(ip net.IP) (*types.IPReservation, error) {
 	client := NewRestClient(i.Inventory.AwsConfigs...)
 
-	response, err := client.Client().NewRequest().Execute(http.MethodGet, i.Inventory.Url(fmt.Sprintf("/ipam/ip/%s", ip.String())))
+	response, err := client.Client().NewRequest().Execute(http.MethodGet, i.Inventory.Url(ipamIPPath(ip)))
 	if err != nil {
 		return nil, fmt.Errorf("unable to get ip reservation: %v", err)
 	}
@@ -44,7 +51,7 @@ func (i *IPAM) UpdateIPReservation(modified *types.IPReservation) (*types.IPRese
 	request := client.Client().NewRequest()
 	request.SetBody(modified)
 
-	response, err := request.Execute(http.MethodPut, i.Inventory.Url(fmt.Sprintf("/ipam/ip/%s", modified.IP.IP.String())))
+	response, err := request.Execute(http.MethodPut, i.Inventory.Url(ipamIPPath(modified.IP.IP)))
 	if err != nil {
 		return nil, fmt.Errorf("unable to get ip reservation: %v", err)
 	}
@@ -61,7 +68,10 @@ func (i *IPAM) UpdateIPReservation(modified *types.IPReservation) (*types.IPRese
 func (i *IPAM) DeleteIPReservation(reservation *types.IPReservation) error {
 	client := NewRestClient(i.Inventory.AwsConfigs...)
 
-	response, err := client.Client().NewRequest().Execute(http.MethodDelete, i.Inventory.Url(fmt.Sprintf("/ipam/ip/%s", reservation.IP.IP.String())))
+	request := client.Client().NewRequest()
+	request.SetBody(reservation)
+
+	response, err := request.Execute(http.MethodDelete, i.Inventory.Url(ipamIPPath(reservation.IP.IP)))
 	if err != nil {
 		return fmt.Errorf("unable to get ip reservation: %v", err)
 	}
@@ -70,6 +80,9 @@ func (i *IPAM) DeleteIPReservation(reservation *types.IPReservation) error {
 	return err
 }
 
+// CreateIPReservation reserves ip, or an address from the requested subnet if
+// ip is nil.  ErrConflict is returned if the server rejects the reservation
+// as a conflict, Conflict describes the cause.
 func (i *IPAM) CreateIPReservation(new *types.IpamIpRequest, ip net.IP) (*types.IPReservation, error) {
 	client := NewRestClient(i.Inventory.AwsConfigs...)
 
@@ -78,7 +91,7 @@ func (i *IPAM) CreateIPReservation(new *types.IpamIpRequest, ip net.IP) (*types.
 
 	url := "/ipam/ip"
 	if ip != nil {
-		url = fmt.Sprintf("/ipam/ip/%s", ip.String())
+		url = ipamIPPath(ip)
 	}
 	response, err := request.Execute(http.MethodPost, i.Inventory.Url(url))
 	if err != nil {
@@ -0,0 +1,279 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/cidr"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+var (
+	// MaxSubnetEnumeration is the largest number of addresses that will be
+	// looked up individually when the server can't list a subnet's reservations
+	MaxSubnetEnumeration uint64 = 4096
+
+	// SubnetEnumerationWorkers is the number of concurrent lookups made while
+	// enumerating a subnet
+	SubnetEnumerationWorkers = 16
+
+	errListUnsupported = errors.New("listing reservations is not supported by the server")
+)
+
+// listUnsupportedMessage is the error given by servers that can only look up
+// reservations by mac or ip address
+const listUnsupportedMessage = "must specify a mac query or an IP address"
+
+// SkippedSubnetsError is returned along with the reservations that could be
+// listed when the server doesn't support listing reservations and some
+// subnets are ipv6 or larger than MaxSubnetEnumeration, so weren't enumerated.
+type SkippedSubnetsError struct {
+	Subnets []*net.IPNet
+}
+
+func (e *SkippedSubnetsError) Error() string {
+	subnets := make([]string, 0, len(e.Subnets))
+	for _, subnet := range e.Subnets {
+		subnets = append(subnets, subnet.String())
+	}
+	return fmt.Sprintf("unable to list reservations in %s: the server doesn't support listing and the subnets can't be enumerated", strings.Join(subnets, ", "))
+}
+
+// SkippedSubnets returns the subnets reported by a *SkippedSubnetsError, or
+// nil for any other error
+func SkippedSubnets(err error) []*net.IPNet {
+	if skipped, ok := err.(*SkippedSubnetsError); ok {
+		return skipped.Subnets
+	}
+	return nil
+}
+
+// addSkipped appends the subnets skipped by err to skipped, returning err if
+// it reports anything else
+func addSkipped(skipped []*net.IPNet, err error) ([]*net.IPNet, error) {
+	if e, ok := err.(*SkippedSubnetsError); ok {
+		return append(skipped, e.Subnets...), nil
+	}
+	return skipped, err
+}
+
+// skippedError returns a *SkippedSubnetsError for skipped, or nil if it's empty
+func skippedError(skipped []*net.IPNet) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+	return &SkippedSubnetsError{Subnets: skipped}
+}
+
+// GetIPReservationsBySubnet returns all reservations within subnet.  If the
+// server doesn't support listing reservations every address in the subnet is
+// looked up individually, as long as it's an ipv4 subnet with no more than
+// MaxSubnetEnumeration addresses.  Otherwise an empty list is returned with a
+// *SkippedSubnetsError.
+func (i *IPAM) GetIPReservationsBySubnet(subnet *net.IPNet) (types.IPReservationList, error) {
+	reservations, err := i.listIPReservations(url.Values{"subnet": []string{subnet.String()}})
+	if err == errListUnsupported {
+		reservations, err = i.enumerateSubnet(subnet)
+	}
+	if err != nil {
+		return reservations, err
+	}
+	sortReservations(reservations)
+	return reservations, nil
+}
+
+// GetIPReservationsByNetwork returns all reservations in the subnets of the
+// named network.  Subnets that can't be enumerated are skipped, and reported
+// by a *SkippedSubnetsError returned with the remaining reservations.
+func (i *IPAM) GetIPReservationsByNetwork(name string) (types.IPReservationList, error) {
+	reservations, err := i.listIPReservations(url.Values{"network": []string{name}})
+	if err == nil {
+		sortReservations(reservations)
+		return reservations, nil
+	} else if err != errListUnsupported {
+		return nil, err
+	}
+
+	network, err := i.Inventory.Network().Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations = types.IPReservationList{}
+	skipped := []*net.IPNet{}
+	for _, subnet := range network.Subnets {
+		if subnet.Cidr == nil {
+			continue
+		}
+		subnetReservations, err := i.GetIPReservationsBySubnet(subnet.Cidr)
+		if skipped, err = addSkipped(skipped, err); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, subnetReservations...)
+	}
+	sortReservations(reservations)
+	return reservations, skippedError(skipped)
+}
+
+// getIPReservationsByNetworks returns the reservations in every named
+// network, or in all networks if none are given.  Skipped subnets are reported
+// as by GetIPReservationsByNetwork.
+func (i *IPAM) getIPReservationsByNetworks(networks []string) (types.IPReservationList, error) {
+	if len(networks) == 0 {
+		all, err := i.Inventory.Network().GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range all {
+			networks = append(networks, n.Name)
+		}
+	}
+
+	reservations := types.IPReservationList{}
+	skipped := []*net.IPNet{}
+	for _, name := range networks {
+		networkReservations, err := i.GetIPReservationsByNetwork(name)
+		if skipped, err = addSkipped(skipped, err); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, networkReservations...)
+	}
+	return reservations, skippedError(skipped)
+}
+
+// GetIPReservationsByHostname returns the reservations whose HostInformation
+// matches hostname.  If the server doesn't support listing reservations, the
+// reservations for every NIC of the node with that hostname are returned instead.
+func (i *IPAM) GetIPReservationsByHostname(hostname string) (types.IPReservationList, error) {
+	reservations, err := i.listIPReservations(url.Values{"host": []string{hostname}})
+	if err == nil {
+		sortReservations(reservations)
+		return reservations, nil
+	} else if err != errListUnsupported {
+		return nil, err
+	}
+
+	nodes, err := i.Inventory.NodeConfig().GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	reservations = types.IPReservationList{}
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		if !strings.EqualFold(node.Hostname, hostname) {
+			continue
+		}
+		for _, nic := range node.Networks {
+			for _, mac := range nic.Interface.NICs {
+				nicReservations, err := i.GetIPReservationsByMAC(mac)
+				if err != nil {
+					return nil, err
+				}
+				for _, r := range nicReservations {
+					if r.IP != nil && !seen[r.IP.IP.String()] {
+						seen[r.IP.IP.String()] = true
+						reservations = append(reservations, r)
+					}
+				}
+			}
+		}
+	}
+	sortReservations(reservations)
+	return reservations, nil
+}
+
+// listIPReservations queries the reservation list endpoint, returning
+// errListUnsupported if the server doesn't implement it.  Released servers
+// without the endpoint reject the query with a 400 asking for a mac address,
+// other 400 responses are returned as errors.
+func (i *IPAM) listIPReservations(query url.Values) (types.IPReservationList, error) {
+	client := NewRestClient(i.Inventory.AwsConfigs...)
+
+	response, err := client.Client().NewRequest().Execute(http.MethodGet, i.Inventory.Url(fmt.Sprintf("/ipam/ip?%s", query.Encode())))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list ip reservations: %v", err)
+	}
+
+	switch response.StatusCode() {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, errListUnsupported
+	}
+
+	reservations := types.IPReservationList{}
+	err = UnmarshalApiResponse(response, &reservations)
+	if apiErr, ok := err.(*ApiError); ok && apiErr.StatusCode == http.StatusBadRequest && strings.Contains(apiErr.Message, listUnsupportedMessage) {
+		return nil, errListUnsupported
+	}
+	return reservations, err
+}
+
+// enumerateSubnet looks up every host address in subnet individually, or
+// returns a *SkippedSubnetsError if it's ipv6 or too large
+func (i *IPAM) enumerateSubnet(subnet *net.IPNet) (types.IPReservationList, error) {
+	if !cidr.IsV4(subnet) || cidr.HostCount(subnet) > MaxSubnetEnumeration {
+		return types.IPReservationList{}, &SkippedSubnetsError{Subnets: []*net.IPNet{subnet}}
+	}
+
+	workers := SubnetEnumerationWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	ips := make(chan net.IP)
+	done := make(chan struct{})
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	reservations := types.IPReservationList{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range ips {
+				r, err := i.GetIPReservation(ip)
+				lock.Lock()
+				switch {
+				case err == nil:
+					reservations = append(reservations, r)
+				case !IsNotFound(err) && firstErr == nil:
+					firstErr = err
+					close(done)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	cidr.EachHost(subnet, func(ip net.IP) bool {
+		select {
+		case ips <- ip:
+			return true
+		case <-done:
+			return false
+		}
+	})
+	close(ips)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("unable to enumerate reservations in %s: %v", subnet, firstErr)
+	}
+	return reservations, nil
+}
+
+func sortReservations(reservations types.IPReservationList) {
+	sort.SliceStable(reservations, func(a, b int) bool {
+		if reservations[a].IP == nil || reservations[b].IP == nil {
+			return reservations[b].IP != nil
+		}
+		return cidr.Compare(reservations[a].IP.IP, reservations[b].IP.IP) < 0
+	})
+}
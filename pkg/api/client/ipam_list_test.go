@@ -0,0 +1,215 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestIPReservationListBySubnet(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.0.0.0/24").
+		Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:06","ip": "10.0.0.20/24"},{"mac": "00:01:02:03:04:05","ip": "10.0.0.3/24"}]`)
+
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/24")
+	reservations, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservationsBySubnet(subnet)
+	if err != nil {
+		t.Fatalf("unable to list ip reservations: %v", err)
+	}
+
+	if len(reservations) != 2 || !reservations[0].IP.IP.Equal(net.ParseIP("10.0.0.3")) {
+		t.Errorf("got unexpected reservations: %v", reservations)
+	}
+}
+
+func TestIPReservationListBySubnetEnumerate(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.0.0.0/29").
+		Reply(http.StatusBadRequest).
+		BodyString(`{"status": "Bad Request", "error": "You must specify a mac query or an IP address"}`)
+	for _, ip := range []string{"10.0.0.2", "10.0.0.5"} {
+		gock.New(testBaseUrl.String()).
+			Get("ipam/ip/" + ip + "$").
+			Reply(http.StatusOK).
+			BodyString(`{"mac": "00:01:02:03:04:05","ip": "` + ip + `/29"}`)
+	}
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.[1-6]$").
+		Persist().
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/29")
+	reservations, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservationsBySubnet(subnet)
+	if err != nil {
+		t.Fatalf("unable to list ip reservations: %v", err)
+	}
+
+	if len(reservations) != 2 || !reservations[0].IP.IP.Equal(net.ParseIP("10.0.0.2")) || !reservations[1].IP.IP.Equal(net.ParseIP("10.0.0.5")) {
+		t.Errorf("got unexpected reservations: %v", reservations)
+	}
+}
+
+func TestIPReservationListBySubnetTooLarge(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.0.0.0/8").
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/8")
+	_, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservationsBySubnet(subnet)
+	if skipped := SkippedSubnets(err); len(skipped) != 1 || skipped[0].String() != "10.0.0.0/8" {
+		t.Errorf("expected the large subnet to be skipped, got %v", err)
+	}
+}
+
+func TestIPReservationListByNetwork(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("network", "provisioning").
+		Reply(http.StatusBadRequest).
+		BodyString(`{"status": "Bad Request", "error": "You must specify a mac query or an IP address"}`)
+	gock.New(testBaseUrl.String()).
+		Get("network/provisioning").
+		Reply(http.StatusOK).
+		BodyString(`{"Name": "provisioning", "Subnets": [{"Name": "a", "Cidr": "10.0.0.0/24"}]}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.0.0.0/24").
+		Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:05","ip": "10.0.0.3/24"}]`)
+
+	reservations, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservationsByNetwork("provisioning")
+	if err != nil {
+		t.Fatalf("unable to list ip reservations: %v", err)
+	}
+
+	if len(reservations) != 1 || reservations[0].MAC.String() != "00:01:02:03:04:05" {
+		t.Errorf("got unexpected reservations: %v", reservations)
+	}
+}
+
+func TestIPReservationListBadRequest(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.0.0.0/29").
+		Reply(http.StatusBadRequest).
+		BodyString(`{"status": "Bad Request", "error": "invalid subnet"}`)
+
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/29")
+	_, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservationsBySubnet(subnet)
+	if err == nil || SkippedSubnets(err) != nil {
+		t.Errorf("expected the bad request to be returned, got %v", err)
+	}
+	if !gock.IsDone() {
+		t.Errorf("unexpected fallback to enumerating the subnet")
+	}
+}
+
+func TestIPReservationListByNetworkSkipped(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip$").
+		Persist().
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+	gock.New(testBaseUrl.String()).
+		Get("network/provisioning").
+		Reply(http.StatusOK).
+		BodyString(`{"Name": "provisioning", "Subnets": [{"Name": "a", "Cidr": "10.0.0.0/30"}, {"Name": "b", "Cidr": "10.1.0.0/16"}, {"Name": "c", "Cidr": "2001:db8::/120"}]}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.1$").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.1/30"}`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.2$").
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+
+	reservations, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservationsByNetwork("provisioning")
+	skipped := SkippedSubnets(err)
+	if len(skipped) != 2 || skipped[0].String() != "10.1.0.0/16" || skipped[1].String() != "2001:db8::/120" {
+		t.Errorf("expected the large and ipv6 subnets to be skipped, got %v", err)
+	}
+
+	if len(reservations) != 1 || !reservations[0].IP.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("got unexpected reservations: %v", reservations)
+	}
+}
+
+func TestIPReservationListByHostname(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("host", "node-000").
+		Reply(http.StatusBadRequest).
+		BodyString(`{"status": "Bad Request", "error": "You must specify a mac query or an IP address"}`)
+	gock.New(testBaseUrl.String()).
+		Get("nodeconfig").
+		Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Hostname": "node-000", "Networks": {"prov": {"Interface": {"NICs": ["00:01:02:03:04:05"]}}}},
+			{"InventoryID": "test-001", "Hostname": "node-001", "Networks": {"prov": {"Interface": {"NICs": ["00:01:02:03:04:06"]}}}}]`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("mac", "00:01:02:03:04:05").
+		Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:05","ip": "10.0.0.3/24"}]`)
+
+	reservations, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().GetIPReservationsByHostname("node-000")
+	if err != nil {
+		t.Fatalf("unable to list ip reservations: %v", err)
+	}
+
+	if len(reservations) != 1 || !reservations[0].IP.IP.Equal(net.ParseIP("10.0.0.3")) {
+		t.Errorf("got unexpected reservations: %v", reservations)
+	}
+}
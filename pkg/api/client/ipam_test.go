@@ -188,6 +188,31 @@ func TestIPReservationDelete(t *testing.T) {
 		t.Errorf("unable to delete ip reservation: %v", err)
 	}
 }
+
+func TestIPReservationDeleteBody(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	// the server parses the reservation from the body of delete requests
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.1").
+		MatchType("json").
+		BodyString(`"ip":"10.0.0.1/24"`).
+		BodyString(`"mac":"00:01:02:03:04:05"`).
+		Reply(http.StatusOK)
+
+	mac, _ := net.ParseMAC("00:01:02:03:04:05")
+	reservation := &types.IPReservation{IP: &net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.IPv4Mask(0xff, 0xff, 0xff, 0x00)}, MAC: mac}
+	err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().DeleteIPReservation(reservation)
+	if err != nil {
+		t.Errorf("unable to delete ip reservation: %v", err)
+	}
+	if !gock.IsDone() {
+		t.Errorf("reservation wasn't sent in the request body")
+	}
+}
 func TestIPReservationDeleteNotFound(t *testing.T) {
 	gock.DisableNetworking()
 	defer gock.EnableNetworking()
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// NeverExpires is returned by ExpiresIn for static reservations
+const NeverExpires = time.Duration(math.MaxInt64)
+
+// Lease adds lifetime helpers to an IP reservation
+type Lease struct {
+	*types.IPReservation
+}
+
+// NewLease wraps a reservation
+func NewLease(r *types.IPReservation) *Lease {
+	return &Lease{IPReservation: r}
+}
+
+// Active returns true if the reservation is currently valid
+func (l *Lease) Active() bool {
+	return l.ValidAt(time.Now())
+}
+
+// ExpiresIn returns the time remaining until the reservation ends, which is
+// negative if it has already expired, or NeverExpires if it is static.
+func (l *Lease) ExpiresIn() time.Duration {
+	if l.End == nil {
+		return NeverExpires
+	}
+	return time.Until(*l.End)
+}
+
+// Renew extends the reservation for ip so that it ends duration from now
+func (i *IPAM) Renew(ip net.IP, duration time.Duration) (*types.IPReservation, error) {
+	reservation, err := i.GetIPReservation(ip)
+	if err != nil {
+		return nil, err
+	}
+	return i.RenewReservation(reservation, duration)
+}
+
+// RenewReservation extends an existing reservation so that it ends duration
+// from now, or DefaultLeaseDuration if duration isn't positive.  Static
+// reservations can't be renewed.  The reservation passed in is not modified.
+func (i *IPAM) RenewReservation(reservation *types.IPReservation, duration time.Duration) (*types.IPReservation, error) {
+	if reservation.IP == nil {
+		return nil, fmt.Errorf("unable to renew reservation without an ip address")
+	}
+	if reservation.End == nil {
+		return nil, fmt.Errorf("unable to renew static reservation for %s", reservation.IP.IP)
+	}
+	if duration <= 0 {
+		duration = DefaultLeaseDuration
+	}
+	renewed := *reservation
+	end := time.Now().Add(duration)
+	renewed.End = &end
+	return i.UpdateIPReservation(&renewed)
+}
+
+// Release deletes the reservation for ip
+func (i *IPAM) Release(ip net.IP) error {
+	reservation, err := i.GetIPReservation(ip)
+	if err != nil {
+		return err
+	}
+	return i.DeleteIPReservation(reservation)
+}
+
+const (
+	DefaultLeaseDuration      = time.Hour
+	DefaultLeaseRenewBefore   = 15 * time.Minute
+	DefaultLeaseCheckInterval = time.Minute
+)
+
+// LeaseFailure describes a reservation the LeaseManager was unable to renew
+type LeaseFailure struct {
+	IP      net.IP
+	Err     error
+	Expired bool
+	Time    time.Time
+}
+
+func (f *LeaseFailure) Error() string {
+	if f.Expired {
+		return fmt.Sprintf("lease for %s expired: %v", f.IP, f.Err)
+	}
+	return fmt.Sprintf("unable to renew lease for %s: %v", f.IP, f.Err)
+}
+
+type managedLease struct {
+	ip          net.IP
+	reservation *types.IPReservation
+	failure     *LeaseFailure
+}
+
+// LeaseManager keeps a set of reservations renewed before they expire
+type LeaseManager struct {
+	IPAM *IPAM
+
+	// Duration is the lifetime requested each time a lease is renewed,
+	// DefaultLeaseDuration if zero
+	Duration time.Duration
+
+	// RenewBefore is how long before expiry a lease is renewed
+	RenewBefore time.Duration
+
+	// CheckInterval is the time between checks of managed leases
+	CheckInterval time.Duration
+
+	// OnFailure, if set, is called each time a lease fails to renew
+	OnFailure func(*LeaseFailure)
+
+	lock      sync.Mutex
+	checkLock sync.Mutex
+	leases    map[string]*managedLease
+}
+
+// NewLeaseManager returns a lease manager with default timings
+func (i *IPAM) NewLeaseManager() *LeaseManager {
+	return &LeaseManager{
+		IPAM:          i,
+		Duration:      DefaultLeaseDuration,
+		RenewBefore:   DefaultLeaseRenewBefore,
+		CheckInterval: DefaultLeaseCheckInterval,
+		leases:        map[string]*managedLease{},
+	}
+}
+
+// Add starts managing the reservation for ip
+func (m *LeaseManager) Add(ip net.IP) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.leases == nil {
+		m.leases = map[string]*managedLease{}
+	}
+	if _, ok := m.leases[ip.String()]; !ok {
+		m.leases[ip.String()] = &managedLease{ip: ip}
+	}
+}
+
+// Remove stops managing the reservation for ip.  The reservation isn't released.
+func (m *LeaseManager) Remove(ip net.IP) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.leases, ip.String())
+}
+
+// Failed returns the managed leases whose most recent renewal failed
+func (m *LeaseManager) Failed() []*LeaseFailure {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	failures := []*LeaseFailure{}
+	for _, l := range m.leases {
+		if l.failure != nil {
+			failures = append(failures, l.failure)
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].IP.String() < failures[j].IP.String() })
+	return failures
+}
+
+// Run checks managed leases every CheckInterval until ctx is cancelled
+func (m *LeaseManager) Run(ctx context.Context) {
+	interval := m.CheckInterval
+	if interval <= 0 {
+		interval = DefaultLeaseCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.Check()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check renews every managed lease that expires within RenewBefore
+func (m *LeaseManager) Check() {
+	m.checkLock.Lock()
+	defer m.checkLock.Unlock()
+
+	m.lock.Lock()
+	leases := make([]*managedLease, 0, len(m.leases))
+	for _, l := range m.leases {
+		leases = append(leases, l)
+	}
+	m.lock.Unlock()
+
+	for _, l := range leases {
+		failure := m.check(l)
+
+		m.lock.Lock()
+		l.failure = failure
+		m.lock.Unlock()
+
+		if failure != nil && m.OnFailure != nil {
+			m.OnFailure(failure)
+		}
+	}
+}
+
+func (m *LeaseManager) check(l *managedLease) *LeaseFailure {
+	if l.reservation == nil {
+		reservation, err := m.IPAM.GetIPReservation(l.ip)
+		if err != nil {
+			return &LeaseFailure{IP: l.ip, Err: err, Time: time.Now()}
+		}
+		l.reservation = reservation
+	}
+
+	lease := NewLease(l.reservation)
+	if lease.ExpiresIn() > m.RenewBefore {
+		return nil
+	}
+
+	renewed, err := m.IPAM.RenewReservation(l.reservation, m.Duration)
+	if err != nil {
+		// refetch the reservation next time in case it was changed elsewhere
+		l.reservation = nil
+		return &LeaseFailure{IP: l.ip, Err: err, Expired: !lease.Active(), Time: time.Now()}
+	}
+	l.reservation = renewed
+	return nil
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestLeaseHelpers(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	static := NewLease(&types.IPReservation{Start: &past})
+	if !static.Active() || static.ExpiresIn() != NeverExpires {
+		t.Errorf("static reservation should be active and never expire")
+	}
+
+	active := NewLease(&types.IPReservation{Start: &past, End: &future})
+	if !active.Active() || active.ExpiresIn() <= 59*time.Minute {
+		t.Errorf("reservation should be active for about an hour: %s", active.ExpiresIn())
+	}
+
+	expired := NewLease(&types.IPReservation{Start: &past, End: &past})
+	if expired.Active() || expired.ExpiresIn() >= 0 {
+		t.Errorf("reservation should have expired: %s", expired.ExpiresIn())
+	}
+}
+
+// endTimeMatcher matches PUT requests whose reservation ends roughly d from now
+func endTimeMatcher(d time.Duration) gock.MatchFunc {
+	return func(r *http.Request, _ *gock.Request) (bool, error) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return false, err
+		}
+		reservation := &types.IPReservation{}
+		if err := json.Unmarshal(body, reservation); err != nil {
+			return false, err
+		}
+		if reservation.End == nil {
+			return false, nil
+		}
+		remaining := time.Until(*reservation.End)
+		return remaining > d-time.Minute && remaining <= d, nil
+	}
+}
+
+func TestIPAMRenew(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": "2019-05-27T02:22:30Z","end": "2019-05-27T03:22:30Z"}`)
+
+	gock.New(testBaseUrl.String()).
+		Put("ipam/ip/10.0.0.2").
+		SetMatcher(gock.NewBasicMatcher()).
+		AddMatcher(endTimeMatcher(2 * time.Hour)).
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": "2019-05-27T02:22:30Z","end": "2099-05-27T03:22:30Z"}`)
+
+	renewed, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().Renew(net.ParseIP("10.0.0.2"), 2*time.Hour)
+	if err != nil {
+		t.Fatalf("unable to renew reservation: %v", err)
+	}
+
+	if !NewLease(renewed).Active() {
+		t.Errorf("renewed reservation isn't active")
+	}
+}
+
+func TestIPAMRenewStatic(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": null,"end": null}`)
+
+	_, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().Renew(net.ParseIP("10.0.0.2"), time.Hour)
+	if err == nil {
+		t.Errorf("renewing a static reservation should fail")
+	}
+}
+
+func TestIPAMRenewDefaultDuration(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	soon := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": null,"end": "` + soon + `"}`)
+
+	gock.New(testBaseUrl.String()).
+		Put("ipam/ip/10.0.0.2").
+		SetMatcher(gock.NewBasicMatcher()).
+		AddMatcher(endTimeMatcher(DefaultLeaseDuration)).
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": null,"end": "2099-05-27T03:22:30Z"}`)
+
+	manager := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().NewLeaseManager()
+	manager.Duration = 0
+	manager.Add(net.ParseIP("10.0.0.2"))
+	manager.Check()
+
+	if !gock.IsDone() {
+		t.Errorf("lease wasn't renewed for the default duration: %d pending", len(gock.Pending()))
+	}
+	if len(manager.Failed()) != 0 {
+		t.Errorf("unexpected failures: %v", manager.Failed())
+	}
+}
+
+func TestIPAMRelease(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": null,"end": null}`)
+
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.2").
+		BodyString(`"mac":"00:01:02:03:04:05"`).
+		Reply(http.StatusOK)
+
+	err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().Release(net.ParseIP("10.0.0.2"))
+	if err != nil {
+		t.Errorf("unable to release reservation: %v", err)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("reservation not deleted")
+	}
+}
+
+func TestLeaseManager(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	soon := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	later := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": null,"end": "` + soon + `"}`)
+	gock.New(testBaseUrl.String()).
+		Put("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","start": null,"end": "` + later + `"}`)
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.3").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:06","ip": "10.0.0.3/24","start": null,"end": "` + soon + `"}`)
+	gock.New(testBaseUrl.String()).
+		Put("ipam/ip/10.0.0.3").
+		Reply(http.StatusBadRequest).
+		BodyString(`{"status": "Bad Request", "error": "unable to update reservation"}`)
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip/10.0.0.4").
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:07","ip": "10.0.0.4/24","start": null,"end": null}`)
+
+	manager := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().NewLeaseManager()
+	reported := []*LeaseFailure{}
+	manager.OnFailure = func(f *LeaseFailure) { reported = append(reported, f) }
+
+	manager.Add(net.ParseIP("10.0.0.2"))
+	manager.Add(net.ParseIP("10.0.0.3"))
+	manager.Add(net.ParseIP("10.0.0.4"))
+	manager.Check()
+
+	if !gock.IsDone() {
+		t.Errorf("not all expected requests were made: %d pending", len(gock.Pending()))
+	}
+
+	failed := manager.Failed()
+	if len(failed) != 1 || !failed[0].IP.Equal(net.ParseIP("10.0.0.3")) || failed[0].Expired {
+		t.Errorf("expected a single renewal failure for 10.0.0.3, got %v", failed)
+	}
+
+	if len(reported) != 1 {
+		t.Errorf("expected failure to be reported once, got %d", len(reported))
+	}
+
+	// the renewed lease is cached and no longer needs renewal
+	manager.Remove(net.ParseIP("10.0.0.3"))
+	manager.Check()
+	if len(manager.Failed()) != 0 {
+		t.Errorf("unexpected failures after renewal: %v", manager.Failed())
+	}
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/cidr"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// SubnetOverlap identifies an existing subnet that overlaps a proposed one
+type SubnetOverlap struct {
+	Network string
+	Subnet  string
+	Cidr    *net.IPNet
+}
+
+func (o *SubnetOverlap) String() string {
+	return fmt.Sprintf("%s (network %s, subnet %s)", o.Cidr, o.Network, o.Subnet)
+}
+
+// SubnetPlanner chooses unused blocks from a set of supernets
+type SubnetPlanner struct {
+	Supernets []*net.IPNet
+	Networks  []*types.Network
+}
+
+// NewSubnetPlanner returns a planner that avoids every subnet currently
+// defined in the inventory
+func (i *InventoryApi) NewSubnetPlanner(supernets ...*net.IPNet) (*SubnetPlanner, error) {
+	networks, err := i.Network().GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return &SubnetPlanner{Supernets: supernets, Networks: networks}, nil
+}
+
+func (p *SubnetPlanner) used() []*net.IPNet {
+	used := []*net.IPNet{}
+	for _, n := range p.Networks {
+		for _, s := range n.Subnets {
+			if s.Cidr != nil {
+				used = append(used, s.Cidr)
+			}
+		}
+	}
+	return used
+}
+
+// Overlaps returns the existing subnets that share addresses with proposed
+func (p *SubnetPlanner) Overlaps(proposed *net.IPNet) []*SubnetOverlap {
+	overlaps := []*SubnetOverlap{}
+	for _, n := range p.Networks {
+		for _, s := range n.Subnets {
+			if s.Cidr != nil && cidr.Overlaps(s.Cidr, proposed) {
+				overlaps = append(overlaps, &SubnetOverlap{Network: n.Name, Subnet: s.Name, Cidr: s.Cidr})
+			}
+		}
+	}
+	return overlaps
+}
+
+// SubnetSize is the address family and prefix length of a block to carve
+type SubnetSize struct {
+	IPv6      bool
+	PrefixLen int
+}
+
+// ParseSubnetSize parses a prefix length, optionally preceded by its address
+// family as in v4/24 or v6/64.  Without a family, prefix lengths longer than
+// 32 are IPv6 and the rest are IPv4.
+func ParseSubnetSize(s string) (SubnetSize, error) {
+	size := SubnetSize{}
+	value := strings.ToLower(strings.TrimSpace(s))
+	family := ""
+	if strings.HasPrefix(value, "v4") || strings.HasPrefix(value, "v6") {
+		family, value = value[:2], value[2:]
+	}
+
+	prefixLen, err := strconv.Atoi(strings.TrimPrefix(value, "/"))
+	if err != nil || prefixLen < 0 {
+		return size, fmt.Errorf("invalid prefix length: %s", s)
+	}
+	size.PrefixLen = prefixLen
+	size.IPv6 = family == "v6" || (family == "" && prefixLen > 32)
+	if (!size.IPv6 && prefixLen > 32) || prefixLen > 128 {
+		return size, fmt.Errorf("invalid prefix length for %s: %s", size.family(), s)
+	}
+	return size, nil
+}
+
+func (s SubnetSize) family() string {
+	if s.IPv6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+func (s SubnetSize) String() string {
+	if s.IPv6 {
+		return fmt.Sprintf("v6/%d", s.PrefixLen)
+	}
+	return fmt.Sprintf("v4/%d", s.PrefixLen)
+}
+
+// Next returns the first free block of the given size, searching the
+// supernets in order.  Supernets of a different address family are skipped.
+func (p *SubnetPlanner) Next(size SubnetSize) (*net.IPNet, error) {
+	used := p.used()
+	for _, supernet := range p.Supernets {
+		if cidr.IsV4(supernet) == size.IPv6 {
+			continue
+		}
+		if block, ok := cidr.NextFree(supernet, size.PrefixLen, used); ok {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("no free %s /%d available in the configured supernets", size.family(), size.PrefixLen)
+}
+
+// Reserve adds the subnets of network to those avoided by Next
+func (p *SubnetPlanner) Reserve(network *types.Network) {
+	p.Networks = append(p.Networks, network)
+}
+
+// NetworkPlan describes a network to be built by PlanNetwork
+type NetworkPlan struct {
+	Name   string
+	Domain string
+	MTU    uint
+
+	// Sizes lists each subnet to carve, eg. v4/24 and v6/64 for a dual stack
+	// network
+	Sizes []SubnetSize
+
+	// StaticAllocationMethod and DynamicAllocationMethod are copied to each subnet
+	StaticAllocationMethod  string
+	DynamicAllocationMethod string
+
+	// DNS servers are copied to each subnet of the matching address family
+	DNS []net.IP
+
+	// NoGateway leaves the gateway unset rather than using the first host address
+	NoGateway bool
+}
+
+// PlanNetwork carves a subnet of each requested size and returns a network
+// ready to pass to Network.Create.  The subnets are reserved in the planner.
+func (p *SubnetPlanner) PlanNetwork(plan *NetworkPlan) (*types.Network, error) {
+	if plan.Name == "" {
+		return nil, fmt.Errorf("unable to plan network: a name is required")
+	}
+	if len(plan.Sizes) == 0 {
+		return nil, fmt.Errorf("unable to plan network %s: at least one subnet size is required", plan.Name)
+	}
+
+	network := types.NewNetwork()
+	network.Name = plan.Name
+	network.Domain = plan.Domain
+	network.MTU = plan.MTU
+	network.Subnets = types.SubnetList{}
+
+	planned := &types.Network{Name: plan.Name}
+	p.Networks = append(p.Networks, planned)
+	for idx, size := range plan.Sizes {
+		block, err := p.Next(size)
+		if err != nil {
+			p.Networks = p.Networks[:len(p.Networks)-1]
+			return nil, fmt.Errorf("unable to plan network %s: %v", plan.Name, err)
+		}
+
+		subnet := &types.Subnet{
+			Name:                    fmt.Sprintf("%s-%d", plan.Name, idx),
+			Cidr:                    block,
+			StaticAllocationMethod:  plan.StaticAllocationMethod,
+			DynamicAllocationMethod: plan.DynamicAllocationMethod,
+			DNS:                     []net.IP{},
+		}
+		if !plan.NoGateway {
+			subnet.Gateway, _ = cidr.HostRange(block)
+		}
+		for _, dns := range plan.DNS {
+			if (dns.To4() != nil) == cidr.IsV4(block) {
+				subnet.DNS = append(subnet.DNS, dns)
+			}
+		}
+
+		network.Subnets = append(network.Subnets, subnet)
+		planned.Subnets = append(planned.Subnets, subnet)
+	}
+	return network, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestSubnetPlanner(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("network$").
+		Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24"}, {"Name": "prov6", "Cidr": "2001:db8::/64"}]}]`)
+
+	_, v4, _ := net.ParseCIDR("10.0.0.0/16")
+	_, v6, _ := net.ParseCIDR("2001:db8::/48")
+	planner, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).NewSubnetPlanner(v4, v6)
+	if err != nil {
+		t.Fatalf("unable to create planner: %v", err)
+	}
+
+	_, proposed, _ := net.ParseCIDR("10.0.0.128/25")
+	if overlaps := planner.Overlaps(proposed); len(overlaps) != 1 || overlaps[0].Network != "prov" {
+		t.Errorf("got unexpected overlaps: %v", overlaps)
+	}
+
+	network, err := planner.PlanNetwork(&NetworkPlan{Name: "mgmt", Sizes: []SubnetSize{{PrefixLen: 24}, {IPv6: true, PrefixLen: 64}}, DNS: []net.IP{net.ParseIP("10.0.0.53"), net.ParseIP("2001:db8::53")}})
+	if err != nil {
+		t.Fatalf("unable to plan network: %v", err)
+	}
+
+	if len(network.Subnets) != 2 || network.Subnets[0].Cidr.String() != "10.0.1.0/24" || network.Subnets[1].Cidr.String() != "2001:db8:0:1::/64" {
+		t.Fatalf("got unexpected subnets: %v", network.Subnets)
+	}
+
+	if network.Subnets[0].Gateway.String() != "10.0.1.1" || len(network.Subnets[0].DNS) != 1 || network.Subnets[1].DNS[0].String() != "2001:db8::53" {
+		t.Errorf("subnet details not set correctly: %+v", network.Subnets[0])
+	}
+
+	next, err := planner.Next(SubnetSize{PrefixLen: 24})
+	if err != nil || next.String() != "10.0.2.0/24" {
+		t.Errorf("planned subnet not reserved, got %v: %v", next, err)
+	}
+
+	if _, err := planner.PlanNetwork(&NetworkPlan{Name: "huge", Sizes: []SubnetSize{{PrefixLen: 12}}}); err == nil {
+		t.Errorf("expected an error planning a subnet larger than the supernets")
+	}
+}
+
+func TestSubnetPlannerFamily(t *testing.T) {
+	// the ipv6 supernet is listed first, but is never used for ipv4 blocks
+	_, v6, _ := net.ParseCIDR("2001:db8::/32")
+	_, v4, _ := net.ParseCIDR("10.0.0.0/16")
+	planner := &SubnetPlanner{Supernets: []*net.IPNet{v6, v4}}
+
+	if block, err := planner.Next(SubnetSize{PrefixLen: 24}); err != nil || block.String() != "10.0.0.0/24" {
+		t.Errorf("got unexpected ipv4 block %v: %v", block, err)
+	}
+	if block, err := planner.Next(SubnetSize{IPv6: true, PrefixLen: 48}); err != nil || block.String() != "2001:db8::/48" {
+		t.Errorf("got unexpected ipv6 block %v: %v", block, err)
+	}
+
+	planner.Supernets = []*net.IPNet{v6}
+	if block, err := planner.Next(SubnetSize{PrefixLen: 24}); err == nil {
+		t.Errorf("expected an error without an ipv4 supernet, got %v", block)
+	}
+}
+
+func TestParseSubnetSize(t *testing.T) {
+	for value, expected := range map[string]SubnetSize{
+		"24":     {PrefixLen: 24},
+		"/23":    {PrefixLen: 23},
+		"64":     {IPv6: true, PrefixLen: 64},
+		"v6/32":  {IPv6: true, PrefixLen: 32},
+		"V4/28":  {PrefixLen: 28},
+		" v6/48": {IPv6: true, PrefixLen: 48},
+	} {
+		if size, err := ParseSubnetSize(value); err != nil || size != expected {
+			t.Errorf("got unexpected size for %q: %v %v", value, size, err)
+		}
+	}
+
+	for _, value := range []string{"", "x", "v4/33", "129", "-1", "v5/24"} {
+		if size, err := ParseSubnetSize(value); err == nil {
+			t.Errorf("expected an error for %q, got %v", value, size)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// ProvisioningLeaseMetadataKey marks reservations created by
+// NewProvisioningLease that haven't been committed
+const ProvisioningLeaseMetadataKey = "provisioningLease"
+
+const (
+	DefaultSweepInterval = 5 * time.Minute
+	DefaultSweepGrace    = time.Minute
+)
+
+// ErrLeaseFinished is returned when a provisioning lease is committed or
+// aborted more than once
+var ErrLeaseFinished = fmt.Errorf("provisioning lease already committed or aborted")
+
+// IsProvisioningLease returns true if r is an uncommitted provisioning lease
+func IsProvisioningLease(r *types.IPReservation) bool {
+	_, ok := r.Metadata[ProvisioningLeaseMetadataKey]
+	return ok
+}
+
+// ProvisioningLease is a temporary reservation that expires after its TTL
+// unless committed.  Uncommitted leases left behind by a process that exits
+// early are removed by a LeaseSweeper.
+type ProvisioningLease struct {
+	IPAM        *IPAM
+	Reservation *types.IPReservation
+
+	lock     sync.Mutex
+	finished bool
+}
+
+// NewProvisioningLease reserves ip, or an address from the requested subnet
+// if ip is nil, for ttl.  The request is not modified.
+func (i *IPAM) NewProvisioningLease(request *types.IpamIpRequest, ip net.IP, ttl time.Duration) (*ProvisioningLease, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("unable to create provisioning lease: a positive ttl is required")
+	}
+
+	leaseRequest := *request
+	leaseRequest.TTL = ttl.String()
+	leaseRequest.Metadata = types.Metadata{}
+	for k, v := range request.Metadata {
+		leaseRequest.Metadata[k] = v
+	}
+	leaseRequest.Metadata[ProvisioningLeaseMetadataKey] = true
+
+	reservation, err := i.CreateIPReservation(&leaseRequest, ip)
+	if err != nil {
+		return nil, err
+	}
+	return &ProvisioningLease{IPAM: i, Reservation: reservation}, nil
+}
+
+// Commit makes the reservation permanent, returning the updated reservation
+func (l *ProvisioningLease) Commit() (*types.IPReservation, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.finished {
+		return nil, ErrLeaseFinished
+	}
+
+	committed := *l.Reservation
+	committed.End = nil
+	committed.Metadata = types.Metadata{}
+	for k, v := range l.Reservation.Metadata {
+		if k != ProvisioningLeaseMetadataKey {
+			committed.Metadata[k] = v
+		}
+	}
+
+	reservation, err := l.IPAM.UpdateIPReservation(&committed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to commit provisioning lease for %s: %v", l.Reservation.IP, err)
+	}
+	l.Reservation = reservation
+	l.finished = true
+	return reservation, nil
+}
+
+// Abort deletes the reservation.  It isn't an error if the reservation has
+// already been removed.
+func (l *ProvisioningLease) Abort() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.finished {
+		return ErrLeaseFinished
+	}
+
+	err := l.IPAM.DeleteIPReservation(l.Reservation)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("unable to abort provisioning lease for %s: %v", l.Reservation.IP, err)
+	}
+	l.finished = true
+	return nil
+}
+
+// LeaseSweeper deletes provisioning leases that expired without being committed
+type LeaseSweeper struct {
+	IPAM *IPAM
+
+	// Networks limits sweeping to the named networks, or all networks if empty
+	Networks []string
+
+	// Grace is how long after expiry an uncommitted lease is kept, allowing
+	// for clock skew and commits in progress
+	Grace time.Duration
+
+	// Interval is the time between sweeps when running
+	Interval time.Duration
+
+	// OnSweep, if set, is called with the result of each sweep made by Run
+	OnSweep func(deleted types.IPReservationList, err error)
+}
+
+// NewLeaseSweeper returns a sweeper with default timings
+func (i *IPAM) NewLeaseSweeper() *LeaseSweeper {
+	return &LeaseSweeper{IPAM: i, Grace: DefaultSweepGrace, Interval: DefaultSweepInterval}
+}
+
+// Expired returns the uncommitted leases in reservations that expired more
+// than Grace before now
+func (s *LeaseSweeper) Expired(reservations types.IPReservationList, now time.Time) types.IPReservationList {
+	expired := types.IPReservationList{}
+	for _, r := range reservations {
+		if IsProvisioningLease(r) && r.End != nil && r.End.Add(s.Grace).Before(now) {
+			expired = append(expired, r)
+		}
+	}
+	return expired
+}
+
+// Sweep deletes expired uncommitted leases, returning those deleted.  Leases
+// that fail to delete are reported in the error and retried on the next sweep.
+// Leases in subnets whose reservations can't be listed are left alone, and
+// the *SkippedSubnetsError is returned once the rest have been swept.
+func (s *LeaseSweeper) Sweep() (types.IPReservationList, error) {
+	reservations, listErr := s.IPAM.getIPReservationsByNetworks(s.Networks)
+	if listErr != nil && SkippedSubnets(listErr) == nil {
+		return nil, listErr
+	}
+
+	deleted := types.IPReservationList{}
+	failed := 0
+	var lastErr error
+	for _, r := range s.Expired(reservations, time.Now()) {
+		err := s.IPAM.DeleteIPReservation(r)
+		if err != nil && !IsNotFound(err) {
+			failed++
+			lastErr = err
+			continue
+		}
+		deleted = append(deleted, r)
+	}
+
+	if failed > 0 {
+		return deleted, fmt.Errorf("unable to delete %d expired provisioning leases: %v", failed, lastErr)
+	}
+	return deleted, listErr
+}
+
+// Run sweeps every Interval until ctx is cancelled
+func (s *LeaseSweeper) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		deleted, err := s.Sweep()
+		if s.OnSweep != nil {
+			s.OnSweep(deleted, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
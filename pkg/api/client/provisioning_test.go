@@ -0,0 +1,133 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestProvisioningLeaseCommit(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip$").
+		BodyString(`"ttl":"30m0s"`).
+		BodyString(`"provisioningLease":true`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","end": "2030-01-01T00:00:00Z","metadata": {"provisioningLease": true, "owner": "installer"}}`)
+
+	var updated *types.IPReservation
+	gock.New(testBaseUrl.String()).
+		Put("ipam/ip/10.0.0.2").
+		SetMatcher(gock.NewBasicMatcher()).
+		AddMatcher(func(r *http.Request, _ *gock.Request) (bool, error) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return false, err
+			}
+			updated = &types.IPReservation{}
+			return true, json.Unmarshal(body, updated)
+		}).
+		Reply(http.StatusOK).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","metadata": {"owner": "installer"}}`)
+
+	request := &types.IpamIpRequest{Subnet: "10.0.0.0/24", HwAddress: "00:01:02:03:04:05", Metadata: types.Metadata{"owner": "installer"}}
+	lease, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().NewProvisioningLease(request, nil, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("unable to create provisioning lease: %v", err)
+	}
+
+	if request.TTL != "" || len(request.Metadata) != 1 {
+		t.Errorf("request was modified: %+v", request)
+	}
+
+	reservation, err := lease.Commit()
+	if err != nil {
+		t.Fatalf("unable to commit lease: %v", err)
+	}
+
+	if updated == nil || updated.End != nil || IsProvisioningLease(updated) || updated.Metadata["owner"] != "installer" {
+		t.Errorf("committed reservation not sent correctly: %+v", updated)
+	}
+
+	if reservation.End != nil {
+		t.Errorf("committed reservation still expires")
+	}
+
+	if err := lease.Abort(); err != ErrLeaseFinished {
+		t.Errorf("expected abort after commit to fail, got %v", err)
+	}
+}
+
+func TestProvisioningLeaseAbort(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.7").
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.7/24","end": "2030-01-01T00:00:00Z","metadata": {"provisioningLease": true}}`)
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.7").
+		Reply(http.StatusOK)
+
+	lease, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().NewProvisioningLease(&types.IpamIpRequest{HwAddress: "00:01:02:03:04:05"}, testReservation("10.0.0.7/24", nil).IP.IP, time.Minute)
+	if err != nil {
+		t.Fatalf("unable to create provisioning lease: %v", err)
+	}
+
+	if err := lease.Abort(); err != nil {
+		t.Errorf("unable to abort lease: %v", err)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("reservation not deleted")
+	}
+}
+
+func TestLeaseSweeper(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("network", "prov").
+		Reply(http.StatusOK).
+		BodyString(`[
+			{"mac": "00:01:02:03:04:05","ip": "10.0.0.2/24","end": "2001-01-01T00:00:00Z","metadata": {"provisioningLease": true}},
+			{"mac": "00:01:02:03:04:06","ip": "10.0.0.3/24","end": "2030-01-01T00:00:00Z","metadata": {"provisioningLease": true}},
+			{"mac": "00:01:02:03:04:07","ip": "10.0.0.4/24","end": "2001-01-01T00:00:00Z"}
+		]`)
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.2").
+		Reply(http.StatusOK)
+
+	sweeper := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().NewLeaseSweeper()
+	sweeper.Networks = []string{"prov"}
+
+	deleted, err := sweeper.Sweep()
+	if err != nil {
+		t.Fatalf("unable to sweep leases: %v", err)
+	}
+
+	if len(deleted) != 1 || deleted[0].IP.IP.String() != "10.0.0.2" {
+		t.Errorf("got unexpected deleted leases: %v", deleted)
+	}
+}
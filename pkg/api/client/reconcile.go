@@ -0,0 +1,344 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// ExpectedIPMetadataKey is the NetworkInterface metadata key holding the
+// addresses a node's interface is expected to have, either as a single string
+// or a list of strings
+const ExpectedIPMetadataKey = "ip"
+
+// ReconcileStatus describes a difference between a node and IPAM
+type ReconcileStatus string
+
+const (
+	// ReconcileMissing means no static reservation exists for the interface
+	// in a subnet with static allocation enabled
+	ReconcileMissing ReconcileStatus = "missing"
+
+	// ReconcileMismatched means the interface has a reservation in the subnet,
+	// but not for the expected address
+	ReconcileMismatched ReconcileStatus = "mismatched"
+
+	// ReconcileExtra means a static reservation exists for the interface that
+	// isn't required by its network, or duplicates another in the same subnet
+	ReconcileExtra ReconcileStatus = "extra"
+
+	// ReconcileUnknownNetwork means the node refers to a network that doesn't exist
+	ReconcileUnknownNetwork ReconcileStatus = "unknown-network"
+)
+
+// ReconcileFinding describes a single difference.  Reservation is the
+// existing reservation for mismatched and extra findings.
+type ReconcileFinding struct {
+	Status      ReconcileStatus      `json:"status"`
+	Node        string               `json:"node"`
+	Network     string               `json:"network"`
+	Subnet      string               `json:"subnet,omitempty"`
+	MAC         string               `json:"mac,omitempty"`
+	ExpectedIP  net.IP               `json:"expectedIP,omitempty"`
+	Reservation *types.IPReservation `json:"reservation,omitempty"`
+
+	// Skipped explains why Apply can't fix the finding
+	Skipped string `json:"skipped,omitempty"`
+
+	// Fixed and Error record the result of Apply
+	Fixed bool   `json:"fixed"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReconcileReport lists the differences found between nodes and IPAM
+type ReconcileReport struct {
+	Nodes    int                 `json:"nodes"`
+	Findings []*ReconcileFinding `json:"findings"`
+}
+
+// Reconciler compares the NICs of every node with their IP reservations
+type Reconciler struct {
+	IPAM *IPAM
+
+	// DeleteExtra allows Apply to delete extra reservations.  Missing and
+	// mismatched reservations are always fixed.
+	DeleteExtra bool
+}
+
+// NewReconciler returns a reconciler that leaves extra reservations in place
+func (i *IPAM) NewReconciler() *Reconciler {
+	return &Reconciler{IPAM: i}
+}
+
+// expectedIPs returns the addresses listed in the interface metadata
+func expectedIPs(iface *types.NetworkInterface) []net.IP {
+	var values []string
+	switch v := iface.Metadata[ExpectedIPMetadataKey].(type) {
+	case string:
+		values = strings.Split(v, ",")
+	case []string:
+		values = v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	ips := []net.IP{}
+	for _, value := range values {
+		if ip := net.ParseIP(strings.TrimSpace(value)); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// Compare finds the differences between nodes and the static reservations for
+// their MACs, keyed by MAC address.
+func (r *Reconciler) Compare(nodes []*types.Node, networks []*types.Network, reservations map[string]types.IPReservationList) *ReconcileReport {
+	networksByName := map[string]*types.Network{}
+	for _, n := range networks {
+		networksByName[n.Name] = n
+	}
+
+	report := &ReconcileReport{Findings: []*ReconcileFinding{}}
+	for _, node := range nodes {
+		report.Nodes++
+
+		names := make([]string, 0, len(node.Networks))
+		for name := range node.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// addresses required by any of the node's networks, keyed by mac
+		required := map[string][]*net.IPNet{}
+		seen := map[string]bool{}
+
+		for _, name := range names {
+			iface := node.Networks[name]
+			if iface == nil || len(iface.NICs) == 0 {
+				continue
+			}
+
+			network, ok := networksByName[name]
+			if !ok {
+				report.Findings = append(report.Findings, &ReconcileFinding{Status: ReconcileUnknownNetwork, Node: node.ID(), Network: name})
+				continue
+			}
+
+			ifaceReservations := types.IPReservationList{}
+			for _, mac := range iface.NICs {
+				ifaceReservations = append(ifaceReservations, reservations[mac.String()].Static()...)
+			}
+			expected := expectedIPs(iface)
+
+			for _, subnet := range network.Subnets {
+				if subnet.Cidr == nil {
+					continue
+				}
+				for _, mac := range iface.NICs {
+					required[mac.String()] = append(required[mac.String()], subnet.Cidr)
+				}
+				if !subnet.StaticAllocationEnabled() {
+					continue
+				}
+
+				var expectedIP net.IP
+				for _, ip := range expected {
+					if subnet.Cidr.Contains(ip) {
+						expectedIP = ip
+						break
+					}
+				}
+
+				existing := types.IPReservationList{}
+				for _, res := range ifaceReservations {
+					if res.IP != nil && subnet.Cidr.Contains(res.IP.IP) && !seen[res.IP.IP.String()] {
+						seen[res.IP.IP.String()] = true
+						existing = append(existing, res)
+					}
+				}
+
+				finding := &ReconcileFinding{Node: node.ID(), Network: name, Subnet: subnet.Cidr.String(), MAC: iface.NICs[0].String(), ExpectedIP: expectedIP}
+				if len(existing) == 0 {
+					finding.Status = ReconcileMissing
+					if expectedIP == nil && !subnet.DynamicAllocationEnabled() {
+						finding.Skipped = "no expected address and dynamic allocation is disabled"
+					}
+					report.Findings = append(report.Findings, finding)
+					continue
+				}
+
+				keep := 0
+				if expectedIP != nil {
+					keep = -1
+					for idx, res := range existing {
+						if res.IP.IP.Equal(expectedIP) {
+							keep = idx
+							break
+						}
+					}
+					if keep < 0 {
+						finding.Status = ReconcileMismatched
+						finding.Reservation = existing[0]
+						finding.MAC = existing[0].MAC.String()
+						report.Findings = append(report.Findings, finding)
+						keep = 0
+					}
+				}
+
+				for idx, res := range existing {
+					if idx != keep {
+						report.Findings = append(report.Findings, &ReconcileFinding{Status: ReconcileExtra, Node: node.ID(), Network: name, Subnet: subnet.Cidr.String(), MAC: res.MAC.String(), Reservation: res})
+					}
+				}
+			}
+		}
+
+		// reservations for the node's macs outside every subnet they belong to
+		for _, name := range names {
+			iface := node.Networks[name]
+			if iface == nil {
+				continue
+			}
+			for _, mac := range iface.NICs {
+				for _, res := range reservations[mac.String()].Static() {
+					if res.IP == nil || seen[res.IP.IP.String()] || containedIn(res.IP.IP, required[mac.String()]) {
+						continue
+					}
+					seen[res.IP.IP.String()] = true
+					report.Findings = append(report.Findings, &ReconcileFinding{Status: ReconcileExtra, Node: node.ID(), Network: name, MAC: mac.String(), Reservation: res})
+				}
+			}
+		}
+	}
+	return report
+}
+
+func containedIn(ip net.IP, subnets []*net.IPNet) bool {
+	for _, s := range subnets {
+		if s.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan fetches every node, network and node reservation and reports the
+// differences without changing anything
+func (r *Reconciler) Plan() (*ReconcileReport, error) {
+	nodes, err := r.IPAM.Inventory.Node().GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	networks, err := r.IPAM.Inventory.Network().GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	reservations := map[string]types.IPReservationList{}
+	for _, node := range nodes {
+		for _, iface := range node.Networks {
+			if iface == nil {
+				continue
+			}
+			for _, mac := range iface.NICs {
+				if _, ok := reservations[mac.String()]; ok {
+					continue
+				}
+				macReservations, err := r.IPAM.GetIPReservationsByMAC(mac)
+				if err != nil {
+					return nil, err
+				}
+				reservations[mac.String()] = macReservations
+			}
+		}
+	}
+
+	return r.Compare(nodes, networks, reservations), nil
+}
+
+// Apply creates missing reservations and replaces mismatched ones.  Extra
+// reservations are deleted only if DeleteExtra is set, and skipped findings
+// are left alone.  The outcome of each finding is recorded in the report, and
+// an error is returned if any fix failed.
+func (r *Reconciler) Apply(report *ReconcileReport) error {
+	failed := 0
+	for _, f := range report.Findings {
+		if f.Skipped != "" {
+			continue
+		}
+
+		var err error
+		switch f.Status {
+		case ReconcileMissing:
+			_, err = r.IPAM.CreateIPReservation(&types.IpamIpRequest{Subnet: f.Subnet, HwAddress: f.MAC}, f.ExpectedIP)
+		case ReconcileMismatched:
+			err = r.replace(f)
+		case ReconcileExtra:
+			if !r.DeleteExtra {
+				continue
+			}
+			err = r.IPAM.DeleteIPReservation(f.Reservation)
+		default:
+			continue
+		}
+
+		if err != nil {
+			f.Error = err.Error()
+			failed++
+			continue
+		}
+		f.Fixed = true
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("unable to fix %d reservations", failed)
+	}
+	return nil
+}
+
+// replace swaps a mismatched reservation for the expected address.  The server
+// refuses a second reservation for a mac in the same subnet, so the existing
+// one is deleted first and restored if the new one can't be created.
+func (r *Reconciler) replace(f *ReconcileFinding) error {
+	if err := r.IPAM.DeleteIPReservation(f.Reservation); err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	_, err := r.IPAM.CreateIPReservation(&types.IpamIpRequest{Subnet: f.Subnet, HwAddress: f.MAC}, f.ExpectedIP)
+	if err == nil {
+		return nil
+	}
+
+	if restoreErr := r.restore(f.Subnet, f.Reservation); restoreErr != nil {
+		return fmt.Errorf("unable to reserve %s: %v, and unable to restore %s: %v", f.ExpectedIP, err, f.Reservation.IP.IP, restoreErr)
+	}
+	return fmt.Errorf("unable to reserve %s: %v, restored %s", f.ExpectedIP, err, f.Reservation.IP.IP)
+}
+
+// restore recreates a deleted static reservation
+func (r *Reconciler) restore(subnet string, old *types.IPReservation) error {
+	_, err := r.IPAM.CreateIPReservation(&types.IpamIpRequest{Subnet: subnet, HwAddress: old.MAC.String(), Metadata: old.Metadata}, old.IP.IP)
+	if err != nil || old.HostInformation == "" {
+		return err
+	}
+	_, err = r.IPAM.UpdateIPReservation(old)
+	return err
+}
+
+// Run plans and applies in one step
+func (r *Reconciler) Run() (*ReconcileReport, error) {
+	report, err := r.Plan()
+	if err != nil {
+		return nil, err
+	}
+	return report, r.Apply(report)
+}
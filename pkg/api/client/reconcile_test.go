@@ -0,0 +1,204 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func testReconcileNetworks() []*types.Network {
+	_, prov, _ := net.ParseCIDR("10.0.0.0/24")
+	_, mgmt, _ := net.ParseCIDR("10.1.0.0/24")
+	return []*types.Network{
+		{Name: "prov", Subnets: types.SubnetList{{Name: "prov", Cidr: prov, StaticAllocationMethod: "sequential"}}},
+		{Name: "mgmt", Subnets: types.SubnetList{{Name: "mgmt", Cidr: mgmt, StaticAllocationMethod: "sequential"}}},
+	}
+}
+
+func testReconcileReservation(ip, mac string) *types.IPReservation {
+	r := testReservation(ip, nil)
+	r.MAC, _ = net.ParseMAC(mac)
+	return r
+}
+
+func TestReconcileCompare(t *testing.T) {
+	provMAC, _ := net.ParseMAC("00:01:02:03:04:05")
+	mgmtMAC, _ := net.ParseMAC("00:01:02:03:04:06")
+	otherMAC, _ := net.ParseMAC("00:01:02:03:04:07")
+
+	nodes := []*types.Node{
+		{InventoryID: "test-000", Networks: types.NICInfoMap{
+			"prov": &types.NetworkInterface{NICs: []net.HardwareAddr{provMAC}, Metadata: types.Metadata{"ip": "10.0.0.10"}},
+			"mgmt": &types.NetworkInterface{NICs: []net.HardwareAddr{mgmtMAC}},
+		}},
+		{InventoryID: "test-001", Networks: types.NICInfoMap{
+			"bogus": &types.NetworkInterface{NICs: []net.HardwareAddr{otherMAC}},
+		}},
+	}
+
+	reservations := map[string]types.IPReservationList{
+		provMAC.String(): {
+			testReconcileReservation("10.0.0.11/24", provMAC.String()),
+			testReconcileReservation("10.2.0.5/24", provMAC.String()),
+		},
+	}
+
+	report := (&IPAM{}).NewReconciler().Compare(nodes, testReconcileNetworks(), reservations)
+
+	expected := []struct {
+		Status ReconcileStatus
+		Node   string
+		Subnet string
+	}{
+		{ReconcileMissing, "test-000", "10.1.0.0/24"},
+		{ReconcileMismatched, "test-000", "10.0.0.0/24"},
+		{ReconcileExtra, "test-000", ""},
+		{ReconcileUnknownNetwork, "test-001", ""},
+	}
+
+	if len(report.Findings) != len(expected) {
+		t.Fatalf("got unexpected findings: %+v", report.Findings)
+	}
+	for idx, exp := range expected {
+		f := report.Findings[idx]
+		if f.Status != exp.Status || f.Node != exp.Node || f.Subnet != exp.Subnet {
+			t.Errorf("got unexpected finding %d: %+v", idx, f)
+		}
+	}
+
+	if !report.Findings[1].ExpectedIP.Equal(net.ParseIP("10.0.0.10")) || report.Findings[1].Reservation.IP.IP.String() != "10.0.0.11" {
+		t.Errorf("mismatch not described correctly: %+v", report.Findings[1])
+	}
+
+	// mgmt has no expected address and only static allocation
+	if report.Findings[0].Skipped == "" || report.Findings[1].Skipped != "" {
+		t.Errorf("missing finding without an address should be skipped: %+v", report.Findings[0])
+	}
+}
+
+// macReservedMatcher simulates the server refusing a second reservation for a
+// mac in the same subnet: it matches while reserved returns true
+func macReservedMatcher(reserved func() bool) gock.MatchFunc {
+	return func(*http.Request, *gock.Request) (bool, error) {
+		return reserved(), nil
+	}
+}
+
+func TestReconcileApplySkipped(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	report := &ReconcileReport{Findings: []*ReconcileFinding{
+		{Status: ReconcileMissing, Node: "test-000", Network: "mgmt", Subnet: "10.1.0.0/24", MAC: "00:01:02:03:04:06", Skipped: "no expected address"},
+	}}
+
+	reconciler := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().NewReconciler()
+	if err := reconciler.Apply(report); err != nil {
+		t.Fatalf("skipped findings shouldn't fail: %v", err)
+	}
+
+	if report.Findings[0].Fixed || report.Findings[0].Error != "" {
+		t.Errorf("skipped finding was applied: %+v", report.Findings[0])
+	}
+}
+
+func TestReconcileApplyRestore(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.11").
+		Reply(http.StatusOK)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.10").
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "a reservation for this ip address already exists"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.11").
+		BodyString(`"mac":"00:01:02:03:04:05"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.11/24"}`)
+
+	report := &ReconcileReport{Findings: []*ReconcileFinding{
+		{Status: ReconcileMismatched, Node: "test-000", Network: "prov", Subnet: "10.0.0.0/24", MAC: "00:01:02:03:04:05",
+			ExpectedIP: net.ParseIP("10.0.0.10"), Reservation: testReconcileReservation("10.0.0.11/24", "00:01:02:03:04:05")},
+	}}
+
+	reconciler := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().NewReconciler()
+	if err := reconciler.Apply(report); err == nil {
+		t.Errorf("expected an error when the expected address is taken")
+	}
+
+	if report.Findings[0].Fixed || !strings.Contains(report.Findings[0].Error, "restored 10.0.0.11") {
+		t.Errorf("restore not recorded: %+v", report.Findings[0])
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("old reservation not restored: %d pending", len(gock.Pending()))
+	}
+}
+
+func TestReconcileApply(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip$").
+		BodyString(`"subnet":"10.1.0.0/24"`).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:06","ip": "10.1.0.2/24"}`)
+	// the mac keeps its reservation in the subnet until 10.0.0.11 is deleted
+	reserved := true
+	gock.New(testBaseUrl.String()).
+		Delete("ipam/ip/10.0.0.11").
+		Reply(http.StatusOK).
+		Map(func(r *http.Response) *http.Response {
+			reserved = false
+			return r
+		})
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.10").
+		SetMatcher(gock.NewBasicMatcher()).
+		AddMatcher(macReservedMatcher(func() bool { return reserved })).
+		Reply(http.StatusConflict).
+		BodyString(`{"status": "Conflict", "error": "a reservation for this mac already exists in this subnet"}`)
+	gock.New(testBaseUrl.String()).
+		Post("ipam/ip/10.0.0.10").
+		SetMatcher(gock.NewBasicMatcher()).
+		AddMatcher(macReservedMatcher(func() bool { return !reserved })).
+		Reply(http.StatusCreated).
+		BodyString(`{"mac": "00:01:02:03:04:05","ip": "10.0.0.10/24"}`)
+
+	report := &ReconcileReport{Findings: []*ReconcileFinding{
+		{Status: ReconcileMissing, Node: "test-000", Network: "mgmt", Subnet: "10.1.0.0/24", MAC: "00:01:02:03:04:06"},
+		{Status: ReconcileMismatched, Node: "test-000", Network: "prov", Subnet: "10.0.0.0/24", MAC: "00:01:02:03:04:05",
+			ExpectedIP: net.ParseIP("10.0.0.10"), Reservation: testReconcileReservation("10.0.0.11/24", "00:01:02:03:04:05")},
+		{Status: ReconcileExtra, Node: "test-000", Network: "prov", MAC: "00:01:02:03:04:05", Reservation: testReconcileReservation("10.2.0.5/24", "00:01:02:03:04:05")},
+	}}
+
+	reconciler := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).IPAM().NewReconciler()
+	if err := reconciler.Apply(report); err != nil {
+		t.Fatalf("unable to apply fixes: %v", err)
+	}
+
+	if !report.Findings[0].Fixed || !report.Findings[1].Fixed || report.Findings[2].Fixed {
+		t.Errorf("fixes not recorded correctly: %+v", report.Findings)
+	}
+
+	if len(gock.Pending()) != 1 {
+		t.Errorf("unexpected requests: %d mocks pending", len(gock.Pending()))
+	}
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/cidr"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// DefaultUtilizationThreshold is the percentage of used addresses above which
+// a subnet is flagged
+const DefaultUtilizationThreshold = 80.0
+
+// SubnetUsage summarizes the reservations within a single subnet.  Each
+// address is counted once, using its most recent reservation.  Expired
+// reservations are counted separately and their addresses are considered free.
+// The gateway address is never free.
+type SubnetUsage struct {
+	Network string `json:"network"`
+	Subnet  string `json:"subnet"`
+	Cidr    string `json:"cidr"`
+
+	Total    uint64 `json:"total"`
+	Reserved uint64 `json:"reserved"`
+	Static   uint64 `json:"static"`
+	Dynamic  uint64 `json:"dynamic"`
+	Expired  uint64 `json:"expired"`
+	Free     uint64 `json:"free"`
+
+	// Utilization is the percentage of host addresses not free
+	Utilization   float64  `json:"utilization"`
+	OverThreshold bool     `json:"overThreshold"`
+	NextFree      []net.IP `json:"nextFree"`
+}
+
+// UtilizationReport lists the usage of every subnet, ordered by network name
+type UtilizationReport struct {
+	Threshold float64        `json:"threshold"`
+	Subnets   []*SubnetUsage `json:"subnets"`
+
+	// Skipped lists subnets whose reservations couldn't be listed
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// OverThreshold returns the subnets whose utilization exceeds the threshold
+func (r *UtilizationReport) OverThreshold() []*SubnetUsage {
+	result := []*SubnetUsage{}
+	for _, s := range r.Subnets {
+		if s.OverThreshold {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// NewSubnetUsage computes the usage of subnet at time now from reservations,
+// which may include addresses outside the subnet.  Up to nextFree free
+// addresses are listed.
+func NewSubnetUsage(network string, subnet *types.Subnet, reservations types.IPReservationList, now time.Time, nextFree int, threshold float64) *SubnetUsage {
+	usage := &SubnetUsage{Network: network, Subnet: subnet.Name, NextFree: []net.IP{}}
+	if subnet.Cidr == nil {
+		return usage
+	}
+	usage.Cidr = subnet.Cidr.String()
+	usage.Total = cidr.HostCount(subnet.Cidr)
+
+	latest := map[string]*types.IPReservation{}
+	for _, r := range reservations {
+		if r.IP == nil || !subnet.Cidr.Contains(r.IP.IP) {
+			continue
+		}
+		if current, ok := latest[r.IP.IP.String()]; !ok || newerReservation(r, current) {
+			latest[r.IP.IP.String()] = r
+		}
+	}
+
+	used := map[string]bool{}
+	for ip, r := range latest {
+		if r.End != nil && r.End.Before(now) {
+			usage.Expired++
+			continue
+		}
+		used[ip] = true
+		usage.Reserved++
+		if r.Static() {
+			usage.Static++
+		} else {
+			usage.Dynamic++
+		}
+	}
+
+	unavailable := usage.Reserved
+	if subnet.Gateway != nil && subnet.Cidr.Contains(subnet.Gateway) && !used[subnet.Gateway.String()] {
+		used[subnet.Gateway.String()] = true
+		unavailable++
+	}
+
+	if unavailable < usage.Total {
+		usage.Free = usage.Total - unavailable
+	}
+	if usage.Total > 0 {
+		usage.Utilization = float64(usage.Total-usage.Free) / float64(usage.Total) * 100
+	}
+	usage.OverThreshold = usage.Utilization > threshold
+
+	if nextFree > 0 && usage.Free > 0 {
+		cidr.EachHost(subnet.Cidr, func(ip net.IP) bool {
+			if !used[ip.String()] {
+				usage.NextFree = append(usage.NextFree, ip)
+			}
+			return len(usage.NextFree) < nextFree
+		})
+	}
+	return usage
+}
+
+// newerReservation returns true if a started after b, or at the same time
+// but ends later.  A missing start is the earliest, a missing end the latest.
+func newerReservation(a, b *types.IPReservation) bool {
+	var aStart, bStart time.Time
+	if a.Start != nil {
+		aStart = *a.Start
+	}
+	if b.Start != nil {
+		bStart = *b.Start
+	}
+	if !aStart.Equal(bStart) {
+		return aStart.After(bStart)
+	}
+	if a.End == nil || b.End == nil {
+		return a.End == nil && b.End != nil
+	}
+	return a.End.After(*b.End)
+}
+
+// Utilization reports the usage of every subnet in the named networks, or in
+// all networks if none are given.  Subnets whose reservations can't be listed
+// are recorded in the report's Skipped list.
+func (i *IPAM) Utilization(threshold float64, nextFree int, networks ...string) (*UtilizationReport, error) {
+	var nets []*types.Network
+	if len(networks) == 0 {
+		all, err := i.Inventory.Network().GetAll()
+		if err != nil {
+			return nil, err
+		}
+		nets = all
+	} else {
+		for _, name := range networks {
+			network, err := i.Inventory.Network().Get(name)
+			if err != nil {
+				return nil, err
+			}
+			nets = append(nets, network)
+		}
+	}
+
+	report := &UtilizationReport{Threshold: threshold, Subnets: []*SubnetUsage{}}
+	now := time.Now()
+	for _, network := range nets {
+		for _, subnet := range network.Subnets {
+			if subnet.Cidr == nil {
+				continue
+			}
+			reservations, err := i.GetIPReservationsBySubnet(subnet.Cidr)
+			if SkippedSubnets(err) != nil {
+				report.Skipped = append(report.Skipped, subnet.Cidr.String())
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			report.Subnets = append(report.Subnets, NewSubnetUsage(network.Name, subnet, reservations, now, nextFree, threshold))
+		}
+	}
+
+	sort.SliceStable(report.Subnets, func(a, b int) bool {
+		return report.Subnets[a].Network < report.Subnets[b].Network
+	})
+	return report, nil
+}
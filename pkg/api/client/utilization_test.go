@@ -0,0 +1,118 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func testReservation(ip string, end *time.Time) *types.IPReservation {
+	addr, subnet, _ := net.ParseCIDR(ip)
+	subnet.IP = addr
+	return &types.IPReservation{IP: subnet, End: end}
+}
+
+func TestSubnetUsage(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	_, cidr, _ := net.ParseCIDR("10.0.0.0/29")
+	subnet := &types.Subnet{Name: "a", Cidr: cidr, Gateway: net.ParseIP("10.0.0.1")}
+	reservations := types.IPReservationList{
+		testReservation("10.0.0.2/29", nil),
+		testReservation("10.0.0.3/29", &future),
+		testReservation("10.0.0.4/29", &past),
+		testReservation("10.1.0.2/24", nil),
+	}
+
+	usage := NewSubnetUsage("prov", subnet, reservations, now, 3, 50)
+	if usage.Total != 6 || usage.Reserved != 2 || usage.Static != 1 || usage.Dynamic != 1 || usage.Expired != 1 || usage.Free != 3 {
+		t.Errorf("got unexpected counts: %+v", usage)
+	}
+
+	if usage.Utilization != 50 || usage.OverThreshold {
+		t.Errorf("got unexpected utilization: %f %v", usage.Utilization, usage.OverThreshold)
+	}
+
+	expected := []string{"10.0.0.4", "10.0.0.5", "10.0.0.6"}
+	if len(usage.NextFree) != len(expected) {
+		t.Fatalf("got unexpected free addresses: %v", usage.NextFree)
+	}
+	for idx, ip := range expected {
+		if usage.NextFree[idx].String() != ip {
+			t.Errorf("got unexpected free addresses: %v", usage.NextFree)
+		}
+	}
+}
+
+func TestSubnetUsageLatestReservation(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-2 * time.Hour)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	_, cidr, _ := net.ParseCIDR("10.0.0.0/29")
+	subnet := &types.Subnet{Name: "a", Cidr: cidr}
+
+	expired := testReservation("10.0.0.2/29", &past)
+	expired.Start = &earlier
+	renewed := testReservation("10.0.0.2/29", &future)
+	renewed.Start = &past
+	released := testReservation("10.0.0.3/29", nil)
+	released.Start = &earlier
+	lapsed := testReservation("10.0.0.3/29", &past)
+	lapsed.Start = &past
+
+	usage := NewSubnetUsage("prov", subnet, types.IPReservationList{renewed, expired, released, lapsed}, now, 0, 50)
+	if usage.Reserved != 1 || usage.Dynamic != 1 || usage.Expired != 1 || usage.Free != 5 {
+		t.Errorf("addresses should be counted once by their latest reservation: %+v", usage)
+	}
+}
+
+func TestUtilization(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("network$").
+		Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Subnets": [{"Name": "a", "Cidr": "10.0.0.0/30"}, {"Name": "b", "Cidr": "10.1.0.0/16"}]}]`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.0.0.0/30").
+		Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:05","ip": "10.0.0.1/30"},{"mac": "00:01:02:03:04:06","ip": "10.0.0.2/30"}]`)
+	gock.New(testBaseUrl.String()).
+		Get("ipam/ip").
+		MatchParam("subnet", "10.1.0.0/16").
+		Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+
+	report, err := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).
+		IPAM().Utilization(DefaultUtilizationThreshold, 5)
+	if err != nil {
+		t.Fatalf("unable to get utilization: %v", err)
+	}
+
+	if len(report.Subnets) != 1 || report.Subnets[0].Free != 0 || len(report.Subnets[0].NextFree) != 0 {
+		t.Fatalf("got unexpected report: %+v", report.Subnets)
+	}
+
+	if len(report.OverThreshold()) != 1 {
+		t.Errorf("full subnet not flagged")
+	}
+
+	if len(report.Skipped) != 1 || report.Skipped[0] != "10.1.0.0/16" {
+		t.Errorf("large subnet not reported as skipped: %v", report.Skipped)
+	}
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// EventType describes the kind of change reported by a Watcher
+type EventType string
+
+const (
+	Added      EventType = "ADDED"
+	Modified   EventType = "MODIFIED"
+	Deleted    EventType = "DELETED"
+	WatchError EventType = "ERROR"
+)
+
+// ObjectKind identifies the type of inventory object an event refers to
+type ObjectKind string
+
+const (
+	KindNode       ObjectKind = "node"
+	KindNodeConfig ObjectKind = "nodeconfig"
+	KindNetwork    ObjectKind = "network"
+	KindSystem     ObjectKind = "system"
+
+	// KindIPReservation objects are listed per network.  Where the server
+	// can't list reservations every address of each subnet is looked up, and
+	// ipv6 subnets or those larger than MaxSubnetEnumeration aren't watched.
+	KindIPReservation ObjectKind = "ipreservation"
+)
+
+// AllKinds lists the kinds of object watched by default
+var AllKinds = []ObjectKind{KindNode, KindNodeConfig, KindNetwork, KindSystem, KindIPReservation}
+
+const (
+	DefaultWatchInterval   = 30 * time.Second
+	DefaultWatchMinBackoff = time.Second
+	DefaultWatchMaxBackoff = 5 * time.Minute
+)
+
+// Event describes a change to a single inventory object.  Old is nil for Added
+// events and New is nil for Deleted events.  For WatchError events only Kind
+// and Err are set.
+type Event struct {
+	Type EventType
+	Kind ObjectKind
+	ID   string
+	Old  interface{}
+	New  interface{}
+	Err  error
+
+	// Initial is set on the Added events reporting the objects present on
+	// the first successful poll of a kind, which aren't new to the inventory
+	Initial bool
+}
+
+// Watcher periodically lists inventory objects and reports differences from
+// the previously observed state.
+type Watcher struct {
+	Inventory *InventoryApi
+	Kinds     []ObjectKind
+
+	// Interval is the time between successful polls.
+	Interval time.Duration
+
+	// ResyncInterval, if non-zero, causes a Modified event to be emitted for
+	// every unchanged object at most this often, with Old and New identical.
+	ResyncInterval time.Duration
+
+	// MinBackoff and MaxBackoff bound the delay before retrying after a failed
+	// poll.  The delay doubles after each consecutive failure.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	state map[ObjectKind]map[string]interface{}
+}
+
+// Watch returns a Watcher for the kinds specified, or all kinds if none are
+// given, configured with default intervals.
+func (i *InventoryApi) Watch(kinds ...ObjectKind) *Watcher {
+	if len(kinds) == 0 {
+		kinds = AllKinds
+	}
+	return &Watcher{
+		Inventory:  i,
+		Kinds:      kinds,
+		Interval:   DefaultWatchInterval,
+		MinBackoff: DefaultWatchMinBackoff,
+		MaxBackoff: DefaultWatchMaxBackoff,
+	}
+}
+
+// Start begins polling in the background.  Every object present on the first
+// successful poll is reported as Added with Initial set.  The returned channel
+// is closed once ctx is cancelled.
+func (w *Watcher) Start(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+	go w.run(ctx, events)
+	return events
+}
+
+func (w *Watcher) run(ctx context.Context, events chan<- Event) {
+	defer close(events)
+
+	w.state = make(map[ObjectKind]map[string]interface{}, len(w.Kinds))
+	lastResync := time.Now()
+	var backoff time.Duration
+
+	for {
+		resync := w.ResyncInterval > 0 && time.Since(lastResync) >= w.ResyncInterval
+		if resync {
+			lastResync = time.Now()
+		}
+
+		wait := w.Interval
+		if wait <= 0 {
+			wait = DefaultWatchInterval
+		}
+		if err := w.poll(ctx, events, resync); err != nil {
+			backoff = w.nextBackoff(backoff)
+			wait = backoff
+		} else {
+			backoff = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (w *Watcher) nextBackoff(current time.Duration) time.Duration {
+	if current <= 0 || current < w.MinBackoff {
+		if w.MinBackoff <= 0 {
+			return DefaultWatchMinBackoff
+		}
+		return w.MinBackoff
+	}
+	current *= 2
+	if w.MaxBackoff > 0 && current > w.MaxBackoff {
+		return w.MaxBackoff
+	}
+	return current
+}
+
+// poll lists every watched kind and emits events for anything that changed.
+// Kinds that fail to list keep their previous state.
+func (w *Watcher) poll(ctx context.Context, events chan<- Event, resync bool) error {
+	var pollErr error
+	for _, kind := range w.Kinds {
+		current, err := w.list(kind)
+		if err != nil {
+			pollErr = err
+			if !sendEvent(ctx, events, Event{Type: WatchError, Kind: kind, Err: err}) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		previous := w.state[kind]
+		for _, e := range diffObjects(kind, previous, current, resync) {
+			e.Initial = previous == nil
+			if !sendEvent(ctx, events, e) {
+				return ctx.Err()
+			}
+		}
+		w.state[kind] = current
+	}
+	return pollErr
+}
+
+func sendEvent(ctx context.Context, events chan<- Event, e Event) bool {
+	select {
+	case events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (w *Watcher) list(kind ObjectKind) (map[string]interface{}, error) {
+	objects := map[string]interface{}{}
+	switch kind {
+	case KindNode:
+		nodes, err := w.Inventory.Node().GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			objects[n.ID()] = n
+		}
+	case KindNodeConfig:
+		nodes, err := w.Inventory.NodeConfig().GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			objects[n.ID()] = n
+		}
+	case KindNetwork:
+		networks, err := w.Inventory.Network().GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range networks {
+			objects[n.ID()] = n
+		}
+	case KindSystem:
+		systems, err := w.Inventory.System().GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range systems {
+			objects[s.ID()] = s
+		}
+	case KindIPReservation:
+		// subnets that can't be enumerated are skipped on every poll, so
+		// leaving them out doesn't produce events
+		reservations, err := w.Inventory.IPAM().getIPReservationsByNetworks(nil)
+		if err != nil && SkippedSubnets(err) == nil {
+			return nil, err
+		}
+		for _, r := range reservations {
+			if r.IP == nil {
+				continue
+			}
+			objects[r.IP.IP.String()] = r
+		}
+	default:
+		return nil, fmt.Errorf("unable to watch unknown object kind: %s", kind)
+	}
+	return objects, nil
+}
+
+// diffObjects compares two snapshots of a kind, returning events ordered by object ID
+func diffObjects(kind ObjectKind, previous, current map[string]interface{}, resync bool) []Event {
+	ids := make([]string, 0, len(previous)+len(current))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	for id := range previous {
+		if _, ok := current[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	events := []Event{}
+	for _, id := range ids {
+		oldObj, existed := previous[id]
+		newObj, exists := current[id]
+		switch {
+		case !existed:
+			events = append(events, Event{Type: Added, Kind: kind, ID: id, New: newObj})
+		case !exists:
+			events = append(events, Event{Type: Deleted, Kind: kind, ID: id, Old: oldObj})
+		case resync || !reflect.DeepEqual(oldObj, newObj):
+			events = append(events, Event{Type: Modified, Kind: kind, ID: id, Old: oldObj, New: newObj})
+		}
+	}
+	return events
+}
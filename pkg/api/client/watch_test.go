@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatalf("event channel closed unexpectedly")
+		}
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestWatchNodes(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).Get("node").Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Role": "worker"}, {"InventoryID": "test-001"}]`)
+	gock.New(testBaseUrl.String()).Get("node").Reply(http.StatusInternalServerError).
+		BodyString(`{"status": "Internal Server Error", "error": "oops"}`)
+	gock.New(testBaseUrl.String()).Get("node").Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Role": "login"}, {"InventoryID": "test-002"}]`)
+	gock.New(testBaseUrl.String()).Get("node").Persist().Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Role": "login"}, {"InventoryID": "test-002"}]`)
+
+	watcher := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).Watch(KindNode)
+	watcher.Interval = 10 * time.Millisecond
+	watcher.MinBackoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := watcher.Start(ctx)
+
+	expected := []struct {
+		Type    EventType
+		ID      string
+		Initial bool
+	}{
+		{Added, "test-000", true},
+		{Added, "test-001", true},
+		{WatchError, "", false},
+		{Modified, "test-000", false},
+		{Deleted, "test-001", false},
+		{Added, "test-002", false},
+	}
+
+	for _, exp := range expected {
+		e := nextEvent(t, events)
+		if e.Type != exp.Type || e.ID != exp.ID || e.Initial != exp.Initial {
+			t.Fatalf("got unexpected event: expected %s %s (initial %t), got %s %s (initial %t)", exp.Type, exp.ID, exp.Initial, e.Type, e.ID, e.Initial)
+		}
+		if e.Kind != KindNode {
+			t.Errorf("got wrong kind: %s", e.Kind)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchIPReservations(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	// reservations are listed per network, so those whose node has been
+	// removed are still seen
+	gock.New(testBaseUrl.String()).Get("network").Persist().Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24"}]}]`)
+	gock.New(testBaseUrl.String()).Get("ipam/ip").MatchParam("network", "prov").Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:05", "ip": "10.0.0.10/24"}, {"mac": "00:01:02:03:04:99", "ip": "10.0.0.99/24"}]`)
+	gock.New(testBaseUrl.String()).Get("ipam/ip").MatchParam("network", "prov").Persist().Reply(http.StatusOK).
+		BodyString(`[{"mac": "00:01:02:03:04:05", "ip": "10.0.0.10/24"}]`)
+
+	watcher := NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}).Watch(KindIPReservation)
+	watcher.Interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := watcher.Start(ctx)
+
+	for _, exp := range []struct {
+		Type EventType
+		ID   string
+	}{{Added, "10.0.0.10"}, {Added, "10.0.0.99"}, {Deleted, "10.0.0.99"}} {
+		if e := nextEvent(t, events); e.Type != exp.Type || e.ID != exp.ID || e.Kind != KindIPReservation {
+			t.Fatalf("got unexpected event: expected %s %s, got %s %s %s", exp.Type, exp.ID, e.Type, e.Kind, e.ID)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchModifiedEventObjects(t *testing.T) {
+	previous := map[string]interface{}{"a": &types.Network{Name: "a", MTU: 1500}}
+	current := map[string]interface{}{"a": &types.Network{Name: "a", MTU: 9000}}
+
+	events := diffObjects(KindNetwork, previous, current, false)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+
+	if events[0].Old.(*types.Network).MTU != 1500 || events[0].New.(*types.Network).MTU != 9000 {
+		t.Errorf("old and new objects not set correctly: %v", events[0])
+	}
+}
+
+func TestWatchResync(t *testing.T) {
+	state := map[string]interface{}{"a": &types.System{Name: "a"}}
+
+	if events := diffObjects(KindSystem, state, state, false); len(events) != 0 {
+		t.Errorf("expected no events for unchanged state, got %d", len(events))
+	}
+
+	events := diffObjects(KindSystem, state, state, true)
+	if len(events) != 1 || events[0].Type != Modified {
+		t.Errorf("expected a single modified event on resync, got %v", events)
+	}
+}
+
+func TestWatchBackoff(t *testing.T) {
+	w := &Watcher{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	backoff := time.Duration(0)
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		backoff = w.nextBackoff(backoff)
+		if backoff != expected {
+			t.Errorf("got wrong backoff: expected %s, got %s", expected, backoff)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package cidr provides address arithmetic for IPv4 and IPv6 subnets.
+package cidr
+
+import (
+	"bytes"
+	"math"
+	"net"
+)
+
+// normalize returns the 4 byte form of IPv4 addresses and the 16 byte form of
+// everything else
+func normalize(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip.To16()
+}
+
+// IsV4 returns true if n is an IPv4 subnet
+func IsV4(n *net.IPNet) bool {
+	return n.IP.To4() != nil && len(n.Mask) == net.IPv4len
+}
+
+// First returns the first address in n
+func First(n *net.IPNet) net.IP {
+	return normalize(n.IP.Mask(n.Mask))
+}
+
+// Last returns the last address in n, the broadcast address for IPv4 subnets
+func Last(n *net.IPNet) net.IP {
+	first := First(n)
+	mask := n.Mask
+	if len(mask) != len(first) {
+		mask = mask[len(mask)-len(first):]
+	}
+	last := make(net.IP, len(first))
+	for i := range first {
+		last[i] = first[i] | ^mask[i]
+	}
+	return last
+}
+
+// Size returns the number of addresses in n, saturating at math.MaxUint64
+func Size(n *net.IPNet) uint64 {
+	ones, bits := n.Mask.Size()
+	if bits-ones >= 64 {
+		return math.MaxUint64
+	}
+	return uint64(1) << uint(bits-ones)
+}
+
+// HostRange returns the first and last usable host addresses in n.  The
+// network and broadcast addresses are excluded from IPv4 subnets larger than
+// a /31.
+func HostRange(n *net.IPNet) (net.IP, net.IP) {
+	first, last := First(n), Last(n)
+	ones, bits := n.Mask.Size()
+	if IsV4(n) && bits-ones > 1 {
+		return Next(first), Prev(last)
+	}
+	return first, last
+}
+
+// HostCount returns the number of usable host addresses in n
+func HostCount(n *net.IPNet) uint64 {
+	size := Size(n)
+	ones, bits := n.Mask.Size()
+	if IsV4(n) && bits-ones > 1 {
+		return size - 2
+	}
+	return size
+}
+
+// Next returns the address following ip, wrapping at the end of the address space
+func Next(ip net.IP) net.IP {
+	next := normalize(ip)
+	next = append(net.IP{}, next...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// Prev returns the address preceding ip, wrapping at the start of the address space
+func Prev(ip net.IP) net.IP {
+	prev := normalize(ip)
+	prev = append(net.IP{}, prev...)
+	for i := len(prev) - 1; i >= 0; i-- {
+		prev[i]--
+		if prev[i] != 0xff {
+			break
+		}
+	}
+	return prev
+}
+
+// Compare returns -1, 0 or 1 if a is less than, equal to or greater than b
+func Compare(a, b net.IP) int {
+	return bytes.Compare(a.To16(), b.To16())
+}
+
+// Overlaps returns true if a and b share any addresses
+func Overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP.Mask(b.Mask)) || b.Contains(a.IP.Mask(a.Mask))
+}
+
+// EachHost calls f for every usable host address in n, in order, until f
+// returns false
+func EachHost(n *net.IPNet, f func(net.IP) bool) {
+	first, last := HostRange(n)
+	for ip := first; ; ip = Next(ip) {
+		if !f(ip) || ip.Equal(last) {
+			return
+		}
+	}
+}
+
+// NextFree returns the first block of the given prefix length within parent
+// that doesn't overlap any of used.  It returns false if parent has no such
+// block or prefixLen is shorter than parent's prefix.
+func NextFree(parent *net.IPNet, prefixLen int, used []*net.IPNet) (*net.IPNet, bool) {
+	ones, bits := parent.Mask.Size()
+	if prefixLen < ones || prefixLen > bits {
+		return nil, false
+	}
+	mask := net.CIDRMask(prefixLen, bits)
+
+	candidate := &net.IPNet{IP: First(parent), Mask: mask}
+	for parent.Contains(candidate.IP) {
+		var conflict *net.IPNet
+		for _, u := range used {
+			if Overlaps(candidate, u) && (conflict == nil || Compare(Last(u), Last(conflict)) > 0) {
+				conflict = u
+			}
+		}
+		if conflict == nil {
+			return candidate, true
+		}
+
+		end := Last(candidate)
+		if Compare(Last(conflict), end) > 0 {
+			end = Last(conflict)
+		}
+		next := Next(end)
+		if Compare(next, end) <= 0 {
+			// wrapped around the end of the address space
+			return nil, false
+		}
+
+		// round up to the next boundary of the requested size
+		aligned := next.Mask(mask)
+		if !aligned.Equal(next) {
+			aligned = Next(Last(&net.IPNet{IP: aligned, Mask: mask}))
+			if Compare(aligned, next) <= 0 {
+				return nil, false
+			}
+		}
+		candidate = &net.IPNet{IP: normalize(aligned), Mask: mask}
+	}
+	return nil, false
+}
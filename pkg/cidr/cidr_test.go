@@ -0,0 +1,138 @@
+package cidr
+
+import (
+	"math"
+	"net"
+	"testing"
+)
+
+func parseNet(s string) *net.IPNet {
+	_, n, _ := net.ParseCIDR(s)
+	return n
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		cidr      string
+		first     string
+		last      string
+		hostFirst string
+		hostLast  string
+		size      uint64
+		hosts     uint64
+	}{
+		{"10.0.0.0/24", "10.0.0.0", "10.0.0.255", "10.0.0.1", "10.0.0.254", 256, 254},
+		{"10.0.0.4/31", "10.0.0.4", "10.0.0.5", "10.0.0.4", "10.0.0.5", 2, 2},
+		{"10.0.0.7/32", "10.0.0.7", "10.0.0.7", "10.0.0.7", "10.0.0.7", 1, 1},
+		{"2001:db8::/126", "2001:db8::", "2001:db8::3", "2001:db8::", "2001:db8::3", 4, 4},
+		{"2001:db8::/64", "2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", math.MaxUint64, math.MaxUint64},
+	}
+
+	for _, test := range tests {
+		n := parseNet(test.cidr)
+		if First(n).String() != test.first || Last(n).String() != test.last {
+			t.Errorf("%s: got wrong range %s - %s", test.cidr, First(n), Last(n))
+		}
+		hostFirst, hostLast := HostRange(n)
+		if hostFirst.String() != test.hostFirst || hostLast.String() != test.hostLast {
+			t.Errorf("%s: got wrong host range %s - %s", test.cidr, hostFirst, hostLast)
+		}
+		if Size(n) != test.size || HostCount(n) != test.hosts {
+			t.Errorf("%s: got wrong sizes %d, %d", test.cidr, Size(n), HostCount(n))
+		}
+	}
+}
+
+func TestNextPrev(t *testing.T) {
+	if Next(net.ParseIP("10.0.0.255")).String() != "10.0.1.0" {
+		t.Errorf("got wrong next address: %s", Next(net.ParseIP("10.0.0.255")))
+	}
+	if Prev(net.ParseIP("10.0.1.0")).String() != "10.0.0.255" {
+		t.Errorf("got wrong previous address: %s", Prev(net.ParseIP("10.0.1.0")))
+	}
+	if Next(net.ParseIP("2001:db8::ffff")).String() != "2001:db8::1:0" {
+		t.Errorf("got wrong next address: %s", Next(net.ParseIP("2001:db8::ffff")))
+	}
+
+	ip := net.ParseIP("10.0.0.1")
+	Next(ip)
+	if ip.String() != "10.0.0.1" {
+		t.Errorf("Next modified its argument")
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	if !Overlaps(parseNet("10.0.0.0/16"), parseNet("10.0.4.0/24")) || !Overlaps(parseNet("10.0.4.0/24"), parseNet("10.0.0.0/16")) {
+		t.Errorf("expected subnets to overlap")
+	}
+	if Overlaps(parseNet("10.0.0.0/24"), parseNet("10.0.1.0/24")) {
+		t.Errorf("expected subnets not to overlap")
+	}
+}
+
+func TestEachHost(t *testing.T) {
+	hosts := []string{}
+	EachHost(parseNet("10.0.0.0/29"), func(ip net.IP) bool {
+		hosts = append(hosts, ip.String())
+		return true
+	})
+	if len(hosts) != 6 || hosts[0] != "10.0.0.1" || hosts[5] != "10.0.0.6" {
+		t.Errorf("got wrong hosts: %v", hosts)
+	}
+
+	count := 0
+	EachHost(parseNet("2001:db8::/64"), func(ip net.IP) bool {
+		count++
+		return count < 3
+	})
+	if count != 3 {
+		t.Errorf("iteration didn't stop when requested")
+	}
+
+	if Compare(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")) != -1 {
+		t.Errorf("got wrong comparison")
+	}
+}
+
+func TestNextFree(t *testing.T) {
+	_, parent, _ := net.ParseCIDR("10.0.0.0/16")
+	used := []*net.IPNet{}
+	for _, s := range []string{"10.0.0.0/24", "10.0.1.0/25", "10.0.2.0/23", "192.168.0.0/24"} {
+		_, n, _ := net.ParseCIDR(s)
+		used = append(used, n)
+	}
+
+	tests := []struct {
+		PrefixLen int
+		Expected  string
+	}{
+		{24, "10.0.4.0/24"},
+		{25, "10.0.1.128/25"},
+		{22, "10.0.4.0/22"},
+		{16, ""},
+		{8, ""},
+	}
+
+	for _, test := range tests {
+		n, ok := NextFree(parent, test.PrefixLen, used)
+		if test.Expected == "" {
+			if ok {
+				t.Errorf("expected no free /%d, got %s", test.PrefixLen, n)
+			}
+			continue
+		}
+		if !ok || n.String() != test.Expected {
+			t.Errorf("got wrong free /%d: expected %s, got %v", test.PrefixLen, test.Expected, n)
+		}
+	}
+}
+
+func TestNextFreeV6(t *testing.T) {
+	_, parent, _ := net.ParseCIDR("2001:db8::/48")
+	_, used, _ := net.ParseCIDR("2001:db8::/64")
+
+	n, ok := NextFree(parent, 64, []*net.IPNet{used})
+	if !ok || n.String() != "2001:db8:0:1::/64" {
+		t.Errorf("got wrong free block: %v", n)
+	}
+}
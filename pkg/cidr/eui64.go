@@ -0,0 +1,43 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+// EUI64 returns the SLAAC address formed from prefix and the modified EUI-64
+// interface identifier of mac, as described in RFC 4291 appendix A.  The
+// prefix must be IPv6 and no longer than /64, and mac must be a 48 or 64 bit
+// address.
+func EUI64(prefix *net.IPNet, mac net.HardwareAddr) (net.IP, error) {
+	if IsV4(prefix) {
+		return nil, fmt.Errorf("unable to compute an eui-64 address in ipv4 prefix %s", prefix)
+	}
+	if ones, _ := prefix.Mask.Size(); ones > 64 {
+		return nil, fmt.Errorf("unable to compute an eui-64 address in %s: prefix must be /64 or shorter", prefix)
+	}
+
+	var id []byte
+	switch len(mac) {
+	case 6:
+		id = []byte{mac[0], mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+	case 8:
+		id = append([]byte{}, mac...)
+	default:
+		return nil, fmt.Errorf("unable to compute an eui-64 address from mac %s", mac)
+	}
+	id[0] ^= 0x02
+
+	ip := append(net.IP{}, prefix.IP.Mask(prefix.Mask).To16()...)
+	copy(ip[8:], id)
+	return ip, nil
+}
+
+// MACFromEUI64 recovers the 48 bit MAC address from an address with a modified
+// EUI-64 interface identifier.  It returns false if ip isn't such an address.
+func MACFromEUI64(ip net.IP) (net.HardwareAddr, bool) {
+	if ip.To4() != nil || len(ip) != net.IPv6len || ip[11] != 0xff || ip[12] != 0xfe {
+		return nil, false
+	}
+	return net.HardwareAddr{ip[8] ^ 0x02, ip[9], ip[10], ip[13], ip[14], ip[15]}, true
+}
@@ -0,0 +1,38 @@
+package cidr
+
+import (
+	"net"
+	"testing"
+)
+
+func TestEUI64(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("2001:db8:1:2::/64")
+	mac, _ := net.ParseMAC("00:25:96:12:34:56")
+
+	ip, err := EUI64(prefix, mac)
+	if err != nil {
+		t.Fatalf("unable to compute eui-64 address: %v", err)
+	}
+	if ip.String() != "2001:db8:1:2:225:96ff:fe12:3456" {
+		t.Errorf("got unexpected address: %s", ip)
+	}
+
+	recovered, ok := MACFromEUI64(ip)
+	if !ok || recovered.String() != mac.String() {
+		t.Errorf("unable to recover mac from %s: %v", ip, recovered)
+	}
+
+	if _, ok := MACFromEUI64(net.ParseIP("2001:db8::1")); ok {
+		t.Errorf("expected non eui-64 address to be rejected")
+	}
+}
+
+func TestEUI64InvalidPrefix(t *testing.T) {
+	mac, _ := net.ParseMAC("00:25:96:12:34:56")
+	for _, prefix := range []string{"10.0.0.0/24", "2001:db8::/96"} {
+		_, n, _ := net.ParseCIDR(prefix)
+		if _, err := EUI64(n, mac); err == nil {
+			t.Errorf("expected an error for prefix %s", prefix)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	DefaultTTL             = time.Minute
+	DefaultRefreshInterval = time.Minute
+
+	// maxUDPSize is the largest response sent over UDP.  Larger responses
+	// are truncated so the client retries over TCP.
+	maxUDPSize = 512
+)
+
+// Server answers queries for names in its zones from the most recently
+// loaded view of the inventory
+type Server struct {
+	Inventory *client.InventoryApi
+
+	// Zones lists the origins the server is authoritative for.  Queries
+	// outside every zone are refused.  If empty, the zones of the current
+	// view are used.
+	Zones []string
+
+	// TTL is used for every answer and for negative caching
+	TTL time.Duration
+
+	// RefreshInterval is the time between reloads of the view made by Run
+	RefreshInterval time.Duration
+
+	// NameServer and Hostmaster are used in SOA records, defaulting to ns
+	// and hostmaster within each zone
+	NameServer string
+	Hostmaster string
+
+	// OnRefresh, if set, is called with the result of each reload made by Run
+	OnRefresh func(err error)
+
+	lock sync.RWMutex
+	view *View
+}
+
+// NewServer returns a server with default timings
+func NewServer(inv *client.InventoryApi, zones ...string) *Server {
+	return &Server{Inventory: inv, Zones: zones, TTL: DefaultTTL, RefreshInterval: DefaultRefreshInterval}
+}
+
+// View returns the current view, or nil if none has been loaded
+func (s *Server) View() *View {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.view
+}
+
+// SetView replaces the current view
+func (s *Server) SetView(v *View) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.view = v
+}
+
+// Refresh reloads the view from the inventory.  The previous view is kept
+// if the reload fails.
+func (s *Server) Refresh() error {
+	v, err := LoadView(s.Inventory)
+	if err != nil {
+		return err
+	}
+	s.SetView(v)
+	return nil
+}
+
+// Run refreshes the view every RefreshInterval until ctx is cancelled
+func (s *Server) Run(ctx context.Context) {
+	interval := s.RefreshInterval
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		err := s.Refresh()
+		if s.OnRefresh != nil {
+			s.OnRefresh(err)
+		}
+	}
+}
+
+// ListenAndServe answers queries over UDP and TCP on addr until ctx is
+// cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on udp %s: %v", addr, err)
+	}
+
+	l, err := net.Listen("tcp", conn.LocalAddr().String())
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("unable to listen on tcp %s: %v", addr, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+		l.Close()
+	}()
+
+	errs := make(chan error, 2)
+	go func() { errs <- s.Serve(conn) }()
+	go func() { errs <- s.ServeTCP(l) }()
+
+	err = <-errs
+	conn.Close()
+	l.Close()
+	<-errs
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Serve answers UDP queries received on conn until it is closed
+func (s *Server) Serve(conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		response, err := s.answer(buf[:n], maxUDPSize)
+		if err != nil {
+			continue
+		}
+		if _, err := conn.WriteTo(response, addr); err != nil {
+			log.Printf("unable to send response to %s: %v", addr, err)
+		}
+	}
+}
+
+// ServeTCP answers length prefixed queries on connections accepted from l
+// until it is closed
+func (s *Server) ServeTCP(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		query := make([]byte, length)
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+
+		response, err := s.answer(query, 0)
+		if err != nil {
+			return
+		}
+		if err := binary.Write(conn, binary.BigEndian, uint16(len(response))); err != nil {
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// Answer returns the response to a single query message
+func (s *Server) Answer(query []byte) ([]byte, error) {
+	return s.answer(query, 0)
+}
+
+// answer builds the response to query, truncating it if it is larger than
+// limit.  Malformed queries and responses are dropped.
+func (s *Server) answer(query []byte, limit int) ([]byte, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse query: %v", err)
+	}
+	if h.Response {
+		return nil, fmt.Errorf("message is not a query")
+	}
+
+	r := &response{header: dnsmessage.Header{ID: h.ID, Response: true, OpCode: h.OpCode, RecursionDesired: h.RecursionDesired}}
+	q, err := p.Question()
+	switch {
+	case err != nil:
+		r.header.RCode = dnsmessage.RCodeFormatError
+	case h.OpCode != 0:
+		r.question = &q
+		r.header.RCode = dnsmessage.RCodeNotImplemented
+	default:
+		r.question = &q
+		s.resolve(r)
+	}
+
+	data, err := r.pack(false)
+	if err == nil && limit > 0 && len(data) > limit {
+		data, err = r.pack(true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to build response: %v", err)
+	}
+	return data, nil
+}
+
+// resolve fills in the answer to the response's question
+func (s *Server) resolve(r *response) {
+	view := s.View()
+	if view == nil {
+		r.header.RCode = dnsmessage.RCodeServerFailure
+		return
+	}
+
+	name := Canonical(r.question.Name.String())
+	zone := s.zone(view, name)
+	if zone == "" || r.question.Class != dnsmessage.ClassINET {
+		r.header.RCode = dnsmessage.RCodeRefused
+		return
+	}
+	r.header.Authoritative = true
+
+	ttl := uint32(s.ttl() / time.Second)
+	ips, exists := view.Lookup(name)
+	ptr, isPTR := view.LookupPTR(name)
+	exists = exists || isPTR || name == zone
+
+	switch r.question.Type {
+	case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+		for _, ip := range ips {
+			if v4 := ip.To4(); v4 != nil && r.question.Type == dnsmessage.TypeA {
+				a := &dnsmessage.AResource{}
+				copy(a.A[:], v4)
+				r.answers = append(r.answers, a)
+			} else if v4 == nil && r.question.Type == dnsmessage.TypeAAAA {
+				aaaa := &dnsmessage.AAAAResource{}
+				copy(aaaa.AAAA[:], ip.To16())
+				r.answers = append(r.answers, aaaa)
+			}
+		}
+	case dnsmessage.TypePTR:
+		if isPTR {
+			target, err := dnsmessage.NewName(ptr)
+			if err == nil {
+				r.answers = append(r.answers, &dnsmessage.PTRResource{PTR: target})
+			}
+		}
+	case dnsmessage.TypeSOA:
+		if name == zone {
+			if soa, err := s.soa(view, zone); err == nil {
+				r.answers = append(r.answers, soa)
+			}
+		}
+	}
+	r.ttl = ttl
+
+	if len(r.answers) == 0 {
+		if !exists {
+			r.header.RCode = dnsmessage.RCodeNameError
+		}
+		if soa, err := s.soa(view, zone); err == nil {
+			soaName, _ := dnsmessage.NewName(zone)
+			r.authority = soa
+			r.authorityName = soaName
+		}
+	}
+}
+
+// zone returns the longest zone containing name, or an empty string
+func (s *Server) zone(view *View, name string) string {
+	zones := s.Zones
+	if len(zones) == 0 {
+		zones = view.Zones
+	}
+
+	match := ""
+	for _, zone := range zones {
+		zone = Canonical(zone)
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(match) {
+			match = zone
+		}
+	}
+	return match
+}
+
+func (s *Server) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return DefaultTTL
+	}
+	return s.TTL
+}
+
+// soa returns the SOA record of a zone, with a serial derived from the time
+// the view was loaded
+func (s *Server) soa(view *View, zone string) (*dnsmessage.SOAResource, error) {
+	ns := "ns." + zone
+	if s.NameServer != "" {
+		ns = Canonical(s.NameServer)
+	}
+	hostmaster := "hostmaster." + zone
+	if s.Hostmaster != "" {
+		hostmaster = Canonical(strings.Replace(s.Hostmaster, "@", ".", 1))
+	}
+
+	nsName, err := dnsmessage.NewName(ns)
+	if err != nil {
+		return nil, err
+	}
+	mbox, err := dnsmessage.NewName(hostmaster)
+	if err != nil {
+		return nil, err
+	}
+	return &dnsmessage.SOAResource{
+		NS:      nsName,
+		MBox:    mbox,
+		Serial:  uint32(view.Loaded.Unix()),
+		Refresh: uint32(export.DefaultZoneRefresh / time.Second),
+		Retry:   uint32(export.DefaultZoneRetry / time.Second),
+		Expire:  uint32(export.DefaultZoneExpire / time.Second),
+		MinTTL:  uint32(s.ttl() / time.Second),
+	}, nil
+}
+
+// response collects the parts of a response before it is packed
+type response struct {
+	header        dnsmessage.Header
+	question      *dnsmessage.Question
+	answers       []dnsmessage.ResourceBody
+	authority     *dnsmessage.SOAResource
+	authorityName dnsmessage.Name
+	ttl           uint32
+}
+
+// pack builds the response message.  Truncated responses omit every record
+// and set the TC bit.
+func (r *response) pack(truncated bool) ([]byte, error) {
+	header := r.header
+	header.Truncated = truncated
+
+	b := dnsmessage.NewBuilder(nil, header)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if r.question != nil {
+		if err := b.Question(*r.question); err != nil {
+			return nil, err
+		}
+	}
+	if truncated || r.question == nil {
+		return b.Finish()
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	rh := dnsmessage.ResourceHeader{Name: r.question.Name, Class: dnsmessage.ClassINET, TTL: r.ttl}
+	for _, answer := range r.answers {
+		var err error
+		switch body := answer.(type) {
+		case *dnsmessage.AResource:
+			err = b.AResource(rh, *body)
+		case *dnsmessage.AAAAResource:
+			err = b.AAAAResource(rh, *body)
+		case *dnsmessage.PTRResource:
+			err = b.PTRResource(rh, *body)
+		case *dnsmessage.SOAResource:
+			err = b.SOAResource(rh, *body)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.authority != nil {
+		if err := b.StartAuthorities(); err != nil {
+			return nil, err
+		}
+		authority := dnsmessage.ResourceHeader{Name: r.authorityName, Class: dnsmessage.ClassINET, TTL: r.ttl}
+		if err := b.SOAResource(authority, *r.authority); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
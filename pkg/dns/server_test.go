@@ -0,0 +1,193 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func testServer() *Server {
+	s := NewServer(nil)
+	s.TTL = 30 * time.Second
+	s.SetView(testView())
+	return s
+}
+
+func query(t *testing.T, name string, qtype dnsmessage.Type) []byte {
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	data, err := q.Pack()
+	if err != nil {
+		t.Fatalf("unable to pack query: %v", err)
+	}
+	return data
+}
+
+func ask(t *testing.T, s *Server, name string, qtype dnsmessage.Type) *dnsmessage.Message {
+	data, err := s.Answer(query(t, name, qtype))
+	if err != nil {
+		t.Fatalf("unable to answer query: %v", err)
+	}
+	m := &dnsmessage.Message{}
+	if err := m.Unpack(data); err != nil {
+		t.Fatalf("unable to unpack response: %v", err)
+	}
+	if m.Header.ID != 42 || !m.Header.Response {
+		t.Errorf("got unexpected header: %v", m.Header)
+	}
+	return m
+}
+
+func TestAnswerA(t *testing.T) {
+	m := ask(t, testServer(), "tsys-test-000.prov.example.com.", dnsmessage.TypeA)
+
+	if m.Header.RCode != dnsmessage.RCodeSuccess || !m.Header.Authoritative {
+		t.Errorf("got unexpected header: %v", m.Header)
+	}
+	if len(m.Answers) != 1 {
+		t.Fatalf("expected one answer, got %v", m.Answers)
+	}
+	a, ok := m.Answers[0].Body.(*dnsmessage.AResource)
+	if !ok || net.IP(a.A[:]).String() != "10.0.0.10" || m.Answers[0].Header.TTL != 30 {
+		t.Errorf("got unexpected answer: %v", m.Answers[0])
+	}
+}
+
+func TestAnswerAAAA(t *testing.T) {
+	m := ask(t, testServer(), "TSYS-TEST-000.prov.example.com.", dnsmessage.TypeAAAA)
+
+	if len(m.Answers) != 1 {
+		t.Fatalf("expected one answer, got %v", m.Answers)
+	}
+	aaaa, ok := m.Answers[0].Body.(*dnsmessage.AAAAResource)
+	if !ok || net.IP(aaaa.AAAA[:]).String() != "2001:db8::10" {
+		t.Errorf("got unexpected answer: %v", m.Answers[0])
+	}
+}
+
+func TestAnswerPTR(t *testing.T) {
+	m := ask(t, testServer(), "20.0.0.10.in-addr.arpa.", dnsmessage.TypePTR)
+
+	if len(m.Answers) != 1 {
+		t.Fatalf("expected one answer, got %v", m.Answers)
+	}
+	ptr, ok := m.Answers[0].Body.(*dnsmessage.PTRResource)
+	if !ok || ptr.PTR.String() != "switch-01.prov.example.com." {
+		t.Errorf("got unexpected answer: %v", m.Answers[0])
+	}
+}
+
+func TestAnswerNXDomain(t *testing.T) {
+	m := ask(t, testServer(), "missing.prov.example.com.", dnsmessage.TypeA)
+
+	if m.Header.RCode != dnsmessage.RCodeNameError {
+		t.Errorf("expected NXDOMAIN, got %s", m.Header.RCode)
+	}
+	if len(m.Authorities) != 1 || m.Authorities[0].Header.Name.String() != "prov.example.com." {
+		t.Fatalf("expected SOA in authority section, got %v", m.Authorities)
+	}
+	if soa := m.Authorities[0].Body.(*dnsmessage.SOAResource); soa.MinTTL != 30 || soa.NS.String() != "ns.prov.example.com." {
+		t.Errorf("got unexpected SOA: %v", soa)
+	}
+}
+
+func TestAnswerNoData(t *testing.T) {
+	m := ask(t, testServer(), "switch-01.prov.example.com.", dnsmessage.TypeAAAA)
+
+	if m.Header.RCode != dnsmessage.RCodeSuccess || len(m.Answers) != 0 || len(m.Authorities) != 1 {
+		t.Errorf("expected an empty answer with SOA, got %v", m)
+	}
+}
+
+func TestAnswerRefused(t *testing.T) {
+	m := ask(t, testServer(), "www.example.org.", dnsmessage.TypeA)
+	if m.Header.RCode != dnsmessage.RCodeRefused || m.Header.Authoritative {
+		t.Errorf("expected refused, got %v", m.Header)
+	}
+
+	s := testServer()
+	s.Zones = []string{"other.example.com"}
+	m = ask(t, s, "tsys-test-000.prov.example.com.", dnsmessage.TypeA)
+	if m.Header.RCode != dnsmessage.RCodeRefused {
+		t.Errorf("expected refused outside configured zones, got %v", m.Header)
+	}
+}
+
+func TestAnswerWithoutView(t *testing.T) {
+	m := ask(t, NewServer(nil), "tsys-test-000.prov.example.com.", dnsmessage.TypeA)
+	if m.Header.RCode != dnsmessage.RCodeServerFailure {
+		t.Errorf("expected server failure, got %v", m.Header)
+	}
+}
+
+func TestListenAndServe(t *testing.T) {
+	s := testServer()
+
+	// find a free port, then serve on it
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to find a free port: %v", err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.ListenAndServe(ctx, addr) }()
+
+	var response []byte
+	for attempt := 0; attempt < 20 && response == nil; attempt++ {
+		c, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Fatalf("unable to dial server: %v", err)
+		}
+		c.SetDeadline(time.Now().Add(100 * time.Millisecond))
+		c.Write(query(t, "tsys-test-000.prov.example.com.", dnsmessage.TypeA))
+		buf := make([]byte, 512)
+		if n, err := c.Read(buf); err == nil {
+			response = buf[:n]
+		} else {
+			time.Sleep(10 * time.Millisecond)
+		}
+		c.Close()
+	}
+
+	m := &dnsmessage.Message{}
+	if err := m.Unpack(response); err != nil || len(m.Answers) != 1 {
+		t.Errorf("got unexpected response: %v %v", m, err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error from server: %v", err)
+	}
+}
+
+func TestAnswerTruncated(t *testing.T) {
+	s := testServer()
+	v := s.View()
+	for i := 1; i <= 40; i++ {
+		v.Add("many.prov.example.com", net.IPv4(10, 0, 0, byte(100+i)))
+	}
+
+	data, err := s.answer(query(t, "many.prov.example.com.", dnsmessage.TypeA), maxUDPSize)
+	if err != nil {
+		t.Fatalf("unable to answer query: %v", err)
+	}
+	m := &dnsmessage.Message{}
+	if err := m.Unpack(data); err != nil {
+		t.Fatalf("unable to unpack response: %v", err)
+	}
+	if !m.Header.Truncated || len(m.Answers) != 0 || len(m.Questions) != 1 {
+		t.Errorf("expected truncated response, got %v", m)
+	}
+
+	if m := ask(t, s, "many.prov.example.com.", dnsmessage.TypeA); m.Header.Truncated || len(m.Answers) != 40 {
+		t.Errorf("expected full response without a limit, got %d answers", len(m.Answers))
+	}
+}
@@ -0,0 +1,137 @@
+// Package dns is a small authoritative DNS server answering A, AAAA and PTR
+// queries from the nodes and IP reservations in the inventory.
+package dns
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+)
+
+// View is a point in time set of names and addresses.  Names are lower case
+// and fully qualified with a trailing dot.
+type View struct {
+	// Zones lists the origins of the forward and reverse zones derived from
+	// the networks, used when a server isn't configured with its own
+	Zones []string
+
+	Loaded time.Time
+
+	// ReservationsErr is set if some or all reservations couldn't be loaded,
+	// in which case the view has the node addresses and the reservations
+	// that could be listed
+	ReservationsErr error
+
+	forward map[string][]net.IP
+	reverse map[string]string
+}
+
+// LoadView fetches every node config, network and network reservation.
+// Failing to list reservations isn't fatal, it's recorded in ReservationsErr.
+func LoadView(inv *client.InventoryApi) (*View, error) {
+	s, err := export.LoadSnapshot(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	reservationsErr := s.LoadReservations(inv)
+	v := NewView(s, time.Now())
+	v.ReservationsErr = reservationsErr
+	return v, nil
+}
+
+// NewView builds a view from a snapshot.  Node addresses are named after
+// their node, as are reservations for a node's MAC that aren't assigned to
+// it, and other reservations after their host information.  Reservations that
+// aren't valid at now, and addresses outside every network with a domain, are
+// ignored.
+func NewView(s *export.Snapshot, now time.Time) *View {
+	v := &View{Loaded: now, forward: map[string][]net.IP{}, reverse: map[string]string{}}
+
+	zones := map[string]bool{}
+	for _, n := range s.Networks {
+		if n.Domain != "" {
+			zones[Canonical(n.Domain)] = true
+		}
+		for _, subnet := range n.Subnets {
+			if subnet.Cidr != nil {
+				zones[export.ReverseZone(subnet.Cidr)] = true
+			}
+		}
+	}
+	for zone := range zones {
+		v.Zones = append(v.Zones, zone)
+	}
+	sort.Strings(v.Zones)
+
+	assigned := map[string]bool{}
+	hostnames := map[string]string{}
+	for _, a := range s.Addresses() {
+		for _, mac := range a.MACs {
+			hostnames[mac.String()] = a.Hostname
+		}
+		assigned[a.IP.String()] = true
+		if a.Network.Domain != "" {
+			v.Add(a.FQDN(), a.IP)
+		}
+	}
+
+	for _, r := range s.Reservations {
+		if r.IP == nil || assigned[r.IP.IP.String()] || !r.ValidAt(now) {
+			continue
+		}
+		network, _ := s.NetworkContaining(r.IP.IP)
+		if network == nil || network.Domain == "" {
+			continue
+		}
+
+		name := r.HostInformation
+		if hostname, ok := hostnames[r.MAC.String()]; ok && len(r.MAC) > 0 {
+			name = hostname
+		}
+		if name == "" || strings.ContainsAny(name, " \t.") {
+			continue
+		}
+		v.Add(name+"."+network.Domain, r.IP.IP)
+	}
+	return v
+}
+
+// Add records that name resolves to ip and ip to name.  The first name
+// added for an address is used for its PTR record.
+func (v *View) Add(name string, ip net.IP) {
+	name = Canonical(name)
+	for _, existing := range v.forward[name] {
+		if existing.Equal(ip) {
+			return
+		}
+	}
+	v.forward[name] = append(v.forward[name], ip)
+
+	ptr := export.ReverseName(ip)
+	if _, ok := v.reverse[ptr]; !ok {
+		v.reverse[ptr] = name
+	}
+}
+
+// Lookup returns the addresses of name, and whether the name exists at all
+func (v *View) Lookup(name string) ([]net.IP, bool) {
+	ips, ok := v.forward[Canonical(name)]
+	return ips, ok
+}
+
+// LookupPTR returns the name of a reverse lookup name, eg.
+// 10.0.0.10.in-addr.arpa.
+func (v *View) LookupPTR(name string) (string, bool) {
+	target, ok := v.reverse[Canonical(name)]
+	return target, ok
+}
+
+// Canonical returns name in lower case with a trailing dot
+func Canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
@@ -0,0 +1,141 @@
+package dns
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func testView() *View {
+	_, prov, _ := net.ParseCIDR("10.0.0.0/24")
+	_, prov6, _ := net.ParseCIDR("2001:db8::/64")
+	provNetwork := &types.Network{Name: "prov", Domain: "prov.example.com", Subnets: types.SubnetList{{Name: "prov", Cidr: prov}, {Name: "prov6", Cidr: prov6}}}
+
+	mac, _ := net.ParseMAC("00:01:02:03:04:05")
+	switchMAC, _ := net.ParseMAC("00:01:02:03:06:06")
+	nodes := []*types.InventoryNode{
+		{Hostname: "tsys-test-000", InventoryID: "test-000", Networks: map[string]*types.NICInstance{
+			"provisioning": {
+				Network:   *provNetwork,
+				Interface: types.NetworkInterface{NICs: []net.HardwareAddr{mac}},
+				Config:    types.NicConfig{IP: []string{"10.0.0.10/24", "2001:db8::10/64"}},
+			},
+		}},
+	}
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	reservation := func(ip string, mac net.HardwareAddr, host string, end *time.Time) *types.IPReservation {
+		addr, cidr, _ := net.ParseCIDR(ip)
+		cidr.IP = addr
+		return &types.IPReservation{IP: cidr, MAC: mac, HostInformation: host, End: end}
+	}
+
+	return NewView(&export.Snapshot{Nodes: nodes, Networks: []*types.Network{provNetwork}, Reservations: types.IPReservationList{
+		reservation("10.0.0.10/24", mac, "stale", nil),
+		reservation("10.0.0.15/24", mac, "", &expired),
+		reservation("10.0.0.20/24", switchMAC, "switch-01", nil),
+		reservation("10.0.0.30/24", nil, "gone", &expired),
+		reservation("10.1.0.10/24", nil, "elsewhere", nil),
+	}}, now)
+}
+
+func TestNewView(t *testing.T) {
+	v := testView()
+
+	ips, ok := v.Lookup("TSYS-test-000.prov.example.com")
+	if !ok || len(ips) != 2 || !ips[0].Equal(net.ParseIP("10.0.0.10")) || !ips[1].Equal(net.ParseIP("2001:db8::10")) {
+		t.Errorf("got unexpected addresses for node: %v", ips)
+	}
+
+	if ips, ok := v.Lookup("switch-01.prov.example.com."); !ok || len(ips) != 1 {
+		t.Errorf("reservation without a node not found: %v", ips)
+	}
+
+	for _, name := range []string{"gone.prov.example.com.", "elsewhere.prov.example.com.", "stale.prov.example.com."} {
+		if _, ok := v.Lookup(name); ok {
+			t.Errorf("unexpected name in view: %s", name)
+		}
+	}
+
+	if name, ok := v.LookupPTR("10.0.0.10.in-addr.arpa."); !ok || name != "tsys-test-000.prov.example.com." {
+		t.Errorf("got unexpected ptr: %s", name)
+	}
+
+	expected := []string{"0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "0.0.10.in-addr.arpa.", "prov.example.com."}
+	if len(v.Zones) != len(expected) {
+		t.Fatalf("got unexpected zones: %v", v.Zones)
+	}
+	for idx, zone := range expected {
+		if v.Zones[idx] != zone {
+			t.Errorf("got unexpected zone: expected %s, got %s", zone, v.Zones[idx])
+		}
+	}
+}
+
+func TestLoadView(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).Get("nodeconfig").Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Hostname": "tsys-test-000", "Networks": {"provisioning": {"Network": {"Name": "prov"}, "Interface": {"NICs": ["00:01:02:03:04:05"]}, "Config": {"IP": ["10.0.0.10/24"]}}}}]`)
+	gock.New(testBaseUrl.String()).Get("network").Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Domain": "prov.example.com", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24"}]}]`)
+	gock.New(testBaseUrl.String()).Get("ipam/ip").MatchParam("network", "prov").Reply(http.StatusOK).
+		BodyString(`[{"IP": "10.0.0.20/24", "MAC": "00:01:02:03:06:06", "HostInformation": "switch-01"}]`)
+
+	inv := client.NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")})
+	v, err := LoadView(inv)
+	if err != nil {
+		t.Fatalf("unable to load view: %v", err)
+	}
+
+	if ips, ok := v.Lookup("tsys-test-000.prov.example.com."); !ok || len(ips) != 1 {
+		t.Errorf("got unexpected addresses: %v", ips)
+	}
+	if _, ok := v.Lookup("switch-01.prov.example.com."); !ok || v.ReservationsErr != nil {
+		t.Errorf("reservation not loaded: %v", v.ReservationsErr)
+	}
+}
+
+func TestLoadViewWithoutReservations(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).Get("nodeconfig").Reply(http.StatusOK).
+		BodyString(`[{"InventoryID": "test-000", "Hostname": "tsys-test-000", "Networks": {"provisioning": {"Network": {"Name": "prov"}, "Interface": {"NICs": ["00:01:02:03:04:05"]}, "Config": {"IP": ["10.0.0.10/24"]}}}}]`)
+	gock.New(testBaseUrl.String()).Get("network").Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Domain": "prov.example.com", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/8"}]}]`)
+	gock.New(testBaseUrl.String()).Get("ipam/ip").MatchParam("network", "prov").Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+	gock.New(testBaseUrl.String()).Get("network/prov").Reply(http.StatusOK).
+		BodyString(`{"Name": "prov", "Domain": "prov.example.com", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/8"}]}`)
+	gock.New(testBaseUrl.String()).Get("ipam/ip").MatchParam("subnet", "10.0.0.0/8").Reply(http.StatusNotFound).
+		BodyString(`{"status": "Not Found", "error": "not found"}`)
+
+	inv := client.NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")})
+	v, err := LoadView(inv)
+	if err != nil {
+		t.Fatalf("unable to load view: %v", err)
+	}
+
+	if client.SkippedSubnets(v.ReservationsErr) == nil {
+		t.Errorf("expected the subnet to be reported as skipped, got %v", v.ReservationsErr)
+	}
+	if ips, ok := v.Lookup("tsys-test-000.prov.example.com."); !ok || len(ips) != 1 {
+		t.Errorf("node addresses not loaded: %v", ips)
+	}
+}
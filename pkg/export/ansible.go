@@ -0,0 +1,233 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// AnsibleOptions control the generated Ansible inventory
+type AnsibleOptions struct {
+	// Networks lists the networks ansible_host is chosen from in order of
+	// preference, as in SSHOptions
+	Networks []string
+
+	Filter *NodeFilter
+}
+
+// AnsibleGroup is a group in an Ansible dynamic inventory
+type AnsibleGroup struct {
+	Hosts    []string               `json:"hosts,omitempty"`
+	Children []string               `json:"children,omitempty"`
+	Vars     map[string]interface{} `json:"vars,omitempty"`
+}
+
+// AnsibleInventory is the output of an Ansible dynamic inventory script.  It
+// marshals to the format expected from --list.
+type AnsibleInventory struct {
+	Groups   map[string]*AnsibleGroup
+	HostVars map[string]map[string]interface{}
+}
+
+type ansibleMeta struct {
+	HostVars map[string]map[string]interface{} `json:"hostvars"`
+}
+
+// MarshalJSON returns the groups along with the host variables in _meta
+func (a *AnsibleInventory) MarshalJSON() ([]byte, error) {
+	doc := map[string]interface{}{"_meta": &ansibleMeta{HostVars: a.HostVars}}
+	for name, group := range a.Groups {
+		doc[name] = group
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON reads the output of MarshalJSON
+func (a *AnsibleInventory) UnmarshalJSON(data []byte) error {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	a.Groups = map[string]*AnsibleGroup{}
+	a.HostVars = map[string]map[string]interface{}{}
+	for name, raw := range doc {
+		if name == "_meta" {
+			meta := &ansibleMeta{}
+			if err := json.Unmarshal(raw, meta); err != nil {
+				return err
+			}
+			if meta.HostVars != nil {
+				a.HostVars = meta.HostVars
+			}
+			continue
+		}
+		group := &AnsibleGroup{}
+		if err := json.Unmarshal(raw, group); err != nil {
+			return fmt.Errorf("unable to unmarshal group %s: %v", name, err)
+		}
+		a.Groups[name] = group
+	}
+	return nil
+}
+
+// Host returns the variables of a host, as expected from --host.  Unknown
+// hosts have no variables.
+func (a *AnsibleInventory) Host(name string) map[string]interface{} {
+	if vars, ok := a.HostVars[name]; ok {
+		return vars
+	}
+	return map[string]interface{}{}
+}
+
+// BuildAnsibleInventory groups nodes by system, role, environment, location
+// and tag, in groups named eg. system_tsys, role_worker and tag_gpu.  Each
+// node's metadata becomes its host variables, along with inventory_
+// prefixed variables describing the node and its networks, and
+// ansible_host.  Every host is also listed in all, so hosts without any
+// group are still part of the inventory.
+func BuildAnsibleInventory(s *Snapshot, opts *AnsibleOptions) *AnsibleInventory {
+	byNode := map[string][]*Address{}
+	for _, a := range s.Addresses() {
+		byNode[a.Hostname] = append(byNode[a.Hostname], a)
+	}
+
+	inv := &AnsibleInventory{Groups: map[string]*AnsibleGroup{}, HostVars: map[string]map[string]interface{}{}}
+	addHost := func(group, host string) {
+		if group == "" {
+			return
+		}
+		g, ok := inv.Groups[group]
+		if !ok {
+			g = &AnsibleGroup{}
+			inv.Groups[group] = g
+		}
+		g.Hosts = append(g.Hosts, host)
+	}
+
+	ssh := &SSHOptions{Networks: opts.Networks}
+	hosts := []string{}
+	for _, node := range opts.Filter.Nodes(s) {
+		hosts = append(hosts, node.Hostname)
+		vars := map[string]interface{}{}
+		for k, v := range node.Metadata {
+			vars[k] = v
+		}
+
+		vars["inventory_id"] = node.InventoryID
+		vars["inventory_role"] = node.Role
+		vars["inventory_tags"] = append([]string{}, node.Tags...)
+		addHost(ansibleGroupName("role", node.Role), node.Hostname)
+		for _, tag := range node.Tags {
+			addHost(ansibleGroupName("tag", tag), node.Hostname)
+		}
+
+		if node.System != nil {
+			vars["inventory_system"] = node.System.Name
+			addHost(ansibleGroupName("system", node.System.Name), node.Hostname)
+		}
+		if env := environmentName(node); env != "" {
+			vars["inventory_environment"] = env
+			addHost(ansibleGroupName("environment", env), node.Hostname)
+		}
+		if node.LocationString != "" {
+			vars["inventory_location"] = node.LocationString
+		}
+		if l := node.Location; l != nil {
+			vars["inventory_rack"] = l.Rack
+			addHost(ansibleGroupName("location", l.Building, l.Room, l.Rack), node.Hostname)
+		}
+
+		networks := map[string]interface{}{}
+		for _, a := range byNode[node.Hostname] {
+			network, ok := networks[a.Network.Name].(map[string]interface{})
+			if !ok {
+				macs := []string{}
+				for _, mac := range a.MACs {
+					macs = append(macs, mac.String())
+				}
+				network = map[string]interface{}{"interface": a.Interface, "macs": macs, "ipv4": []string{}, "ipv6": []string{}}
+				networks[a.Network.Name] = network
+			}
+			family := "ipv6"
+			if a.IsV4() {
+				family = "ipv4"
+			}
+			network[family] = append(network[family].([]string), a.IP.String())
+		}
+		vars["inventory_networks"] = networks
+
+		if target := ssh.sshAddress(byNode[node.Hostname]); target != nil {
+			vars["ansible_host"] = target.IP.String()
+		}
+		inv.HostVars[node.Hostname] = vars
+	}
+
+	children := []string{}
+	for name, group := range inv.Groups {
+		sort.Strings(group.Hosts)
+		children = append(children, name)
+	}
+	sort.Strings(children)
+	sort.Strings(hosts)
+	inv.Groups["all"] = &AnsibleGroup{Hosts: hosts, Children: children}
+	return inv
+}
+
+// GenerateAnsible returns the Ansible inventory as JSON
+func GenerateAnsible(s *Snapshot, opts *AnsibleOptions) ([]byte, error) {
+	data, err := json.MarshalIndent(BuildAnsibleInventory(s, opts), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal ansible inventory: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ansibleGroupName joins the non empty parts with a prefix, replacing any
+// character that isn't valid in a group name.  It returns an empty string
+// if every part is empty.
+func ansibleGroupName(prefix string, parts ...string) string {
+	name := prefix
+	empty := true
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		empty = false
+		name += "_" + part
+	}
+	if empty {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// environmentName returns the name the node's environment has in its
+// system, or an empty string if it can't be found
+func environmentName(node *types.InventoryNode) string {
+	if node.System == nil || node.Environment == nil {
+		return ""
+	}
+	names := make([]string, 0, len(node.System.Environments))
+	for name := range node.System.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env := node.System.Environments[name]
+		if env == node.Environment || reflect.DeepEqual(env, node.Environment) {
+			return name
+		}
+	}
+	return ""
+}
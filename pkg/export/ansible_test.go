@@ -0,0 +1,104 @@
+package export
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func testAnsibleSnapshot() *Snapshot {
+	s := testSnapshot()
+	prod := &types.Environment{IPXEUrl: "http://boot/prod"}
+	system := &types.System{Name: "tsys", Environments: map[string]*types.Environment{"prod": prod, "test": {IPXEUrl: "http://boot/test"}}}
+	for _, node := range s.Nodes {
+		node.System = system
+		node.Environment = prod
+	}
+	s.Nodes[0].Location = &types.ChassisLocation{Building: "bldg1", Room: "101", Rack: "a-1", BottomU: 10}
+	s.Nodes[0].LocationString = "a-1-10"
+	s.Nodes[0].Metadata = map[string]interface{}{"gpus": 4.0, "inventory_id": "overridden"}
+	return s
+}
+
+func TestBuildAnsibleInventory(t *testing.T) {
+	inv := BuildAnsibleInventory(testAnsibleSnapshot(), &AnsibleOptions{Networks: []string{"prov"}})
+
+	expected := map[string][]string{
+		"environment_prod":       {"node-000", "node-001"},
+		"location_bldg1_101_a_1": {"node-000"},
+		"role_login":             {"node-001"},
+		"role_worker":            {"node-000"},
+		"system_tsys":            {"node-000", "node-001"},
+		"tag_gpu":                {"node-000"},
+	}
+	for name, hosts := range expected {
+		group, ok := inv.Groups[name]
+		if !ok {
+			t.Errorf("missing group %s", name)
+			continue
+		}
+		if !reflect.DeepEqual(group.Hosts, hosts) {
+			t.Errorf("got unexpected hosts in %s: %v", name, group.Hosts)
+		}
+	}
+	if len(inv.Groups) != len(expected)+1 || len(inv.Groups["all"].Children) != len(expected) {
+		t.Errorf("got unexpected groups: %v", inv.Groups["all"])
+	}
+	if !reflect.DeepEqual(inv.Groups["all"].Hosts, []string{"node-000", "node-001"}) {
+		t.Errorf("got unexpected hosts in all: %v", inv.Groups["all"].Hosts)
+	}
+
+	vars := inv.Host("node-000")
+	if vars["ansible_host"] != "10.0.0.10" || vars["gpus"] != 4.0 || vars["inventory_id"] != "test-000" || vars["inventory_environment"] != "prod" {
+		t.Errorf("got unexpected host vars: %v", vars)
+	}
+
+	networks := vars["inventory_networks"].(map[string]interface{})
+	prov := networks["prov"].(map[string]interface{})
+	if !reflect.DeepEqual(prov["ipv4"], []string{"10.0.0.10"}) || !reflect.DeepEqual(prov["ipv6"], []string{"2001:db8::10"}) || prov["interface"] != "provisioning" {
+		t.Errorf("got unexpected network vars: %v", prov)
+	}
+
+	if len(inv.Host("missing")) != 0 {
+		t.Errorf("expected no vars for an unknown host")
+	}
+}
+
+func TestAnsibleInventoryJSON(t *testing.T) {
+	data, err := GenerateAnsible(testAnsibleSnapshot(), &AnsibleOptions{Filter: &NodeFilter{Roles: []string{"login"}}})
+	if err != nil {
+		t.Fatalf("unable to generate ansible inventory: %v", err)
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unable to unmarshal inventory: %v", err)
+	}
+	if _, ok := doc["_meta"].(map[string]interface{})["hostvars"].(map[string]interface{})["node-001"]; !ok {
+		t.Errorf("missing host vars in _meta: %s", data)
+	}
+	if _, ok := doc["role_worker"]; ok {
+		t.Errorf("filtered node included: %s", data)
+	}
+
+	inv := &AnsibleInventory{}
+	if err := json.Unmarshal(data, inv); err != nil {
+		t.Fatalf("unable to read inventory back: %v", err)
+	}
+	if !reflect.DeepEqual(inv.Groups["role_login"].Hosts, []string{"node-001"}) || inv.Host("node-001")["ansible_host"] != "10.0.0.11" {
+		t.Errorf("inventory didn't round trip: %s", data)
+	}
+}
+
+func TestBuildAnsibleInventoryUngrouped(t *testing.T) {
+	s := testSnapshot()
+	s.Nodes[1].Role = ""
+	s.Nodes[1].Tags = nil
+
+	inv := BuildAnsibleInventory(s, &AnsibleOptions{})
+	if !reflect.DeepEqual(inv.Groups["all"].Hosts, []string{"node-000", "node-001"}) {
+		t.Errorf("node without groups missing from all: %v", inv.Groups["all"].Hosts)
+	}
+}
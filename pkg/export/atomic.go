@@ -0,0 +1,48 @@
+package export
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic replaces the contents of filename with data by writing a
+// temporary file in the same directory and renaming it into place.  The file
+// is left untouched if its contents already match.  It returns true if the
+// file was changed.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) (bool, error) {
+	existing, err := ioutil.ReadFile(filename)
+	if err == nil && bytes.Equal(existing, data) {
+		return false, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	if err != nil {
+		return false, err
+	}
+	tmpName := f.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return false, err
+	}
+	if err := f.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(tmpName, filename); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "hosts")
+
+	for _, test := range []struct {
+		Data    string
+		Changed bool
+	}{
+		{"a\n", true},
+		{"a\n", false},
+		{"b\n", true},
+	} {
+		changed, err := WriteFileAtomic(filename, []byte(test.Data), 0644)
+		if err != nil {
+			t.Fatalf("unable to write file: %v", err)
+		}
+		if changed != test.Changed {
+			t.Errorf("got wrong changed value writing %q: %v", test.Data, changed)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(filename)
+	if string(data) != "b\n" {
+		t.Errorf("got unexpected contents: %q", data)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("temporary files left behind: %v", files)
+	}
+}
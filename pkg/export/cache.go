@@ -0,0 +1,60 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CachedFile returns the contents of filename if it was written within ttl,
+// otherwise it calls load and caches the result.  The cache may include
+// sensitive metadata, so it's only readable by its owner.  An empty filename
+// disables the cache, and failing to write it isn't fatal.
+func CachedFile(filename string, ttl time.Duration, load func() ([]byte, error)) ([]byte, error) {
+	if filename != "" && ttl > 0 {
+		if info, err := os.Stat(filename); err == nil && time.Since(info.ModTime()) < ttl {
+			if data, err := ioutil.ReadFile(filename); err == nil {
+				return data, nil
+			}
+		}
+	}
+
+	data, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if filename != "" {
+		if err := os.MkdirAll(filepath.Dir(filename), 0700); err == nil {
+			WriteFileAtomic(filename, data, 0600)
+			os.Chtimes(filename, time.Now(), time.Now())
+		}
+	}
+	return data, nil
+}
+
+// CachePath adds a hash of the settings the cached data depends on to
+// filename, so data loaded with different settings isn't shared
+func CachePath(filename string, settings ...string) string {
+	if filename == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(settings, "\x00")))
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(filename, ext), hex.EncodeToString(sum[:])[:12], ext)
+}
+
+// DefaultCacheFile returns name in the user's cache directory, or an empty
+// string if there isn't one
+func DefaultCacheFile(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "inventory", name)
+}
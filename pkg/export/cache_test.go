@@ -0,0 +1,69 @@
+package export
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cache", "ansible.json")
+
+	loads := 0
+	load := func() ([]byte, error) {
+		loads++
+		return []byte(fmt.Sprintf(`{"load": %d}`, loads)), nil
+	}
+
+	for _, expected := range []string{`{"load": 1}`, `{"load": 1}`} {
+		data, err := CachedFile(filename, time.Minute, load)
+		if err != nil || string(data) != expected {
+			t.Errorf("got unexpected data: %s %v", data, err)
+		}
+	}
+
+	// an expired cache is reloaded
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filename, old, old)
+	if data, _ := CachedFile(filename, time.Minute, load); string(data) != `{"load": 2}` {
+		t.Errorf("expired cache wasn't reloaded: %s", data)
+	}
+
+	// a zero ttl always reloads, but still updates the cache
+	if data, _ := CachedFile(filename, 0, load); string(data) != `{"load": 3}` {
+		t.Errorf("cache wasn't bypassed: %s", data)
+	}
+	if data, _ := ioutil.ReadFile(filename); string(data) != `{"load": 3}` {
+		t.Errorf("cache wasn't updated: %s", data)
+	}
+
+	if _, err := CachedFile(filename, 0, func() ([]byte, error) { return nil, fmt.Errorf("oops") }); err == nil {
+		t.Errorf("expected load error to be returned")
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	a := CachePath("/tmp/ansible.json", "prod", "role=worker", "prov")
+	if filepath.Dir(a) != "/tmp" || filepath.Ext(a) != ".json" {
+		t.Errorf("got unexpected cache path: %s", a)
+	}
+	if a != CachePath("/tmp/ansible.json", "prod", "role=worker", "prov") {
+		t.Errorf("cache path isn't stable")
+	}
+	for _, settings := range [][]string{{"test", "role=worker", "prov"}, {"prod", "", "prov"}, {"prod", "role=worker", ""}} {
+		if b := CachePath("/tmp/ansible.json", settings...); b == a {
+			t.Errorf("settings %v share the cache with %s", settings, a)
+		}
+	}
+	if CachePath("", "prod") != "" {
+		t.Errorf("expected the cache to stay disabled")
+	}
+}
@@ -0,0 +1,245 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// DHCPOptions control the generated DHCP configuration.  Only IPv4 subnets
+// and addresses are included, along with any static reservations loaded into
+// the snapshot that aren't assigned to a node.
+type DHCPOptions struct {
+	// Networks limits the output to the named networks, or all networks if empty
+	Networks []string
+
+	// NextServer and Filename are set on every host for network booting
+	NextServer string
+	Filename   string
+}
+
+// dhcpHost is a single MAC to address mapping
+type dhcpHost struct {
+	Name     string
+	Hostname string
+	MAC      net.HardwareAddr
+	IP       net.IP
+}
+
+// dhcpSubnet is an IPv4 subnet along with its hosts
+type dhcpSubnet struct {
+	Network *types.Network
+	Subnet  *types.Subnet
+	Hosts   []*dhcpHost
+}
+
+// dhcpSubnets groups the IPv4 addresses of every node, and the unassigned
+// reservations with a MAC address, by subnet, in network and subnet order
+func dhcpSubnets(s *Snapshot, opts *DHCPOptions) []*dhcpSubnet {
+	subnets := []*dhcpSubnet{}
+	bySubnet := map[*types.Subnet]*dhcpSubnet{}
+	for _, network := range s.Networks {
+		if !contains(opts.Networks, network.Name) {
+			continue
+		}
+		for _, subnet := range network.Subnets {
+			if subnet.Cidr == nil || subnet.Cidr.IP.To4() == nil {
+				continue
+			}
+			ds := &dhcpSubnet{Network: network, Subnet: subnet, Hosts: []*dhcpHost{}}
+			subnets = append(subnets, ds)
+			bySubnet[subnet] = ds
+		}
+	}
+
+	names := map[string]int{}
+	unique := func(name string) string {
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, names[name]-1)
+		}
+		return name
+	}
+
+	for _, a := range s.Addresses() {
+		ds, ok := bySubnet[a.Subnet]
+		if !ok || !a.IsV4() {
+			continue
+		}
+		for idx, mac := range a.MACs {
+			name := fmt.Sprintf("%s-%s", a.Hostname, a.Interface)
+			if len(a.MACs) > 1 {
+				name = fmt.Sprintf("%s-%d", name, idx)
+			}
+			ds.Hosts = append(ds.Hosts, &dhcpHost{Name: unique(name), Hostname: a.Hostname, MAC: mac, IP: a.IP})
+		}
+	}
+
+	for _, r := range s.UnassignedReservations() {
+		if r.IP.IP.To4() == nil || len(r.MAC) == 0 {
+			continue
+		}
+		_, subnet := s.NetworkContaining(r.IP.IP)
+		ds, ok := bySubnet[subnet]
+		if !ok {
+			continue
+		}
+
+		h := &dhcpHost{MAC: r.MAC, IP: r.IP.IP}
+		if validHostname(r.HostInformation) {
+			h.Hostname = r.HostInformation
+			h.Name = unique(r.HostInformation)
+		} else {
+			h.Name = unique("reservation-" + strings.Replace(r.IP.IP.String(), ".", "-", -1))
+		}
+		ds.Hosts = append(ds.Hosts, h)
+	}
+	return subnets
+}
+
+// validHostname returns true if name is a valid, possibly qualified, host name
+func validHostname(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func ipList(ips []net.IP, v4 bool) []string {
+	result := []string{}
+	for _, ip := range ips {
+		if (ip.To4() != nil) == v4 {
+			result = append(result, ip.String())
+		}
+	}
+	return result
+}
+
+// GenerateDhcpd returns ISC dhcpd subnet declarations and host stanzas.
+// Reservations are named by their host information if it is a valid host
+// name, or by their address otherwise.
+func GenerateDhcpd(s *Snapshot, opts *DHCPOptions) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+
+	for _, ds := range dhcpSubnets(s, opts) {
+		fmt.Fprintf(buf, "\n# %s %s\n", ds.Network.Name, ds.Subnet.Name)
+		fmt.Fprintf(buf, "subnet %s netmask %s {\n", ds.Subnet.Cidr.IP, net.IP(ds.Subnet.Cidr.Mask))
+		if ds.Subnet.Gateway != nil {
+			fmt.Fprintf(buf, "  option routers %s;\n", ds.Subnet.Gateway)
+		}
+		if dns := ipList(ds.Subnet.DNS, true); len(dns) > 0 {
+			fmt.Fprintf(buf, "  option domain-name-servers %s;\n", strings.Join(dns, ", "))
+		}
+		if ds.Network.Domain != "" {
+			fmt.Fprintf(buf, "  option domain-name %q;\n", ds.Network.Domain)
+		}
+		if ds.Network.MTU > 0 {
+			fmt.Fprintf(buf, "  option interface-mtu %d;\n", ds.Network.MTU)
+		}
+		fmt.Fprintln(buf, "}")
+
+		for _, h := range ds.Hosts {
+			fmt.Fprintf(buf, "\nhost %s {\n", h.Name)
+			fmt.Fprintf(buf, "  hardware ethernet %s;\n", h.MAC)
+			fmt.Fprintf(buf, "  fixed-address %s;\n", h.IP)
+			if h.Hostname != "" {
+				fmt.Fprintf(buf, "  option host-name %q;\n", h.Hostname)
+			}
+			if opts.NextServer != "" {
+				fmt.Fprintf(buf, "  next-server %s;\n", opts.NextServer)
+			}
+			if opts.Filename != "" {
+				fmt.Fprintf(buf, "  filename %q;\n", opts.Filename)
+			}
+			fmt.Fprintln(buf, "}")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+type keaConfig struct {
+	Dhcp4 *keaDhcp4 `json:"Dhcp4"`
+}
+
+type keaDhcp4 struct {
+	Subnet4 []*keaSubnet `json:"subnet4"`
+}
+
+type keaSubnet struct {
+	ID           uint32            `json:"id"`
+	Subnet       string            `json:"subnet"`
+	OptionData   []*keaOption      `json:"option-data,omitempty"`
+	Reservations []*keaReservation `json:"reservations"`
+}
+
+type keaOption struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+type keaReservation struct {
+	HWAddress    string `json:"hw-address"`
+	IPAddress    string `json:"ip-address"`
+	Hostname     string `json:"hostname,omitempty"`
+	NextServer   string `json:"next-server,omitempty"`
+	BootFileName string `json:"boot-file-name,omitempty"`
+}
+
+// GenerateKea returns a Kea DHCPv4 configuration containing a subnet, with
+// reservations, for each inventory subnet.  Subnet IDs are the network
+// address as an integer, so they remain stable as subnets are added.
+func GenerateKea(s *Snapshot, opts *DHCPOptions) ([]byte, error) {
+	config := &keaConfig{Dhcp4: &keaDhcp4{Subnet4: []*keaSubnet{}}}
+	for _, ds := range dhcpSubnets(s, opts) {
+		ks := &keaSubnet{
+			ID:           binary.BigEndian.Uint32(ds.Subnet.Cidr.IP.To4()),
+			Subnet:       ds.Subnet.Cidr.String(),
+			Reservations: []*keaReservation{},
+		}
+		if ds.Subnet.Gateway != nil {
+			ks.OptionData = append(ks.OptionData, &keaOption{Name: "routers", Data: ds.Subnet.Gateway.String()})
+		}
+		if dns := ipList(ds.Subnet.DNS, true); len(dns) > 0 {
+			ks.OptionData = append(ks.OptionData, &keaOption{Name: "domain-name-servers", Data: strings.Join(dns, ", ")})
+		}
+		if ds.Network.Domain != "" {
+			ks.OptionData = append(ks.OptionData, &keaOption{Name: "domain-name", Data: ds.Network.Domain})
+		}
+		if ds.Network.MTU > 0 {
+			ks.OptionData = append(ks.OptionData, &keaOption{Name: "interface-mtu", Data: fmt.Sprintf("%d", ds.Network.MTU)})
+		}
+
+		for _, h := range ds.Hosts {
+			ks.Reservations = append(ks.Reservations, &keaReservation{
+				HWAddress:    h.MAC.String(),
+				IPAddress:    h.IP.String(),
+				Hostname:     h.Hostname,
+				NextServer:   opts.NextServer,
+				BootFileName: opts.Filename,
+			})
+		}
+		config.Dhcp4.Subnet4 = append(config.Dhcp4.Subnet4, ks)
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal kea config: %v", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// testDHCPSnapshot adds an unassigned reservation whose host information
+// isn't a valid host name
+func testDHCPSnapshot() *Snapshot {
+	s := testSnapshotWithReservations()
+	mac, _ := net.ParseMAC("00:01:02:03:06:07")
+	ip, cidr, _ := net.ParseCIDR("10.0.0.21/24")
+	cidr.IP = ip
+	s.Reservations = append(s.Reservations, &types.IPReservation{IP: cidr, MAC: mac, HostInformation: "rack 4 pdu"})
+	return s
+}
+
+func TestGenerateDhcpd(t *testing.T) {
+	data, err := GenerateDhcpd(testDHCPSnapshot(), &DHCPOptions{Networks: []string{"prov"}, NextServer: "10.0.0.2", Filename: "pxelinux.0"})
+	if err != nil {
+		t.Fatalf("unable to generate dhcpd config: %v", err)
+	}
+	config := string(data)
+
+	for _, expected := range []string{
+		"subnet 10.0.0.0 netmask 255.255.255.0 {\n  option routers 10.0.0.1;\n  option domain-name-servers 10.0.0.53;\n  option domain-name \"prov.example.com\";\n  option interface-mtu 9000;\n}",
+		"host node-000-provisioning {\n  hardware ethernet 00:01:02:03:04:05;\n  fixed-address 10.0.0.10;\n  option host-name \"node-000\";\n  next-server 10.0.0.2;\n  filename \"pxelinux.0\";\n}",
+		"host node-001-provisioning {",
+		"host switch-01 {\n  hardware ethernet 00:01:02:03:06:06;\n  fixed-address 10.0.0.20;\n  option host-name \"switch-01\";\n",
+		"host reservation-10-0-0-21 {\n  hardware ethernet 00:01:02:03:06:07;\n  fixed-address 10.0.0.21;\n  next-server",
+	} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("expected config to contain:\n%s\ngot:\n%s", expected, config)
+		}
+	}
+
+	if strings.Contains(config, "10.1.0") || strings.Contains(config, "2001:db8") {
+		t.Errorf("unexpected network or ipv6 address in config:\n%s", config)
+	}
+}
+
+func TestGenerateKea(t *testing.T) {
+	data, err := GenerateKea(testDHCPSnapshot(), &DHCPOptions{Filename: "ipxe.efi"})
+	if err != nil {
+		t.Fatalf("unable to generate kea config: %v", err)
+	}
+
+	config := &keaConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		t.Fatalf("unable to parse generated config: %v", err)
+	}
+
+	subnets := config.Dhcp4.Subnet4
+	if len(subnets) != 2 || subnets[0].Subnet != "10.1.0.0/24" || subnets[1].Subnet != "10.0.0.0/24" {
+		t.Fatalf("got unexpected subnets: %s", data)
+	}
+
+	if subnets[1].ID != 0x0a000000 || len(subnets[1].OptionData) != 4 {
+		t.Errorf("subnet not generated correctly: %+v", subnets[1])
+	}
+
+	reservations := subnets[1].Reservations
+	if len(reservations) != 4 || reservations[0].HWAddress != "00:01:02:03:04:05" || reservations[0].IPAddress != "10.0.0.10" || reservations[0].BootFileName != "ipxe.efi" {
+		t.Fatalf("got unexpected reservations: %s", data)
+	}
+
+	if reservations[2].IPAddress != "10.0.0.20" || reservations[2].Hostname != "switch-01" || reservations[3].IPAddress != "10.0.0.21" || reservations[3].Hostname != "" {
+		t.Errorf("unassigned reservations not included correctly: %s", data)
+	}
+}
+
+func TestValidHostname(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"switch-01":             true,
+		"node-000.example.com.": true,
+		"":                      false,
+		"rack 4":                false,
+		"-node":                 false,
+		"node..example":         false,
+		"node,tag":              false,
+	} {
+		if validHostname(name) != valid {
+			t.Errorf("expected validHostname(%q) to be %v", name, valid)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DnsmasqOptions control the generated dnsmasq configuration
+type DnsmasqOptions struct {
+	// Networks limits the output to the named networks, or all networks if empty
+	Networks []string
+
+	// HostsFile omits the "dhcp-host=" prefix and per network settings so the
+	// output can be used with dhcp-hostsfile
+	HostsFile bool
+}
+
+// dnsmasqTag returns the tag used for a network, which may only contain
+// characters valid in a dnsmasq tag name
+func dnsmasqTag(network string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, network)
+}
+
+// GenerateDnsmasqDHCP returns dhcp-host lines for every IPv4 node address
+// and static reservation, grouped by network.  Each address has a single line
+// listing all of its MAC addresses, since dnsmasq only hands an address to
+// one of several lines that share it.  Hosts are tagged with their network
+// name, and unless HostsFile is set each network's IPv4 subnets are declared
+// as static dhcp-ranges with their gateway, DNS and domain options.
+// Reservations are only named if their host information is a valid host name.
+func GenerateDnsmasqDHCP(s *Snapshot, opts *DnsmasqOptions) ([]byte, error) {
+	type host struct {
+		macs []string
+		ip   net.IP
+		name string
+	}
+	hosts := map[string][]*host{}
+	for _, a := range s.Addresses() {
+		if !a.IsV4() || a.Subnet == nil || len(a.MACs) == 0 {
+			continue
+		}
+		h := &host{ip: a.IP, name: a.Hostname}
+		for _, mac := range a.MACs {
+			h.macs = append(h.macs, mac.String())
+		}
+		hosts[a.Network.Name] = append(hosts[a.Network.Name], h)
+	}
+	for _, r := range s.UnassignedReservations() {
+		if r.IP.IP.To4() == nil || len(r.MAC) == 0 {
+			continue
+		}
+		if network, _ := s.NetworkContaining(r.IP.IP); network != nil {
+			h := &host{macs: []string{r.MAC.String()}, ip: r.IP.IP}
+			if validHostname(r.HostInformation) {
+				h.name = r.HostInformation
+			}
+			hosts[network.Name] = append(hosts[network.Name], h)
+		}
+	}
+
+	prefix := "dhcp-host="
+	if opts.HostsFile {
+		prefix = ""
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+	for _, network := range s.Networks {
+		if !contains(opts.Networks, network.Name) {
+			continue
+		}
+		tag := dnsmasqTag(network.Name)
+		fmt.Fprintf(buf, "\n# network %s\n", network.Name)
+
+		if !opts.HostsFile {
+			for _, subnet := range network.Subnets {
+				if subnet.Cidr == nil || subnet.Cidr.IP.To4() == nil {
+					continue
+				}
+				fmt.Fprintf(buf, "dhcp-range=set:%s,%s,static,%s\n", tag, subnet.Cidr.IP, net.IP(subnet.Cidr.Mask))
+				if subnet.Gateway != nil {
+					fmt.Fprintf(buf, "dhcp-option=tag:%s,option:router,%s\n", tag, subnet.Gateway)
+				}
+				if dns := ipList(subnet.DNS, true); len(dns) > 0 {
+					fmt.Fprintf(buf, "dhcp-option=tag:%s,option:dns-server,%s\n", tag, strings.Join(dns, ","))
+				}
+			}
+			if network.Domain != "" {
+				fmt.Fprintf(buf, "dhcp-option=tag:%s,option:domain-name,%s\n", tag, network.Domain)
+			}
+		}
+
+		for _, h := range hosts[network.Name] {
+			line := fmt.Sprintf("%s%s,set:%s,%s", prefix, strings.Join(h.macs, ","), tag, h.ip)
+			if h.name != "" {
+				line = fmt.Sprintf("%s,%s", line, h.name)
+			}
+			fmt.Fprintln(buf, line)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateDnsmasqHosts returns an addn-hosts file listing every node address
+// and named static reservation with its fully qualified and short names
+func GenerateDnsmasqHosts(s *Snapshot, opts *DnsmasqOptions) ([]byte, error) {
+	addresses := s.Addresses()
+	unassigned := s.UnassignedReservations()
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+	for _, network := range s.Networks {
+		if !contains(opts.Networks, network.Name) {
+			continue
+		}
+		fmt.Fprintf(buf, "\n# network %s\n", network.Name)
+		for _, a := range addresses {
+			if a.Network.Name != network.Name {
+				continue
+			}
+			writeHostsLine(buf, a.IP, a.FQDN(), a.Hostname)
+		}
+		for _, r := range unassigned {
+			if !validHostname(r.HostInformation) {
+				continue
+			}
+			if n, _ := s.NetworkContaining(r.IP.IP); n != nil && n.Name == network.Name {
+				writeHostsLine(buf, r.IP.IP, qualify(r.HostInformation, network.Domain), r.HostInformation)
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// qualify appends domain to name unless name is empty or already qualified
+func qualify(name, domain string) string {
+	if domain == "" || strings.Contains(name, ".") {
+		return name
+	}
+	return fmt.Sprintf("%s.%s", name, domain)
+}
+
+func writeHostsLine(buf *bytes.Buffer, ip net.IP, names ...string) {
+	fmt.Fprintf(buf, "%s\t%s\n", ip, strings.Join(uniqueNames(names...), " "))
+}
+
+// uniqueNames returns the unique, non empty names in their original order
+func uniqueNames(names ...string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func testSnapshotWithReservations() *Snapshot {
+	s := testSnapshot()
+	mac, _ := net.ParseMAC("00:01:02:03:06:06")
+	ip, cidr, _ := net.ParseCIDR("10.0.0.20/24")
+	cidr.IP = ip
+	assigned, assignedCidr, _ := net.ParseCIDR("10.0.0.10/24")
+	assignedCidr.IP = assigned
+	s.Reservations = types.IPReservationList{
+		{IP: cidr, MAC: mac, HostInformation: "switch-01"},
+		{IP: assignedCidr, MAC: mac},
+	}
+	return s
+}
+
+func TestGenerateDnsmasqDHCP(t *testing.T) {
+	data, err := GenerateDnsmasqDHCP(testSnapshotWithReservations(), &DnsmasqOptions{Networks: []string{"prov"}})
+	if err != nil {
+		t.Fatalf("unable to generate dnsmasq config: %v", err)
+	}
+	config := string(data)
+
+	expected := `# network prov
+dhcp-range=set:prov,10.0.0.0,static,255.255.255.0
+dhcp-option=tag:prov,option:router,10.0.0.1
+dhcp-option=tag:prov,option:dns-server,10.0.0.53
+dhcp-option=tag:prov,option:domain-name,prov.example.com
+dhcp-host=00:01:02:03:04:05,set:prov,10.0.0.10,node-000
+dhcp-host=00:01:02:03:04:06,set:prov,10.0.0.11,node-001
+dhcp-host=00:01:02:03:06:06,set:prov,10.0.0.20,switch-01
+`
+	if !strings.HasSuffix(config, expected) {
+		t.Errorf("got unexpected config:\n%s", config)
+	}
+
+	if strings.Contains(config, "mgmt") {
+		t.Errorf("unexpected network in config:\n%s", config)
+	}
+}
+
+func TestGenerateDnsmasqDHCPMultipleMACs(t *testing.T) {
+	s := testSnapshotWithReservations()
+	bond, _ := net.ParseMAC("00:01:02:03:04:15")
+	nic := s.Nodes[0].Networks["provisioning"]
+	nic.Interface.NICs = append(nic.Interface.NICs, bond)
+
+	mac, _ := net.ParseMAC("00:01:02:03:06:07")
+	ip, cidr, _ := net.ParseCIDR("10.0.0.21/24")
+	cidr.IP = ip
+	s.Reservations = append(s.Reservations, &types.IPReservation{IP: cidr, MAC: mac, HostInformation: "pdu,set:evil"})
+
+	data, err := GenerateDnsmasqDHCP(s, &DnsmasqOptions{Networks: []string{"prov"}})
+	if err != nil {
+		t.Fatalf("unable to generate dnsmasq config: %v", err)
+	}
+	config := string(data)
+
+	for _, expected := range []string{
+		"\ndhcp-host=00:01:02:03:04:05,00:01:02:03:04:15,set:prov,10.0.0.10,node-000\n",
+		"\ndhcp-host=00:01:02:03:06:07,set:prov,10.0.0.21\n",
+	} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("expected %q in config:\n%s", expected, config)
+		}
+	}
+
+	if strings.Count(config, "10.0.0.10") != 1 || strings.Contains(config, "evil") {
+		t.Errorf("got unexpected config:\n%s", config)
+	}
+}
+
+func TestGenerateDnsmasqHostsFile(t *testing.T) {
+	data, err := GenerateDnsmasqDHCP(testSnapshot(), &DnsmasqOptions{HostsFile: true})
+	if err != nil {
+		t.Fatalf("unable to generate dhcp hosts file: %v", err)
+	}
+	config := string(data)
+
+	if !strings.Contains(config, "\n00:01:02:03:05:05,set:mgmt,10.1.0.10,node-000\n") || strings.Contains(config, "dhcp-") {
+		t.Errorf("got unexpected hosts file:\n%s", config)
+	}
+}
+
+func TestGenerateDnsmasqHosts(t *testing.T) {
+	data, err := GenerateDnsmasqHosts(testSnapshotWithReservations(), &DnsmasqOptions{})
+	if err != nil {
+		t.Fatalf("unable to generate hosts: %v", err)
+	}
+	hosts := string(data)
+
+	for _, expected := range []string{
+		"10.1.0.10\tnode-000\n",
+		"10.0.0.10\tnode-000.prov.example.com node-000\n",
+		"2001:db8::10\tnode-000.prov.example.com node-000\n",
+		"10.0.0.20\tswitch-01.prov.example.com switch-01\n",
+	} {
+		if !strings.Contains(hosts, expected) {
+			t.Errorf("expected %q in hosts:\n%s", expected, hosts)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/printer"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/spf13/viper"
+)
+
+// DefaultRoleClass is the class format used for roles without a mapping
+const DefaultRoleClass = "role::%s"
+
+// ClassifierConfig maps inventory nodes to configuration management classes
+type ClassifierConfig struct {
+	// Classes are applied to every node
+	Classes []string
+
+	// Roles, Systems and Tags map names to the classes applied to matching
+	// nodes.  Roles without a mapping get a single class from RoleClass.
+	Roles   map[string][]string
+	Systems map[string][]string
+	Tags    map[string][]string
+
+	// RoleClass is a format string with a single %s for the role name, or
+	// empty to use DefaultRoleClass
+	RoleClass string `mapstructure:"role_class"`
+
+	// Environments maps inventory environment names to configuration
+	// management environments.  Unmapped names are used as is, and nodes
+	// without an environment get DefaultEnvironment.
+	Environments       map[string]string
+	DefaultEnvironment string `mapstructure:"default_environment"`
+
+	// Parameters are set for every node, and are overridden by node metadata
+	Parameters map[string]interface{}
+}
+
+// LoadClassifierConfig reads a classifier mapping file
+func LoadClassifierConfig(path string) (*ClassifierConfig, error) {
+	cfg := viper.New()
+	cfg.SetConfigFile(path)
+	err := cfg.ReadInConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error reading classifier mapping file: %v", err)
+	}
+
+	c := &ClassifierConfig{}
+	err = cfg.Unmarshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling classifier mapping: %v", err)
+	}
+	if c.RoleClass != "" && !validRoleClass(c.RoleClass) {
+		return nil, fmt.Errorf("invalid role_class %q: expected a single %%s for the role name", c.RoleClass)
+	}
+	return c, nil
+}
+
+// validRoleClass reports whether format has exactly one %s and no other
+// verbs, other than %%
+func validRoleClass(format string) bool {
+	rest := strings.Replace(format, "%%", "", -1)
+	return strings.Count(rest, "%s") == 1 && strings.Count(rest, "%") == 1
+}
+
+// Classification is the output of an external node classifier
+type Classification struct {
+	Classes     []string
+	Environment string
+	Parameters  map[string]interface{}
+}
+
+// Classify returns the classes, environment and parameters of a node.
+// Parameters include the node's metadata and inventory_ prefixed values
+// describing the node.
+func (c *ClassifierConfig) Classify(node *types.InventoryNode) *Classification {
+	classes := append([]string{}, c.Classes...)
+	if node.Role != "" {
+		if roleClasses, ok := lookupClasses(c.Roles, node.Role); ok {
+			classes = append(classes, roleClasses...)
+		} else {
+			format := c.RoleClass
+			if format == "" {
+				format = DefaultRoleClass
+			}
+			classes = append(classes, fmt.Sprintf(format, node.Role))
+		}
+	}
+	if node.System != nil {
+		systemClasses, _ := lookupClasses(c.Systems, node.System.Name)
+		classes = append(classes, systemClasses...)
+	}
+	for _, tag := range node.Tags {
+		tagClasses, _ := lookupClasses(c.Tags, tag)
+		classes = append(classes, tagClasses...)
+	}
+
+	environment := c.DefaultEnvironment
+	if name := environmentName(node); name != "" {
+		environment = name
+		if mapped, ok := c.Environments[name]; ok {
+			environment = mapped
+		} else if mapped, ok := c.Environments[strings.ToLower(name)]; ok {
+			environment = mapped
+		}
+	}
+
+	parameters := map[string]interface{}{}
+	for k, v := range c.Parameters {
+		parameters[k] = v
+	}
+	for k, v := range node.Metadata {
+		parameters[k] = v
+	}
+	parameters["inventory_id"] = node.InventoryID
+	parameters["inventory_hostname"] = node.Hostname
+	parameters["inventory_role"] = node.Role
+	parameters["inventory_tags"] = append([]string{}, node.Tags...)
+	if node.System != nil {
+		parameters["inventory_system"] = node.System.Name
+	}
+	if node.LocationString != "" {
+		parameters["inventory_location"] = node.LocationString
+	}
+
+	return &Classification{Classes: uniqueNames(classes...), Environment: environment, Parameters: parameters}
+}
+
+// lookupClasses looks up name in a mapping, falling back to its lower case
+// form since mapping file keys are case insensitive
+func lookupClasses(mapping map[string][]string, name string) ([]string, bool) {
+	if classes, ok := mapping[name]; ok {
+		return classes, true
+	}
+	classes, ok := mapping[strings.ToLower(name)]
+	return classes, ok
+}
+
+// Puppet returns the classification as Puppet ENC YAML, with classes as a
+// hash of empty parameter lists
+func (cl *Classification) Puppet() ([]byte, error) {
+	classes := map[string]interface{}{}
+	for _, class := range cl.Classes {
+		classes[class] = map[string]interface{}{}
+	}
+	doc := map[string]interface{}{"classes": classes, "parameters": cl.Parameters}
+	if cl.Environment != "" {
+		doc["environment"] = cl.Environment
+	}
+	return printer.ToYAML(doc)
+}
+
+// Salt returns the classification as Salt external nodes YAML, with classes
+// as a list
+func (cl *Classification) Salt() ([]byte, error) {
+	doc := map[string]interface{}{"classes": cl.Classes, "parameters": cl.Parameters}
+	if cl.Environment != "" {
+		doc["environment"] = cl.Environment
+	}
+	return printer.ToYAML(doc)
+}
+
+// FindNode returns the node with the given hostname.  Fully qualified names
+// match on their first label if no node has the full name.
+func FindNode(nodes []*types.InventoryNode, hostname string) *types.InventoryNode {
+	hostname = strings.TrimSuffix(hostname, ".")
+	short := strings.SplitN(hostname, ".", 2)[0]
+
+	candidates := []*types.InventoryNode{}
+	for _, node := range nodes {
+		if node.Hostname == hostname {
+			return node
+		}
+		if node.Hostname == short {
+			candidates = append(candidates, node)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].InventoryID < candidates[b].InventoryID })
+	return candidates[0]
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+const testClassifierConfig = `
+classes: [base]
+role_class: "roles::%s"
+roles:
+  login: [roles::login, profile::ssh]
+systems:
+  tsys: [profile::tsys]
+tags:
+  gpu: [profile::gpu]
+environments:
+  prod: production
+default_environment: staging
+parameters:
+  site: pgc
+  gpus: 0
+`
+
+func loadTestClassifier(t *testing.T, config string) (*ClassifierConfig, error) {
+	dir, err := ioutil.TempDir("", "enc")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "enc.yml")
+	ioutil.WriteFile(filename, []byte(config), 0644)
+	return LoadClassifierConfig(filename)
+}
+
+func testClassifier(t *testing.T) *ClassifierConfig {
+	c, err := loadTestClassifier(t, testClassifierConfig)
+	if err != nil {
+		t.Fatalf("unable to load classifier config: %v", err)
+	}
+	return c
+}
+
+func TestLoadClassifierConfigRoleClass(t *testing.T) {
+	for _, format := range []string{"roles", "roles::%s::%s", "roles::%d", "roles::%s%v", "100%%"} {
+		if _, err := loadTestClassifier(t, "role_class: \""+format+"\"\n"); err == nil {
+			t.Errorf("expected an error for role_class %q", format)
+		}
+	}
+
+	for _, format := range []string{"role::%s", "%s", "100%%::%s"} {
+		if _, err := loadTestClassifier(t, "role_class: \""+format+"\"\n"); err != nil {
+			t.Errorf("unexpected error for role_class %q: %v", format, err)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	s := testAnsibleSnapshot()
+	c := testClassifier(t)
+
+	worker := c.Classify(s.Nodes[0])
+	if expected := []string{"base", "roles::worker", "profile::tsys", "profile::gpu"}; !reflect.DeepEqual(worker.Classes, expected) {
+		t.Errorf("got unexpected classes: %v", worker.Classes)
+	}
+	if worker.Environment != "production" {
+		t.Errorf("got unexpected environment: %s", worker.Environment)
+	}
+	if worker.Parameters["site"] != "pgc" || worker.Parameters["gpus"] != 4.0 || worker.Parameters["inventory_role"] != "worker" {
+		t.Errorf("got unexpected parameters: %v", worker.Parameters)
+	}
+
+	login := c.Classify(s.Nodes[1])
+	if expected := []string{"base", "roles::login", "profile::ssh", "profile::tsys"}; !reflect.DeepEqual(login.Classes, expected) {
+		t.Errorf("got unexpected classes: %v", login.Classes)
+	}
+
+	s.Nodes[1].Environment = nil
+	if cl := c.Classify(s.Nodes[1]); cl.Environment != "staging" {
+		t.Errorf("expected default environment, got %s", cl.Environment)
+	}
+
+	if cl := (&ClassifierConfig{}).Classify(s.Nodes[0]); !reflect.DeepEqual(cl.Classes, []string{"role::worker"}) || cl.Environment != "prod" {
+		t.Errorf("got unexpected default classification: %v", cl)
+	}
+}
+
+func TestClassificationOutput(t *testing.T) {
+	cl := &Classification{Classes: []string{"base", "role::worker"}, Environment: "production", Parameters: map[string]interface{}{"site": "pgc"}}
+
+	puppet, err := cl.Puppet()
+	if err != nil {
+		t.Fatalf("unable to render puppet output: %v", err)
+	}
+	expected := `classes:
+  base: {}
+  role::worker: {}
+environment: production
+parameters:
+  site: pgc
+`
+	if string(puppet) != expected {
+		t.Errorf("got unexpected puppet output:\n%s", puppet)
+	}
+
+	salt, err := cl.Salt()
+	if err != nil {
+		t.Fatalf("unable to render salt output: %v", err)
+	}
+	if !strings.HasPrefix(string(salt), "classes:\n- base\n- role::worker\n") {
+		t.Errorf("got unexpected salt output:\n%s", salt)
+	}
+}
+
+func TestFindNode(t *testing.T) {
+	nodes := []*types.InventoryNode{
+		{Hostname: "node-000", InventoryID: "b"},
+		{Hostname: "node-000.example.com", InventoryID: "a"},
+		{Hostname: "node-001", InventoryID: "c"},
+	}
+
+	for _, tc := range []struct {
+		hostname, expected string
+	}{
+		{"node-000", "b"},
+		{"node-000.example.com.", "a"},
+		{"node-001.prov.example.com", "c"},
+		{"node-002", ""},
+	} {
+		node := FindNode(nodes, tc.hostname)
+		if (node == nil && tc.expected != "") || (node != nil && node.InventoryID != tc.expected) {
+			t.Errorf("got unexpected node for %s: %v", tc.hostname, node)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// NodeFilter selects nodes by role, tag, system and hostname.  A node matches
+// if it matches any of the values given for every field that isn't empty.
+// Hosts are shell patterns, eg. "node-0*".
+type NodeFilter struct {
+	Roles   []string
+	Tags    []string
+	Systems []string
+	Hosts   []string
+}
+
+// ParseNodeFilter parses a comma separated list of key=value pairs, where
+// key is one of role, tag, system or host, eg. "role=worker,tag=gpu".
+func ParseNodeFilter(s string) (*NodeFilter, error) {
+	f := &NodeFilter{}
+	if s == "" {
+		return f, nil
+	}
+	for _, term := range strings.Split(s, ",") {
+		parts := strings.SplitN(term, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid filter term %q, expected key=value", term)
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch key {
+		case "role":
+			f.Roles = append(f.Roles, value)
+		case "tag":
+			f.Tags = append(f.Tags, value)
+		case "system":
+			f.Systems = append(f.Systems, value)
+		case "host":
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, fmt.Errorf("invalid host pattern %q: %v", value, err)
+			}
+			f.Hosts = append(f.Hosts, value)
+		default:
+			return nil, fmt.Errorf("unknown filter key %q", key)
+		}
+	}
+	return f, nil
+}
+
+// Match returns true if the node is selected by the filter.  A nil filter
+// matches every node.
+func (f *NodeFilter) Match(node *types.InventoryNode) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Roles) > 0 && !containsAny(f.Roles, node.Role) {
+		return false
+	}
+	if len(f.Tags) > 0 && !containsAny(f.Tags, node.Tags...) {
+		return false
+	}
+	if len(f.Systems) > 0 && (node.System == nil || !containsAny(f.Systems, node.System.Name)) {
+		return false
+	}
+	if len(f.Hosts) > 0 {
+		for _, pattern := range f.Hosts {
+			if ok, _ := path.Match(pattern, node.Hostname); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Nodes returns the nodes of the snapshot selected by the filter
+func (f *NodeFilter) Nodes(s *Snapshot) []*types.InventoryNode {
+	nodes := []*types.InventoryNode{}
+	for _, node := range s.Nodes {
+		if f.Match(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func containsAny(names []string, values ...string) bool {
+	for _, name := range names {
+		for _, value := range values {
+			if name == value {
+				return true
+			}
+		}
+	}
+	return false
+}
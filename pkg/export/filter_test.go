@@ -0,0 +1,58 @@
+package export
+
+import (
+	"testing"
+)
+
+func TestParseNodeFilter(t *testing.T) {
+	f, err := ParseNodeFilter("role=worker,role=login,tag=gpu,system=tsys,host=node-0*")
+	if err != nil {
+		t.Fatalf("unable to parse filter: %v", err)
+	}
+	if len(f.Roles) != 2 || len(f.Tags) != 1 || len(f.Systems) != 1 || len(f.Hosts) != 1 {
+		t.Errorf("got unexpected filter: %+v", f)
+	}
+
+	for _, invalid := range []string{"role", "role=", "colour=blue", "host=["} {
+		if _, err := ParseNodeFilter(invalid); err == nil {
+			t.Errorf("expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestNodeFilterMatch(t *testing.T) {
+	s := testSnapshot()
+
+	for _, tc := range []struct {
+		filter   string
+		expected []string
+	}{
+		{"", []string{"node-000", "node-001"}},
+		{"role=login", []string{"node-001"}},
+		{"role=login,role=worker", []string{"node-000", "node-001"}},
+		{"role=login,tag=gpu", []string{}},
+		{"tag=gpu,system=tsys", []string{"node-000"}},
+		{"system=other", []string{}},
+		{"host=*-001", []string{"node-001"}},
+	} {
+		f, err := ParseNodeFilter(tc.filter)
+		if err != nil {
+			t.Fatalf("unable to parse filter %q: %v", tc.filter, err)
+		}
+		nodes := f.Nodes(s)
+		if len(nodes) != len(tc.expected) {
+			t.Errorf("got wrong number of nodes for %q: expected %v, got %d", tc.filter, tc.expected, len(nodes))
+			continue
+		}
+		for idx, node := range nodes {
+			if node.Hostname != tc.expected[idx] {
+				t.Errorf("got unexpected node for %q: %s", tc.filter, node.Hostname)
+			}
+		}
+	}
+
+	var f *NodeFilter
+	if !f.Match(s.Nodes[0]) {
+		t.Errorf("nil filter should match every node")
+	}
+}
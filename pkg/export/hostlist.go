@@ -0,0 +1,79 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Hostlist compresses hostnames into the range syntax understood by Slurm,
+// pdsh and clush, eg. node-[000-003,005].  Names are grouped by the text
+// before their trailing number and the number's width, so zero padding is
+// preserved.  Names without a trailing number are listed as is.
+func Hostlist(names []string) string {
+	type group struct {
+		prefix  string
+		width   int
+		numbers []int
+	}
+	groups := map[string]*group{}
+	order := []string{}
+	for _, name := range uniqueNames(names...) {
+		idx := len(name)
+		for idx > 0 && name[idx-1] >= '0' && name[idx-1] <= '9' {
+			idx--
+		}
+		key := name
+		prefix, digits := name[:idx], name[idx:]
+		n, err := strconv.Atoi(digits)
+		if digits != "" && err == nil {
+			key = fmt.Sprintf("%s\x00%d", prefix, len(digits))
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{prefix: name}
+			if key != name {
+				g.prefix, g.width = prefix, len(digits)
+			}
+			groups[key] = g
+			order = append(order, key)
+		}
+		if g.width > 0 {
+			g.numbers = append(g.numbers, n)
+		}
+	}
+	sort.Strings(order)
+
+	parts := []string{}
+	for _, key := range order {
+		g := groups[key]
+		if g.width == 0 {
+			parts = append(parts, g.prefix)
+			continue
+		}
+
+		sort.Ints(g.numbers)
+		ranges := []string{}
+		for start := 0; start < len(g.numbers); {
+			end := start
+			for end+1 < len(g.numbers) && g.numbers[end+1] == g.numbers[end]+1 {
+				end++
+			}
+			if start == end {
+				ranges = append(ranges, fmt.Sprintf("%0*d", g.width, g.numbers[start]))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%0*d-%0*d", g.width, g.numbers[start], g.width, g.numbers[end]))
+			}
+			start = end + 1
+		}
+
+		if len(g.numbers) == 1 {
+			parts = append(parts, g.prefix+ranges[0])
+		} else {
+			parts = append(parts, fmt.Sprintf("%s[%s]", g.prefix, strings.Join(ranges, ",")))
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,22 @@
+package export
+
+import (
+	"testing"
+)
+
+func TestHostlist(t *testing.T) {
+	for _, tc := range []struct {
+		names    []string
+		expected string
+	}{
+		{[]string{}, ""},
+		{[]string{"node-001"}, "node-001"},
+		{[]string{"node-003", "node-001", "node-002", "node-005", "node-002"}, "node-[001-003,005]"},
+		{[]string{"node-9", "node-10", "node-11"}, "node-9,node-[10-11]"},
+		{[]string{"login", "gpu01", "gpu02", "node-000"}, "gpu[01-02],login,node-000"},
+	} {
+		if got := Hostlist(tc.names); got != tc.expected {
+			t.Errorf("got unexpected hostlist for %v: expected %s, got %s", tc.names, tc.expected, got)
+		}
+	}
+}
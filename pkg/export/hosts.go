@@ -0,0 +1,154 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// HostsOptions control the generated /etc/hosts fragment
+type HostsOptions struct {
+	// Networks limits the output to the named networks, or all networks if empty
+	Networks []string
+
+	// Primary names the network whose addresses also get the node's plain
+	// hostname
+	Primary string
+
+	// Suffixes maps network names to the suffix of the per network alias,
+	// eg. "ib" for node01-ib.  The network name is used if not listed.
+	Suffixes map[string]string
+
+	Filter *NodeFilter
+}
+
+// alias returns the per network alias of an address
+func (opts *HostsOptions) alias(a *Address) string {
+	suffix, ok := opts.Suffixes[a.Network.Name]
+	if !ok {
+		suffix = a.Network.Name
+	}
+	return fmt.Sprintf("%s-%s", a.Hostname, suffix)
+}
+
+// GenerateHosts returns an /etc/hosts fragment with a line for every node
+// address.  Each address is named with its per network alias and, if its
+// network has a domain, its fully qualified name.  Addresses on the primary
+// network also get the node's hostname.
+func GenerateHosts(s *Snapshot, opts *HostsOptions) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+	for _, a := range s.Addresses() {
+		if !contains(opts.Networks, a.Network.Name) || !opts.Filter.Match(a.Node) {
+			continue
+		}
+		if a.Network.Name == opts.Primary {
+			writeHostsLine(buf, a.IP, a.FQDN(), a.Hostname, opts.alias(a))
+		} else if a.Network.Domain != "" {
+			writeHostsLine(buf, a.IP, opts.alias(a), a.FQDN())
+		} else {
+			writeHostsLine(buf, a.IP, opts.alias(a))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// SSHOptions control the generated ssh client configuration
+type SSHOptions struct {
+	// Networks lists the networks to connect over in order of preference.
+	// A node's first address in the first of these networks it has an
+	// address in is used, or its first IPv4 address if empty.
+	Networks []string
+
+	// User and IdentityFile are set for every host if not empty
+	User         string
+	IdentityFile string
+
+	// Suffixes are used for per network aliases as in HostsOptions
+	Suffixes map[string]string
+
+	// JumpHosts maps system names to the host used as a ProxyJump for nodes
+	// in that system, with "*" applying to nodes in any other system.
+	JumpHosts map[string]string
+
+	Filter *NodeFilter
+}
+
+// sshAddress returns the address used to connect to a node
+func (opts *SSHOptions) sshAddress(addresses []*Address) *Address {
+	if len(opts.Networks) == 0 {
+		for _, a := range addresses {
+			if a.IsV4() {
+				return a
+			}
+		}
+		return nil
+	}
+	for _, network := range opts.Networks {
+		for _, a := range addresses {
+			if a.Network.Name == network {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// GenerateSSHConfig returns a Host block for every node with an address to
+// connect to, matching its hostname and fully qualified name, followed by a
+// block for each per network alias of the node.
+func GenerateSSHConfig(s *Snapshot, opts *SSHOptions) ([]byte, error) {
+	byNode := map[string][]*Address{}
+	for _, a := range s.Addresses() {
+		byNode[a.Hostname] = append(byNode[a.Hostname], a)
+	}
+
+	aliases := &HostsOptions{Suffixes: opts.Suffixes}
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+	for _, node := range opts.Filter.Nodes(s) {
+		addresses := byNode[node.Hostname]
+		target := opts.sshAddress(addresses)
+		if target == nil {
+			continue
+		}
+		opts.writeHost(buf, node, target, node.Hostname, target.FQDN())
+
+		seen := map[string]bool{}
+		for _, a := range addresses {
+			alias := aliases.alias(a)
+			if seen[alias] || (len(opts.Networks) == 0 && !a.IsV4()) || !contains(opts.Networks, a.Network.Name) {
+				continue
+			}
+			seen[alias] = true
+			opts.writeHost(buf, node, a, alias)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (opts *SSHOptions) writeHost(buf *bytes.Buffer, node *types.InventoryNode, a *Address, names ...string) {
+	fmt.Fprintf(buf, "\nHost %s\n", strings.Join(uniqueNames(names...), " "))
+	fmt.Fprintf(buf, "    HostName %s\n", a.IP)
+	if opts.User != "" {
+		fmt.Fprintf(buf, "    User %s\n", opts.User)
+	}
+	if opts.IdentityFile != "" {
+		fmt.Fprintf(buf, "    IdentityFile %s\n", opts.IdentityFile)
+	}
+	if jump := opts.jumpHost(node.System); jump != "" && jump != node.Hostname && jump != a.FQDN() {
+		fmt.Fprintf(buf, "    ProxyJump %s\n", jump)
+	}
+}
+
+// jumpHost returns the jump host for nodes in system
+func (opts *SSHOptions) jumpHost(system *types.System) string {
+	if system != nil {
+		if jump, ok := opts.JumpHosts[system.Name]; ok {
+			return jump
+		}
+	}
+	return opts.JumpHosts["*"]
+}
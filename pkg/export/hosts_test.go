@@ -0,0 +1,91 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateHosts(t *testing.T) {
+	data, err := GenerateHosts(testSnapshot(), &HostsOptions{Primary: "prov", Suffixes: map[string]string{"mgmt": "bmc"}})
+	if err != nil {
+		t.Fatalf("unable to generate hosts: %v", err)
+	}
+
+	expected := `# Generated from the inventory, do not edit
+10.1.0.10	node-000-bmc
+10.0.0.10	node-000.prov.example.com node-000 node-000-prov
+2001:db8::10	node-000.prov.example.com node-000 node-000-prov
+10.0.0.11	node-001.prov.example.com node-001 node-001-prov
+`
+	if string(data) != expected {
+		t.Errorf("got unexpected hosts:\n%s", data)
+	}
+}
+
+func TestGenerateHostsFiltered(t *testing.T) {
+	data, err := GenerateHosts(testSnapshot(), &HostsOptions{Networks: []string{"prov"}, Filter: &NodeFilter{Roles: []string{"login"}}})
+	if err != nil {
+		t.Fatalf("unable to generate hosts: %v", err)
+	}
+
+	if hosts := string(data); !strings.HasSuffix(hosts, "\n10.0.0.11\tnode-001-prov node-001.prov.example.com\n") || strings.Contains(hosts, "node-000") {
+		t.Errorf("got unexpected hosts:\n%s", hosts)
+	}
+}
+
+func TestGenerateSSHConfig(t *testing.T) {
+	opts := &SSHOptions{
+		Networks:  []string{"prov"},
+		User:      "root",
+		JumpHosts: map[string]string{"tsys": "node-001", "*": "bastion"},
+	}
+	data, err := GenerateSSHConfig(testSnapshot(), opts)
+	if err != nil {
+		t.Fatalf("unable to generate ssh config: %v", err)
+	}
+
+	expected := `# Generated from the inventory, do not edit
+
+Host node-000 node-000.prov.example.com
+    HostName 10.0.0.10
+    User root
+    ProxyJump node-001
+
+Host node-000-prov
+    HostName 10.0.0.10
+    User root
+    ProxyJump node-001
+
+Host node-001 node-001.prov.example.com
+    HostName 10.0.0.11
+    User root
+
+Host node-001-prov
+    HostName 10.0.0.11
+    User root
+`
+	if string(data) != expected {
+		t.Errorf("got unexpected ssh config:\n%s", data)
+	}
+}
+
+func TestGenerateSSHConfigDefaultNetwork(t *testing.T) {
+	s := testSnapshot()
+	s.Nodes[1].System = nil
+	data, err := GenerateSSHConfig(s, &SSHOptions{JumpHosts: map[string]string{"*": "bastion"}})
+	if err != nil {
+		t.Fatalf("unable to generate ssh config: %v", err)
+	}
+	config := string(data)
+
+	// node-000's first IPv4 address is on the bmc interface
+	if !strings.Contains(config, "Host node-000\n    HostName 10.1.0.10\n    ProxyJump bastion\n") {
+		t.Errorf("got unexpected ssh config:\n%s", config)
+	}
+	if !strings.Contains(config, "Host node-000-mgmt\n") || !strings.Contains(config, "Host node-001-prov\n") {
+		t.Errorf("missing network aliases:\n%s", config)
+	}
+	if strings.Contains(config, "2001:db8::10") {
+		t.Errorf("unexpected IPv6 address without a network preference:\n%s", config)
+	}
+}
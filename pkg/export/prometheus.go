@@ -0,0 +1,129 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+)
+
+// DefaultExporters is used when no exporters are configured
+var DefaultExporters = map[string]int{"node": 9100}
+
+// PrometheusOptions control the generated file_sd target groups
+type PrometheusOptions struct {
+	// Networks lists the networks targets are chosen from in order of
+	// preference, as in SSHOptions
+	Networks []string
+
+	// Exporters maps exporter names to the port they listen on, defaulting
+	// to DefaultExporters
+	Exporters map[string]int
+
+	// Roles maps node roles to the exporters scraped on nodes with that role.
+	// Roles that aren't listed use the "*" entry, or every exporter if there
+	// isn't one.
+	Roles map[string][]string
+
+	Filter *NodeFilter
+}
+
+// TargetGroup is a Prometheus file_sd target group
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// exporters returns the names of the exporters scraped on nodes with role
+func (opts *PrometheusOptions) exporters(role string) []string {
+	ports := opts.ports()
+	names, ok := opts.Roles[role]
+	if !ok {
+		names, ok = opts.Roles["*"]
+	}
+	if !ok {
+		names = make([]string, 0, len(ports))
+		for name := range ports {
+			names = append(names, name)
+		}
+	}
+
+	result := []string{}
+	for _, name := range names {
+		if _, ok := ports[name]; ok {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return uniqueNames(result...)
+}
+
+func (opts *PrometheusOptions) ports() map[string]int {
+	if len(opts.Exporters) == 0 {
+		return DefaultExporters
+	}
+	return opts.Exporters
+}
+
+// BuildTargetGroups returns a target group for every exporter on every node
+// with an address to scrape, ordered by hostname and exporter.  Groups are
+// labelled with the exporter, the node's hostname as its instance, and its
+// system, role, environment, rack, location and the network scraped over.
+func BuildTargetGroups(s *Snapshot, opts *PrometheusOptions) []*TargetGroup {
+	byNode := map[string][]*Address{}
+	for _, a := range s.Addresses() {
+		byNode[a.Hostname] = append(byNode[a.Hostname], a)
+	}
+
+	ssh := &SSHOptions{Networks: opts.Networks}
+	ports := opts.ports()
+	groups := []*TargetGroup{}
+	for _, node := range opts.Filter.Nodes(s) {
+		target := ssh.sshAddress(byNode[node.Hostname])
+		if target == nil {
+			continue
+		}
+
+		labels := map[string]string{
+			"instance": node.Hostname,
+			"role":     node.Role,
+			"network":  target.Network.Name,
+		}
+		if node.System != nil {
+			labels["system"] = node.System.Name
+		}
+		if env := environmentName(node); env != "" {
+			labels["environment"] = env
+		}
+		if node.Location != nil && node.Location.Rack != "" {
+			labels["rack"] = node.Location.Rack
+		}
+		if node.LocationString != "" {
+			labels["location"] = node.LocationString
+		}
+
+		for _, exporter := range opts.exporters(node.Role) {
+			groupLabels := map[string]string{"exporter": exporter}
+			for k, v := range labels {
+				if v != "" {
+					groupLabels[k] = v
+				}
+			}
+			groups = append(groups, &TargetGroup{
+				Targets: []string{net.JoinHostPort(target.IP.String(), strconv.Itoa(ports[exporter]))},
+				Labels:  groupLabels,
+			})
+		}
+	}
+	return groups
+}
+
+// GeneratePrometheus returns the target groups as a file_sd JSON document
+func GeneratePrometheus(s *Snapshot, opts *PrometheusOptions) ([]byte, error) {
+	data, err := json.MarshalIndent(BuildTargetGroups(s, opts), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal target groups: %v", err)
+	}
+	return append(data, '\n'), nil
+}
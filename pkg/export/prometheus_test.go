@@ -0,0 +1,58 @@
+package export
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBuildTargetGroups(t *testing.T) {
+	opts := &PrometheusOptions{
+		Networks:  []string{"prov"},
+		Exporters: map[string]int{"node": 9100, "dcgm": 9400},
+		Roles:     map[string][]string{"worker": {"node", "dcgm", "missing"}, "*": {"node"}},
+	}
+	groups := BuildTargetGroups(testAnsibleSnapshot(), opts)
+
+	expected := []*TargetGroup{
+		{
+			Targets: []string{"10.0.0.10:9400"},
+			Labels: map[string]string{"exporter": "dcgm", "instance": "node-000", "role": "worker", "network": "prov",
+				"system": "tsys", "environment": "prod", "rack": "a-1", "location": "a-1-10"},
+		},
+		{
+			Targets: []string{"10.0.0.10:9100"},
+			Labels: map[string]string{"exporter": "node", "instance": "node-000", "role": "worker", "network": "prov",
+				"system": "tsys", "environment": "prod", "rack": "a-1", "location": "a-1-10"},
+		},
+		{
+			Targets: []string{"10.0.0.11:9100"},
+			Labels:  map[string]string{"exporter": "node", "instance": "node-001", "role": "login", "network": "prov", "system": "tsys", "environment": "prod"},
+		},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		data, _ := json.Marshal(groups)
+		t.Errorf("got unexpected target groups: %s", data)
+	}
+}
+
+func TestBuildTargetGroupsDefaults(t *testing.T) {
+	s := testSnapshot()
+	s.Nodes[0].Networks["provisioning"].Config.IP = []string{"2001:db8::10/64"}
+	delete(s.Nodes[0].Networks, "bmc")
+
+	groups := BuildTargetGroups(s, &PrometheusOptions{Networks: []string{"prov"}, Filter: &NodeFilter{Roles: []string{"worker"}}})
+	if len(groups) != 1 || groups[0].Targets[0] != "[2001:db8::10]:9100" || groups[0].Labels["exporter"] != "node" {
+		t.Errorf("got unexpected target groups: %v", groups)
+	}
+}
+
+func TestGeneratePrometheus(t *testing.T) {
+	data, err := GeneratePrometheus(testSnapshot(), &PrometheusOptions{Filter: &NodeFilter{Systems: []string{"none"}}})
+	if err != nil {
+		t.Fatalf("unable to generate targets: %v", err)
+	}
+	if string(data) != "[]\n" {
+		t.Errorf("expected an empty list, got %s", data)
+	}
+}
@@ -0,0 +1,233 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// Node metadata keys describing a node's hardware to Slurm.  CPUs and memory,
+// in megabytes, are whole numbers.  GRES and features are lists or comma separated
+// strings, eg. "gpu:a100:4".
+const (
+	SlurmCPUsMetadataKey     = "cpus"
+	SlurmMemoryMetadataKey   = "memory"
+	SlurmGresMetadataKey     = "gres"
+	SlurmFeaturesMetadataKey = "features"
+)
+
+// SlurmOptions control the generated slurm.conf fragment
+type SlurmOptions struct {
+	// System is the cluster the fragment is generated for, and is required
+	System string
+
+	// DefaultPartition names the role whose partition is the default
+	DefaultPartition string
+
+	Filter *NodeFilter
+}
+
+// slurmNodeAttributes returns the NodeName attributes of a node, other than
+// its name
+func slurmNodeAttributes(node *types.InventoryNode) (string, error) {
+	attrs := []string{}
+	cpus, ok, err := metadataNumber(node.Metadata, SlurmCPUsMetadataKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid slurm metadata for %s: %v", node.Hostname, err)
+	}
+	if ok {
+		attrs = append(attrs, fmt.Sprintf("CPUs=%d", cpus))
+	}
+	memory, ok, err := metadataNumber(node.Metadata, SlurmMemoryMetadataKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid slurm metadata for %s: %v", node.Hostname, err)
+	}
+	if ok {
+		attrs = append(attrs, fmt.Sprintf("RealMemory=%d", memory))
+	}
+	if gres := metadataList(node.Metadata, SlurmGresMetadataKey); len(gres) > 0 {
+		attrs = append(attrs, "Gres="+strings.Join(gres, ","))
+	}
+	if features := metadataList(node.Metadata, SlurmFeaturesMetadataKey); len(features) > 0 {
+		attrs = append(attrs, "Feature="+strings.Join(features, ","))
+	}
+	return strings.Join(append(attrs, "State=UNKNOWN"), " "), nil
+}
+
+// GenerateSlurm returns NodeName lines for every node in the system,
+// combining nodes with identical hardware into one line, followed by a
+// PartitionName line for each of the system's roles.  Nodes in other systems
+// are left out, since each system is a separate Slurm cluster.
+func GenerateSlurm(s *Snapshot, opts *SlurmOptions) ([]byte, error) {
+	if opts.System == "" {
+		return nil, fmt.Errorf("a system is required")
+	}
+
+	byAttributes := map[string][]string{}
+	byRole := map[string][]string{}
+	for _, node := range opts.Filter.Nodes(s) {
+		if node.System == nil || node.System.Name != opts.System {
+			continue
+		}
+		attrs, err := slurmNodeAttributes(node)
+		if err != nil {
+			return nil, err
+		}
+		byAttributes[attrs] = append(byAttributes[attrs], node.Hostname)
+		if node.Role != "" {
+			byRole[node.Role] = append(byRole[node.Role], node.Hostname)
+		}
+	}
+
+	// order node lines by their host lists
+	lines := []string{}
+	for attrs, hosts := range byAttributes {
+		lines = append(lines, fmt.Sprintf("NodeName=%s %s", Hostlist(hosts), attrs))
+	}
+	sort.Strings(lines)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+	for _, line := range lines {
+		fmt.Fprintln(buf, line)
+	}
+
+	fmt.Fprintln(buf)
+	for _, role := range sortedKeys(byRole) {
+		line := fmt.Sprintf("PartitionName=%s Nodes=%s", role, Hostlist(byRole[role]))
+		if role == opts.DefaultPartition {
+			line += " Default=YES"
+		}
+		fmt.Fprintln(buf, line+" State=UP")
+	}
+	return buf.Bytes(), nil
+}
+
+// ClusterOptions control the generated genders and clush group files
+type ClusterOptions struct {
+	Filter *NodeFilter
+}
+
+// GenerateGenders returns a genders file listing each node with system= and
+// role= attributes and its tags
+func GenerateGenders(s *Snapshot, opts *ClusterOptions) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+	for _, node := range opts.Filter.Nodes(s) {
+		attrs := []string{}
+		if node.System != nil {
+			attrs = append(attrs, "system="+node.System.Name)
+		}
+		if node.Role != "" {
+			attrs = append(attrs, "role="+node.Role)
+		}
+		attrs = append(attrs, node.Tags...)
+		if len(attrs) == 0 {
+			fmt.Fprintln(buf, node.Hostname)
+			continue
+		}
+		fmt.Fprintf(buf, "%s %s\n", node.Hostname, strings.Join(uniqueNames(attrs...), ","))
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateClushGroups returns a clush group file with groups for all nodes,
+// each system, each role within a system, named <system>-<role>, and each
+// tag, named tag-<tag>.  Systems, roles or tags whose group names collide,
+// such as a system named all, are rejected.
+func GenerateClushGroups(s *Snapshot, opts *ClusterOptions) ([]byte, error) {
+	groups := map[string][]string{}
+	owners := map[string]string{}
+	add := func(name, owner, host string) error {
+		if existing, ok := owners[name]; ok && existing != owner {
+			return fmt.Errorf("unable to generate clush groups: the group for %s has the same name as %s", owner, existing)
+		}
+		owners[name] = owner
+		groups[name] = append(groups[name], host)
+		return nil
+	}
+
+	for _, node := range opts.Filter.Nodes(s) {
+		if err := add("all", "all nodes", node.Hostname); err != nil {
+			return nil, err
+		}
+		if node.System != nil {
+			if err := add(node.System.Name, "system "+node.System.Name, node.Hostname); err != nil {
+				return nil, err
+			}
+			if node.Role != "" {
+				owner := fmt.Sprintf("role %s of system %s", node.Role, node.System.Name)
+				if err := add(node.System.Name+"-"+node.Role, owner, node.Hostname); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, tag := range node.Tags {
+			if err := add("tag-"+tag, "tag "+tag, node.Hostname); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# Generated from the inventory, do not edit")
+	for _, name := range sortedKeys(groups) {
+		fmt.Fprintf(buf, "%s: %s\n", name, Hostlist(groups[name]))
+	}
+	return buf.Bytes(), nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metadataNumber returns a whole number from node metadata, which is
+// decoded from JSON as a float64.  It returns false if the key isn't set, and
+// an error if the value isn't a whole number.
+func metadataNumber(metadata types.Metadata, key string) (int64, bool, error) {
+	switch v := metadata[key].(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return int64(v), true, nil
+		}
+	case int:
+		return int64(v), true, nil
+	case int64:
+		return v, true, nil
+	}
+	return 0, false, fmt.Errorf("%s must be a whole number, got %v", key, metadata[key])
+}
+
+// metadataList returns a list of strings from node metadata, given either
+// as a list or a comma separated string
+func metadataList(metadata types.Metadata, key string) []string {
+	values := []string{}
+	switch v := metadata[key].(type) {
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	case []string:
+		values = append(values, v...)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func testClusterSnapshot() *Snapshot {
+	system := &types.System{Name: "tsys"}
+	gpu := types.Metadata{"cpus": 64.0, "memory": 512000.0, "gres": []interface{}{"gpu:a100:4"}, "features": "a100, ib"}
+	cpu := types.Metadata{"cpus": 128.0, "memory": 256000.0}
+	return &Snapshot{Nodes: []*types.InventoryNode{
+		{Hostname: "tsys-gpu01", Role: "gpu", System: system, Tags: []string{"ib"}, Metadata: gpu},
+		{Hostname: "tsys-gpu02", Role: "gpu", System: system, Tags: []string{"ib"}, Metadata: gpu},
+		{Hostname: "tsys-login01", Role: "login", System: system},
+		{Hostname: "tsys-node001", Role: "compute", System: system, Metadata: cpu},
+		{Hostname: "tsys-node002", Role: "compute", System: system, Metadata: cpu},
+		{Hostname: "tsys-node003", Role: "compute", System: system, Metadata: cpu},
+		{Hostname: "other-node001", Role: "compute", System: &types.System{Name: "other"}},
+	}}
+}
+
+func TestGenerateSlurm(t *testing.T) {
+	data, err := GenerateSlurm(testClusterSnapshot(), &SlurmOptions{System: "tsys", DefaultPartition: "compute"})
+	if err != nil {
+		t.Fatalf("unable to generate slurm config: %v", err)
+	}
+
+	expected := `# Generated from the inventory, do not edit
+NodeName=tsys-gpu[01-02] CPUs=64 RealMemory=512000 Gres=gpu:a100:4 Feature=a100,ib State=UNKNOWN
+NodeName=tsys-login01 State=UNKNOWN
+NodeName=tsys-node[001-003] CPUs=128 RealMemory=256000 State=UNKNOWN
+
+PartitionName=compute Nodes=tsys-node[001-003] Default=YES State=UP
+PartitionName=gpu Nodes=tsys-gpu[01-02] State=UP
+PartitionName=login Nodes=tsys-login01 State=UP
+`
+	if string(data) != expected {
+		t.Errorf("got unexpected slurm config:\n%s", data)
+	}
+}
+
+func TestGenerateSlurmInvalid(t *testing.T) {
+	if _, err := GenerateSlurm(testClusterSnapshot(), &SlurmOptions{}); err == nil {
+		t.Errorf("expected an error without a system")
+	}
+
+	for _, value := range []interface{}{64.5, "64"} {
+		s := testClusterSnapshot()
+		s.Nodes[3].Metadata = types.Metadata{"cpus": value}
+		if _, err := GenerateSlurm(s, &SlurmOptions{System: "tsys"}); err == nil {
+			t.Errorf("expected an error for cpus %v", value)
+		}
+	}
+
+	// metadata of nodes in other systems isn't used
+	s := testClusterSnapshot()
+	s.Nodes[6].Metadata = types.Metadata{"memory": 1.5}
+	if _, err := GenerateSlurm(s, &SlurmOptions{System: "tsys"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGenerateGenders(t *testing.T) {
+	data, err := GenerateGenders(testClusterSnapshot(), &ClusterOptions{Filter: &NodeFilter{Roles: []string{"gpu", "login"}}})
+	if err != nil {
+		t.Fatalf("unable to generate genders: %v", err)
+	}
+
+	expected := `# Generated from the inventory, do not edit
+tsys-gpu01 system=tsys,role=gpu,ib
+tsys-gpu02 system=tsys,role=gpu,ib
+tsys-login01 system=tsys,role=login
+`
+	if string(data) != expected {
+		t.Errorf("got unexpected genders:\n%s", data)
+	}
+}
+
+func TestGenerateClushGroups(t *testing.T) {
+	data, err := GenerateClushGroups(testClusterSnapshot(), &ClusterOptions{})
+	if err != nil {
+		t.Fatalf("unable to generate clush groups: %v", err)
+	}
+
+	expected := `# Generated from the inventory, do not edit
+all: other-node001,tsys-gpu[01-02],tsys-login01,tsys-node[001-003]
+other: other-node001
+other-compute: other-node001
+tag-ib: tsys-gpu[01-02]
+tsys: tsys-gpu[01-02],tsys-login01,tsys-node[001-003]
+tsys-compute: tsys-node[001-003]
+tsys-gpu: tsys-gpu[01-02]
+tsys-login: tsys-login01
+`
+	if string(data) != expected {
+		t.Errorf("got unexpected clush groups:\n%s", data)
+	}
+}
+
+func TestGenerateClushGroupsCollision(t *testing.T) {
+	for _, nodes := range [][]*types.InventoryNode{
+		{{Hostname: "node-000", System: &types.System{Name: "all"}}},
+		{{Hostname: "node-000", System: &types.System{Name: "tag-gpu"}}, {Hostname: "node-001", Tags: []string{"gpu"}}},
+		{{Hostname: "node-000", System: &types.System{Name: "tsys-login"}}, {Hostname: "node-001", Role: "login", System: &types.System{Name: "tsys"}}},
+	} {
+		if data, err := GenerateClushGroups(&Snapshot{Nodes: nodes}, &ClusterOptions{}); err == nil {
+			t.Errorf("expected an error for colliding groups, got:\n%s", data)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package export generates configuration files for other services from the
+// contents of the inventory.
+package export
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// Snapshot holds the inventory objects used to generate configuration
+type Snapshot struct {
+	Nodes    []*types.InventoryNode
+	Networks []*types.Network
+
+	// Reservations is only populated by LoadReservations
+	Reservations types.IPReservationList
+}
+
+// LoadSnapshot fetches every node config and network.  The addresses of each
+// node config are its static IPAM reservations.
+func LoadSnapshot(inv *client.InventoryApi) (*Snapshot, error) {
+	nodes, err := inv.NodeConfig().GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get node configs: %v", err)
+	}
+
+	networks, err := inv.Network().GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get networks: %v", err)
+	}
+
+	s := &Snapshot{Nodes: nodes, Networks: networks}
+	s.sort()
+	return s, nil
+}
+
+// LoadReservations fetches the reservations in every network of the
+// snapshot, which includes addresses not assigned to any node.  If some
+// subnets couldn't be listed the remaining reservations are still loaded and
+// a *client.SkippedSubnetsError is returned.
+func (s *Snapshot) LoadReservations(inv *client.InventoryApi) error {
+	reservations := types.IPReservationList{}
+	skipped := []*net.IPNet{}
+	for _, n := range s.Networks {
+		networkReservations, err := inv.IPAM().GetIPReservationsByNetwork(n.Name)
+		if subnets := client.SkippedSubnets(err); subnets != nil {
+			skipped = append(skipped, subnets...)
+		} else if err != nil {
+			return fmt.Errorf("unable to get reservations for network %s: %v", n.Name, err)
+		}
+		reservations = append(reservations, networkReservations...)
+	}
+	s.Reservations = reservations
+	if len(skipped) > 0 {
+		return &client.SkippedSubnetsError{Subnets: skipped}
+	}
+	return nil
+}
+
+func (s *Snapshot) sort() {
+	sort.SliceStable(s.Nodes, func(a, b int) bool { return s.Nodes[a].Hostname < s.Nodes[b].Hostname })
+	sort.SliceStable(s.Networks, func(a, b int) bool { return s.Networks[a].Name < s.Networks[b].Name })
+}
+
+// Network returns the named network, or nil if it doesn't exist
+func (s *Snapshot) Network(name string) *types.Network {
+	for _, n := range s.Networks {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// Address is a single address assigned to a node interface
+type Address struct {
+	// Hostname is the node's hostname and Network the name of the network
+	// the interface is attached to
+	Hostname string
+	Network  *types.Network
+
+	// Interface is the logical name of the interface on the node
+	Interface string
+
+	// MACs lists every NIC attached to the interface
+	MACs []net.HardwareAddr
+
+	IP     net.IP
+	Subnet *types.Subnet
+
+	Node *types.InventoryNode
+}
+
+// Addresses returns every address assigned to a node interface, ordered by
+// hostname, interface name and address.  Addresses outside every subnet of
+// their network have a nil Subnet.
+func (s *Snapshot) Addresses() []*Address {
+	addresses := []*Address{}
+	for _, node := range s.Nodes {
+		names := make([]string, 0, len(node.Networks))
+		for name := range node.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			nic := node.Networks[name]
+			if nic == nil {
+				continue
+			}
+			network := s.Network(nic.Network.Name)
+			if network == nil {
+				network = &nic.Network
+			}
+
+			for _, ipString := range nic.Config.IP {
+				ip, _, err := net.ParseCIDR(ipString)
+				if err != nil {
+					if ip = net.ParseIP(ipString); ip == nil {
+						continue
+					}
+				}
+				addresses = append(addresses, &Address{
+					Hostname:  node.Hostname,
+					Network:   network,
+					Interface: name,
+					MACs:      nic.Interface.NICs,
+					IP:        ip,
+					Subnet:    subnetContaining(network, ip),
+					Node:      node,
+				})
+			}
+		}
+	}
+	return addresses
+}
+
+// FQDN returns the hostname qualified with the network's domain, if it has one
+func (a *Address) FQDN() string {
+	if a.Network == nil || a.Network.Domain == "" {
+		return a.Hostname
+	}
+	return fmt.Sprintf("%s.%s", a.Hostname, a.Network.Domain)
+}
+
+// IsV4 returns true if the address is an IPv4 address
+func (a *Address) IsV4() bool {
+	return a.IP.To4() != nil
+}
+
+// UnassignedReservations returns the static reservations whose address isn't
+// assigned to a node interface
+func (s *Snapshot) UnassignedReservations() types.IPReservationList {
+	assigned := map[string]bool{}
+	for _, a := range s.Addresses() {
+		assigned[a.IP.String()] = true
+	}
+
+	result := types.IPReservationList{}
+	for _, r := range s.Reservations {
+		if r.IP != nil && r.Static() && !assigned[r.IP.IP.String()] {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// NetworkContaining returns the network and subnet containing ip, or nils
+func (s *Snapshot) NetworkContaining(ip net.IP) (*types.Network, *types.Subnet) {
+	for _, n := range s.Networks {
+		if subnet := subnetContaining(n, ip); subnet != nil {
+			return n, subnet
+		}
+	}
+	return nil, nil
+}
+
+// subnetContaining is GetSubnetContainingIP, skipping subnets without a cidr
+func subnetContaining(n *types.Network, ip net.IP) *types.Subnet {
+	for _, subnet := range n.Subnets {
+		if subnet.Cidr != nil && subnet.Cidr.Contains(ip) {
+			return subnet
+		}
+	}
+	return nil
+}
+
+// contains returns true if names is empty or includes name
+func contains(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
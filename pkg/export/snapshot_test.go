@@ -0,0 +1,114 @@
+package export
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func testSnapshot() *Snapshot {
+	_, prov, _ := net.ParseCIDR("10.0.0.0/24")
+	_, prov6, _ := net.ParseCIDR("2001:db8::/64")
+	_, mgmt, _ := net.ParseCIDR("10.1.0.0/24")
+
+	provNetwork := &types.Network{Name: "prov", Domain: "prov.example.com", MTU: 9000, Subnets: types.SubnetList{
+		{Name: "prov", Cidr: prov, Gateway: net.ParseIP("10.0.0.1"), DNS: []net.IP{net.ParseIP("10.0.0.53"), net.ParseIP("2001:db8::53")}},
+		{Name: "prov6", Cidr: prov6},
+	}}
+	mgmtNetwork := &types.Network{Name: "mgmt", Subnets: types.SubnetList{{Name: "mgmt", Cidr: mgmt}}}
+
+	mac0, _ := net.ParseMAC("00:01:02:03:04:05")
+	mac1, _ := net.ParseMAC("00:01:02:03:04:06")
+	bmc0, _ := net.ParseMAC("00:01:02:03:05:05")
+
+	return &Snapshot{
+		Networks: []*types.Network{mgmtNetwork, provNetwork},
+		Nodes: []*types.InventoryNode{
+			{
+				Hostname:    "node-000",
+				InventoryID: "test-000",
+				Role:        "worker",
+				Tags:        []string{"gpu"},
+				System:      &types.System{Name: "tsys"},
+				Networks: map[string]*types.NICInstance{
+					"provisioning": {
+						Network:   *provNetwork,
+						Interface: types.NetworkInterface{NICs: []net.HardwareAddr{mac0}},
+						Config:    types.NicConfig{IP: []string{"10.0.0.10/24", "2001:db8::10/64"}},
+					},
+					"bmc": {
+						Network:   *mgmtNetwork,
+						Interface: types.NetworkInterface{NICs: []net.HardwareAddr{bmc0}},
+						Config:    types.NicConfig{IP: []string{"10.1.0.10/24"}},
+					},
+				},
+			},
+			{
+				Hostname:    "node-001",
+				InventoryID: "test-001",
+				Role:        "login",
+				System:      &types.System{Name: "tsys"},
+				Networks: map[string]*types.NICInstance{
+					"provisioning": {
+						Network:   *provNetwork,
+						Interface: types.NetworkInterface{NICs: []net.HardwareAddr{mac1}},
+						Config:    types.NicConfig{IP: []string{"10.0.0.11/24"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSnapshotAddresses(t *testing.T) {
+	addresses := testSnapshot().Addresses()
+
+	expected := []string{"node-000 bmc 10.1.0.10", "node-000 provisioning 10.0.0.10", "node-000 provisioning 2001:db8::10", "node-001 provisioning 10.0.0.11"}
+	if len(addresses) != len(expected) {
+		t.Fatalf("got unexpected addresses: %v", addresses)
+	}
+	for idx, a := range addresses {
+		if got := a.Hostname + " " + a.Interface + " " + a.IP.String(); got != expected[idx] {
+			t.Errorf("got unexpected address: expected %s, got %s", expected[idx], got)
+		}
+		if a.Subnet == nil {
+			t.Errorf("subnet not found for %s", a.IP)
+		}
+	}
+
+	if addresses[1].FQDN() != "node-000.prov.example.com" || addresses[0].FQDN() != "node-000" {
+		t.Errorf("got unexpected fqdns: %s %s", addresses[1].FQDN(), addresses[0].FQDN())
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).
+		Get("nodeconfig").
+		Reply(http.StatusOK).
+		BodyString(`[{"Hostname": "node-001"}, {"Hostname": "node-000"}]`)
+	gock.New(testBaseUrl.String()).
+		Get("network").
+		Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov"}]`)
+
+	s, err := LoadSnapshot(client.NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")}))
+	if err != nil {
+		t.Fatalf("unable to load snapshot: %v", err)
+	}
+
+	if len(s.Nodes) != 2 || s.Nodes[0].Hostname != "node-000" || s.Network("prov") == nil {
+		t.Errorf("got unexpected snapshot: %+v", s)
+	}
+}
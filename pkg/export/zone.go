@@ -0,0 +1,436 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// ZoneAliasMetadataKey is the node metadata key listing additional names for
+// a node, either as a list or a comma separated string.  Each alias becomes
+// a CNAME to the node's hostname.
+const ZoneAliasMetadataKey = "dnsAliases"
+
+const (
+	DefaultZoneTTL     = time.Hour
+	DefaultZoneRefresh = time.Hour
+	DefaultZoneRetry   = 15 * time.Minute
+	DefaultZoneExpire  = 14 * 24 * time.Hour
+	DefaultZoneMinimum = 5 * time.Minute
+)
+
+// Record types generated for a zone
+const (
+	RecordSOA   = "SOA"
+	RecordNS    = "NS"
+	RecordA     = "A"
+	RecordAAAA  = "AAAA"
+	RecordCNAME = "CNAME"
+	RecordPTR   = "PTR"
+)
+
+// ZoneOptions control the generated zones
+type ZoneOptions struct {
+	// Networks limits the output to the named networks, or all networks if empty
+	Networks []string
+
+	// PrimaryNS is the SOA primary name server and Hostmaster the responsible
+	// mailbox, either as an email address or in domain name form.  Hostmaster
+	// defaults to hostmaster in the primary name server's domain.
+	PrimaryNS  string
+	Hostmaster string
+
+	// NameServers are published as NS records, defaulting to PrimaryNS
+	NameServers []string
+
+	// TTL and the SOA timers default to the DefaultZone values
+	TTL     time.Duration
+	Refresh time.Duration
+	Retry   time.Duration
+	Expire  time.Duration
+	Minimum time.Duration
+}
+
+// Record is a single resource record.  Name is relative to the zone origin,
+// with "@" for the origin itself.  Names in Data are relative to the origin
+// unless they end with a dot.
+type Record struct {
+	Name string
+	Type string
+	Data string
+
+	// ip orders the records of reverse zones
+	ip net.IP
+}
+
+// Zone is a forward zone for a network domain or a reverse zone covering
+// one or more subnets
+type Zone struct {
+	Origin  string
+	Reverse bool
+	Options ZoneOptions
+	Records []*Record
+}
+
+// FQDN returns the fully qualified form of a record name in this zone
+func (z *Zone) FQDN(name string) string {
+	if name == "@" {
+		return z.Origin
+	}
+	return name + "." + z.Origin
+}
+
+// Filename returns the conventional file name for the zone
+func (z *Zone) Filename() string {
+	return strings.TrimSuffix(z.Origin, ".") + ".zone"
+}
+
+// BuildZones returns a forward zone for the domain of every network, and a
+// reverse zone for every subnet, ordered by origin.  Forward zones contain
+// A and AAAA records for node addresses and named static reservations, and
+// CNAMEs for node aliases.  Reverse zones are cut on octet boundaries for
+// IPv4 and nibble boundaries for IPv6, and contain PTR records for every
+// address with a fully qualified name.
+func BuildZones(s *Snapshot, opts *ZoneOptions) ([]*Zone, error) {
+	if opts.PrimaryNS == "" {
+		return nil, fmt.Errorf("a primary name server is required")
+	}
+	options := opts.withDefaults()
+
+	zones := map[string]*Zone{}
+	zone := func(origin string, reverse bool) *Zone {
+		z, ok := zones[origin]
+		if !ok {
+			z = &Zone{Origin: origin, Reverse: reverse, Options: options, Records: []*Record{}}
+			zones[origin] = z
+		}
+		return z
+	}
+
+	reverseOrigins := []string{}
+	for _, network := range s.Networks {
+		if !contains(opts.Networks, network.Name) {
+			continue
+		}
+		if network.Domain != "" {
+			zone(absolute(network.Domain), false)
+		}
+		for _, subnet := range network.Subnets {
+			if subnet.Cidr == nil {
+				continue
+			}
+			origin := ReverseZone(subnet.Cidr)
+			if _, ok := zones[origin]; !ok {
+				reverseOrigins = append(reverseOrigins, origin)
+			}
+			zone(origin, true)
+		}
+	}
+	// longest origins first, so each PTR lands in the most specific zone
+	sort.Slice(reverseOrigins, func(a, b int) bool { return len(reverseOrigins[a]) > len(reverseOrigins[b]) })
+
+	add := func(network *types.Network, name string, ip net.IP) {
+		if network.Domain == "" || !validName(name) {
+			return
+		}
+		z := zones[absolute(network.Domain)]
+		rtype := RecordAAAA
+		if ip.To4() != nil {
+			rtype = RecordA
+		}
+		z.Records = append(z.Records, &Record{Name: name, Type: rtype, Data: ip.String(), ip: ip})
+		fqdn := z.FQDN(name)
+
+		ptr := ReverseName(ip)
+		for _, origin := range reverseOrigins {
+			if strings.HasSuffix(ptr, "."+origin) {
+				z := zones[origin]
+				z.Records = append(z.Records, &Record{Name: strings.TrimSuffix(ptr, "."+origin), Type: RecordPTR, Data: fqdn, ip: ip})
+				break
+			}
+		}
+	}
+
+	for _, a := range s.Addresses() {
+		if contains(opts.Networks, a.Network.Name) && a.Subnet != nil {
+			add(a.Network, a.Hostname, a.IP)
+		}
+	}
+	for _, r := range s.UnassignedReservations() {
+		network, _ := s.NetworkContaining(r.IP.IP)
+		if network != nil && contains(opts.Networks, network.Name) {
+			add(network, r.HostInformation, r.IP.IP)
+		}
+	}
+	addAliases(s, zones, opts)
+
+	result := make([]*Zone, 0, len(zones))
+	for _, z := range zones {
+		z.Records = append(z.nameServers(), uniqueRecords(z.Records)...)
+		result = append(result, z)
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].Origin < result[b].Origin })
+	return result, nil
+}
+
+// addAliases adds a CNAME for each node alias to every forward zone the node
+// has an address in.  Qualified aliases are only added to the zone they
+// belong to, and aliases that clash with other records are skipped.
+func addAliases(s *Snapshot, zones map[string]*Zone, opts *ZoneOptions) {
+	for _, node := range s.Nodes {
+		aliases := nodeAliases(node)
+		if len(aliases) == 0 {
+			continue
+		}
+
+		origins := map[string]bool{}
+		for _, nic := range node.Networks {
+			if nic == nil || !contains(opts.Networks, nic.Network.Name) {
+				continue
+			}
+			if network := s.Network(nic.Network.Name); network != nil && network.Domain != "" {
+				origins[absolute(network.Domain)] = true
+			}
+		}
+
+		for origin := range origins {
+			z := zones[origin]
+			for _, alias := range aliases {
+				name := alias
+				if strings.Contains(alias, ".") {
+					if !strings.HasSuffix(absolute(alias), "."+origin) {
+						continue
+					}
+					name = strings.TrimSuffix(absolute(alias), "."+origin)
+				}
+				if !validName(name) || z.hasName(name) {
+					continue
+				}
+				z.Records = append(z.Records, &Record{Name: name, Type: RecordCNAME, Data: node.Hostname})
+			}
+		}
+	}
+}
+
+// nodeAliases returns the aliases listed in a node's metadata
+func nodeAliases(node *types.InventoryNode) []string {
+	return metadataList(node.Metadata, ZoneAliasMetadataKey)
+}
+
+func (z *Zone) hasName(name string) bool {
+	for _, r := range z.Records {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (z *Zone) nameServers() []*Record {
+	records := []*Record{}
+	for _, ns := range z.Options.NameServers {
+		records = append(records, &Record{Name: "@", Type: RecordNS, Data: absolute(ns)})
+	}
+	return records
+}
+
+// uniqueRecords drops duplicate records and sorts the rest, by address for
+// PTR records and by name, type and data otherwise
+func uniqueRecords(records []*Record) []*Record {
+	seen := map[string]bool{}
+	result := []*Record{}
+	for _, r := range records {
+		key := r.Name + " " + r.Type + " " + r.Data
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		ra, rb := result[a], result[b]
+		if ra.Type == RecordPTR && rb.Type == RecordPTR {
+			return bytes.Compare(ra.ip.To16(), rb.ip.To16()) < 0
+		}
+		if ra.Name != rb.Name {
+			return ra.Name < rb.Name
+		}
+		if ra.Type != rb.Type {
+			return ra.Type < rb.Type
+		}
+		return ra.Data < rb.Data
+	})
+	return result
+}
+
+func (opts *ZoneOptions) withDefaults() ZoneOptions {
+	o := *opts
+	o.Networks = nil
+	o.PrimaryNS = absolute(o.PrimaryNS)
+	if o.Hostmaster == "" {
+		domain := o.PrimaryNS
+		if idx := strings.Index(domain, "."); idx >= 0 && idx < len(domain)-1 {
+			domain = domain[idx+1:]
+		}
+		o.Hostmaster = "hostmaster." + domain
+	}
+	o.Hostmaster = absolute(strings.Replace(o.Hostmaster, "@", ".", 1))
+	if len(o.NameServers) == 0 {
+		o.NameServers = []string{o.PrimaryNS}
+	}
+	for _, d := range []struct {
+		value *time.Duration
+		def   time.Duration
+	}{
+		{&o.TTL, DefaultZoneTTL},
+		{&o.Refresh, DefaultZoneRefresh},
+		{&o.Retry, DefaultZoneRetry},
+		{&o.Expire, DefaultZoneExpire},
+		{&o.Minimum, DefaultZoneMinimum},
+	} {
+		if *d.value <= 0 {
+			*d.value = d.def
+		}
+	}
+	return o
+}
+
+// absolute returns name with a trailing dot
+func absolute(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9_])?$`)
+
+// validName returns true if every label of name is a valid host name label
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if !labelPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReverseName returns the in-addr.arpa or ip6.arpa name of ip
+func ReverseName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", v4[3], v4[2], v4[1], v4[0])
+	}
+	return reverseNibbles(ip.To16(), 32) + "ip6.arpa."
+}
+
+// ReverseZone returns the reverse zone containing subnet, with the prefix
+// rounded down to an octet boundary for IPv4 and a nibble boundary for IPv6
+func ReverseZone(subnet *net.IPNet) string {
+	ones, _ := subnet.Mask.Size()
+	if v4 := subnet.IP.To4(); v4 != nil {
+		octets := ones / 8
+		if octets == 0 {
+			octets = 1
+		}
+		labels := []string{}
+		for idx := octets - 1; idx >= 0; idx-- {
+			labels = append(labels, strconv.Itoa(int(v4[idx])))
+		}
+		return strings.Join(labels, ".") + ".in-addr.arpa."
+	}
+
+	nibbles := ones / 4
+	if nibbles == 0 {
+		nibbles = 1
+	}
+	return reverseNibbles(subnet.IP.To16(), nibbles) + "ip6.arpa."
+}
+
+// reverseNibbles returns the first count nibbles of ip in reverse order,
+// each followed by a dot
+func reverseNibbles(ip net.IP, count int) string {
+	buf := &bytes.Buffer{}
+	for idx := count - 1; idx >= 0; idx-- {
+		b := ip[idx/2]
+		if idx%2 == 0 {
+			b >>= 4
+		}
+		fmt.Fprintf(buf, "%x.", b&0xf)
+	}
+	return buf.String()
+}
+
+// Render returns the zone in RFC 1035 master file format with the given
+// SOA serial
+func (z *Zone) Render(serial uint32) []byte {
+	o := z.Options
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "; Generated from the inventory, do not edit")
+	fmt.Fprintf(buf, "$ORIGIN %s\n", z.Origin)
+	fmt.Fprintf(buf, "$TTL %d\n", seconds(o.TTL))
+	fmt.Fprintf(buf, "@\tIN\tSOA\t%s %s (\n", o.PrimaryNS, o.Hostmaster)
+	fmt.Fprintf(buf, "\t%d ; serial\n", serial)
+	fmt.Fprintf(buf, "\t%d ; refresh\n", seconds(o.Refresh))
+	fmt.Fprintf(buf, "\t%d ; retry\n", seconds(o.Retry))
+	fmt.Fprintf(buf, "\t%d ; expire\n", seconds(o.Expire))
+	fmt.Fprintf(buf, "\t%d ; minimum\n", seconds(o.Minimum))
+	fmt.Fprintln(buf, ")")
+	for _, r := range z.Records {
+		fmt.Fprintf(buf, "%s\tIN\t%s\t%s\n", r.Name, r.Type, r.Data)
+	}
+	return buf.Bytes()
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+var serialPattern = regexp.MustCompile(`(?m)^\s*(\d+)\s*; serial$`)
+
+// ZoneSerial returns the SOA serial of a zone generated by Render
+func ZoneSerial(data []byte) (uint32, bool) {
+	match := serialPattern.FindSubmatch(data)
+	if match == nil {
+		return 0, false
+	}
+	serial, err := strconv.ParseUint(string(match[1]), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(serial), true
+}
+
+// NextSerial returns a date based serial of the form YYYYMMDDnn that is
+// greater than previous
+func NextSerial(previous uint32, now time.Time) uint32 {
+	now = now.UTC()
+	base := uint32(now.Year()*1000000 + int(now.Month())*10000 + now.Day()*100)
+	if previous < base {
+		return base
+	}
+	return previous + 1
+}
+
+// WriteZoneFile writes the zone to filename, keeping the existing serial if
+// no records have changed and otherwise advancing it.  It returns true if
+// the file was changed.
+func WriteZoneFile(filename string, z *Zone, now time.Time) (bool, error) {
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("unable to read existing zone: %v", err)
+	}
+
+	previous, ok := ZoneSerial(existing)
+	if ok && bytes.Equal(existing, z.Render(previous)) {
+		return false, nil
+	}
+	return WriteFileAtomic(filename, z.Render(NextSerial(previous, now)), 0644)
+}
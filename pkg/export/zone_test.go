@@ -0,0 +1,177 @@
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testZones(t *testing.T) map[string]*Zone {
+	s := testSnapshotWithReservations()
+	s.Nodes[0].Metadata = map[string]interface{}{ZoneAliasMetadataKey: []interface{}{"gpu01", "node-001", "www.other.example.com"}}
+	s.Nodes[1].Metadata = map[string]interface{}{ZoneAliasMetadataKey: "login, login.prov.example.com."}
+
+	zones, err := BuildZones(s, &ZoneOptions{PrimaryNS: "ns1.example.com"})
+	if err != nil {
+		t.Fatalf("unable to build zones: %v", err)
+	}
+
+	byOrigin := map[string]*Zone{}
+	origins := []string{}
+	for _, z := range zones {
+		byOrigin[z.Origin] = z
+		origins = append(origins, z.Origin)
+	}
+
+	expected := []string{"0.0.10.in-addr.arpa.", "0.1.10.in-addr.arpa.", "0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "prov.example.com."}
+	for _, origin := range expected {
+		if byOrigin[origin] == nil {
+			t.Errorf("missing zone %s, got %v", origin, origins)
+		}
+	}
+	if len(zones) != len(expected) {
+		t.Errorf("got unexpected zones: %v", origins)
+	}
+	return byOrigin
+}
+
+func recordStrings(z *Zone) []string {
+	records := []string{}
+	for _, r := range z.Records {
+		records = append(records, r.Name+" "+r.Type+" "+r.Data)
+	}
+	return records
+}
+
+func TestBuildZonesForward(t *testing.T) {
+	z := testZones(t)["prov.example.com."]
+
+	expected := []string{
+		"@ NS ns1.example.com.",
+		"gpu01 CNAME node-000",
+		"login CNAME node-001",
+		"node-000 A 10.0.0.10",
+		"node-000 AAAA 2001:db8::10",
+		"node-001 A 10.0.0.11",
+		"switch-01 A 10.0.0.20",
+	}
+	if got := recordStrings(z); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got unexpected records:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestBuildZonesReverse(t *testing.T) {
+	zones := testZones(t)
+
+	expected := []string{
+		"@ NS ns1.example.com.",
+		"10 PTR node-000.prov.example.com.",
+		"11 PTR node-001.prov.example.com.",
+		"20 PTR switch-01.prov.example.com.",
+	}
+	if got := recordStrings(zones["0.0.10.in-addr.arpa."]); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got unexpected records:\n%s", strings.Join(got, "\n"))
+	}
+
+	// the mgmt network has no domain, so there's nothing to point at
+	if got := recordStrings(zones["0.1.10.in-addr.arpa."]); len(got) != 1 {
+		t.Errorf("expected only an NS record, got %v", got)
+	}
+
+	v6 := zones["0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."]
+	if got := recordStrings(v6); len(got) != 2 || got[1] != "0.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0 PTR node-000.prov.example.com." {
+		t.Errorf("got unexpected records: %v", got)
+	}
+}
+
+func TestBuildZonesRequiresNS(t *testing.T) {
+	if _, err := BuildZones(testSnapshot(), &ZoneOptions{}); err == nil {
+		t.Errorf("expected an error without a primary name server")
+	}
+}
+
+func TestZoneRender(t *testing.T) {
+	z := testZones(t)["prov.example.com."]
+	zone := string(z.Render(2026101901))
+
+	expected := `; Generated from the inventory, do not edit
+$ORIGIN prov.example.com.
+$TTL 3600
+@	IN	SOA	ns1.example.com. hostmaster.example.com. (
+	2026101901 ; serial
+	3600 ; refresh
+	900 ; retry
+	1209600 ; expire
+	300 ; minimum
+)
+@	IN	NS	ns1.example.com.
+gpu01	IN	CNAME	node-000
+`
+	if !strings.HasPrefix(zone, expected) {
+		t.Errorf("got unexpected zone:\n%s", zone)
+	}
+
+	if serial, ok := ZoneSerial([]byte(zone)); !ok || serial != 2026101901 {
+		t.Errorf("unable to parse serial: %d %t", serial, ok)
+	}
+}
+
+func TestNextSerial(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		previous, expected uint32
+	}{
+		{0, 2026101900},
+		{2026010105, 2026101900},
+		{2026101900, 2026101901},
+		{2026101999, 2026102000},
+	} {
+		if got := NextSerial(tc.previous, now); got != tc.expected {
+			t.Errorf("got wrong serial after %d: expected %d, got %d", tc.previous, tc.expected, got)
+		}
+	}
+}
+
+func TestWriteZoneFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zone")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	z := testZones(t)["prov.example.com."]
+	filename := filepath.Join(dir, z.Filename())
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	serial := func() uint32 {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("unable to read zone: %v", err)
+		}
+		s, _ := ZoneSerial(data)
+		return s
+	}
+
+	if changed, err := WriteZoneFile(filename, z, now); err != nil || !changed {
+		t.Fatalf("expected new zone to be written: %t %v", changed, err)
+	}
+	if serial() != 2026101900 {
+		t.Errorf("got wrong initial serial: %d", serial())
+	}
+
+	if changed, err := WriteZoneFile(filename, z, now.Add(24*time.Hour)); err != nil || changed {
+		t.Errorf("expected unchanged zone to be left alone: %t %v", changed, err)
+	}
+
+	z.Records = z.Records[:len(z.Records)-1]
+	if changed, err := WriteZoneFile(filename, z, now); err != nil || !changed {
+		t.Fatalf("expected modified zone to be written: %t %v", changed, err)
+	}
+	if serial() != 2026101901 {
+		t.Errorf("serial wasn't advanced: %d", serial())
+	}
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/spf13/viper"
+)
+
+// Config describes a notifier and the inventory objects it watches
+type Config struct {
+	Profile        string
+	Webhooks       []*Webhook
+	MaxRetries     int           `mapstructure:"max_retries"`
+	RetryDelay     time.Duration `mapstructure:"retry_delay"`
+	DeadLetterFile string        `mapstructure:"dead_letter_file"`
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	Timeout        time.Duration
+	Kinds          []client.ObjectKind
+}
+
+// LoadConfig reads a notifier configuration file
+func LoadConfig(path string) (*Config, error) {
+	cfg := viper.New()
+	cfg.SetConfigFile(path)
+	cfg.SetDefault("max_retries", DefaultMaxRetries)
+	cfg.SetDefault("retry_delay", DefaultRetryDelay)
+	cfg.SetDefault("poll_interval", client.DefaultWatchInterval)
+	cfg.SetDefault("timeout", DefaultTimeout)
+
+	err := cfg.ReadInConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error reading notifier configuration file: %v", err)
+	}
+
+	notifyConfig := &Config{}
+	err = cfg.Unmarshal(notifyConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling notifier configuration: %v", err)
+	}
+
+	for _, webhook := range notifyConfig.Webhooks {
+		if webhook.URL == "" {
+			return nil, fmt.Errorf("all webhooks must specify a url")
+		}
+	}
+	return notifyConfig, nil
+}
+
+// Notifier returns a Notifier built from this configuration
+func (c *Config) Notifier() *Notifier {
+	return &Notifier{
+		Webhooks:       c.Webhooks,
+		MaxRetries:     c.MaxRetries,
+		RetryDelay:     c.RetryDelay,
+		DeadLetterFile: c.DeadLetterFile,
+		Timeout:        c.Timeout,
+	}
+}
+
+// Watcher returns a Watcher for the configured kinds
+func (c *Config) Watcher(inv *client.InventoryApi) *client.Watcher {
+	w := inv.Watch(c.Kinds...)
+	w.Interval = c.PollInterval
+	return w
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "notify.yml")
+	err = ioutil.WriteFile(configFile, []byte(`
+retry_delay: 5s
+dead_letter_file: /var/lib/inventory/deadletter.json
+kinds:
+  - node
+  - ipreservation
+webhooks:
+  - url: https://hooks.local/inventory
+    secret: secret
+    types:
+      - DELETED
+`), 0644)
+	if err != nil {
+		t.Fatalf("unable to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("unable to load config: %v", err)
+	}
+
+	if cfg.MaxRetries != DefaultMaxRetries || cfg.RetryDelay != 5*time.Second || cfg.Timeout != DefaultTimeout {
+		t.Errorf("retry settings not loaded correctly: %d, %s", cfg.MaxRetries, cfg.RetryDelay)
+	}
+
+	if len(cfg.Kinds) != 2 || cfg.Kinds[1] != client.KindIPReservation {
+		t.Errorf("kinds not loaded correctly: %v", cfg.Kinds)
+	}
+
+	if len(cfg.Webhooks) != 1 || cfg.Webhooks[0].Secret != "secret" || cfg.Webhooks[0].Types[0] != client.Deleted {
+		t.Errorf("webhooks not loaded correctly: %v", cfg.Webhooks)
+	}
+}
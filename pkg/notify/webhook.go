@@ -0,0 +1,350 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"gopkg.in/resty.v1"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body,
+	// prefixed with "sha256=".
+	SignatureHeader = "X-Inventory-Signature"
+
+	// EventHeader carries the event name, eg. "node.added"
+	EventHeader = "X-Inventory-Event"
+
+	DefaultMaxRetries = 3
+	DefaultRetryDelay = time.Second
+	DefaultTimeout    = 10 * time.Second
+)
+
+// Webhook describes a single notification endpoint
+type Webhook struct {
+	URL string
+
+	// Secret is used to sign each payload.  Payloads are unsigned if empty.
+	Secret string
+
+	// Kinds and Types filter the events sent to this webhook.  An empty list
+	// matches everything.
+	Kinds []client.ObjectKind
+	Types []client.EventType
+}
+
+// Matches returns true if the event should be delivered to this webhook
+func (w *Webhook) Matches(e client.Event) bool {
+	return matchKind(w.Kinds, e.Kind) && matchType(w.Types, e.Type)
+}
+
+func matchKind(kinds []client.ObjectKind, kind client.ObjectKind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func matchType(types []client.EventType, t client.EventType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, et := range types {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON document POSTed to each webhook
+type Payload struct {
+	Event     string            `json:"event"`
+	Type      client.EventType  `json:"type"`
+	Kind      client.ObjectKind `json:"kind"`
+	ID        string            `json:"id"`
+	Summary   string            `json:"summary"`
+	Changes   []string          `json:"changes,omitempty"`
+	Old       interface{}       `json:"old,omitempty"`
+	New       interface{}       `json:"new,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// NewPayload builds a payload describing the event
+func NewPayload(e client.Event) (*Payload, error) {
+	p := &Payload{
+		Event:     EventName(e),
+		Type:      e.Type,
+		Kind:      e.Kind,
+		ID:        e.ID,
+		Old:       e.Old,
+		New:       e.New,
+		Timestamp: time.Now().UTC(),
+	}
+
+	if e.Type == client.Modified {
+		changes, err := ChangedFields(e.Old, e.New)
+		if err != nil {
+			return nil, err
+		}
+		p.Changes = changes
+	}
+
+	p.Summary = fmt.Sprintf("%s %s %s", e.Kind, e.ID, strings.ToLower(string(e.Type)))
+	if len(p.Changes) > 0 {
+		p.Summary = fmt.Sprintf("%s: %s", p.Summary, strings.Join(p.Changes, ", "))
+	}
+	return p, nil
+}
+
+// EventName returns a short dotted name for the event, eg. "network.modified"
+func EventName(e client.Event) string {
+	return fmt.Sprintf("%s.%s", e.Kind, strings.ToLower(string(e.Type)))
+}
+
+// ChangedFields returns the sorted names of the top level JSON fields that
+// differ between old and new.
+func ChangedFields(old, new interface{}) ([]string, error) {
+	oldFields, err := jsonFields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := jsonFields(new)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for k, v := range newFields {
+		if !reflect.DeepEqual(oldFields[k], v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range oldFields {
+		if _, ok := newFields[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+func jsonFields(obj interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if obj == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal object: %v", err)
+	}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal object fields: %v", err)
+	}
+	return fields, nil
+}
+
+// Sign returns the value of the signature header for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a signature header value against the body
+func VerifySignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Notifier delivers inventory change events to webhooks
+type Notifier struct {
+	Webhooks []*Webhook
+
+	// MaxRetries is the number of additional attempts made after a failed
+	// delivery.  RetryDelay doubles after each attempt.
+	MaxRetries int
+	RetryDelay time.Duration
+
+	// DeadLetterFile, if set, receives one JSON line for every delivery that
+	// fails after all retries.
+	DeadLetterFile string
+
+	// Timeout limits each request to a webhook, DefaultTimeout if zero
+	Timeout time.Duration
+
+	deadLetterLock sync.Mutex
+	clientOnce     sync.Once
+	client         *resty.Client
+}
+
+// NewNotifier returns a notifier with default retry settings
+func NewNotifier(webhooks ...*Webhook) *Notifier {
+	return &Notifier{Webhooks: webhooks, MaxRetries: DefaultMaxRetries, RetryDelay: DefaultRetryDelay, Timeout: DefaultTimeout}
+}
+
+// DeadLetter is written to the dead letter file for each failed delivery
+type DeadLetter struct {
+	URL     string    `json:"url"`
+	Error   string    `json:"error"`
+	Time    time.Time `json:"time"`
+	Payload *Payload  `json:"payload"`
+}
+
+// Run delivers events until the channel is closed or ctx is cancelled.  Watch
+// errors are logged rather than delivered, and the Added events of the
+// watcher's initial sync are dropped so a restart doesn't resend every object.
+func (n *Notifier) Run(ctx context.Context, events <-chan client.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type == client.WatchError {
+				log.Printf("error watching %s: %v", e.Kind, e.Err)
+				continue
+			}
+			if e.Initial {
+				continue
+			}
+			if err := n.Notify(ctx, e); err != nil {
+				log.Printf("unable to deliver %s %s: %v", EventName(e), e.ID, err)
+			}
+		}
+	}
+}
+
+// Notify delivers the event to every matching webhook.  Deliveries that still
+// fail after all retries are written to the dead letter file if one is
+// configured, otherwise they are reported in the returned error.
+func (n *Notifier) Notify(ctx context.Context, e client.Event) error {
+	payload, err := NewPayload(e)
+	if err != nil {
+		return fmt.Errorf("unable to build payload: %v", err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal payload: %v", err)
+	}
+
+	failures := []string{}
+	for _, webhook := range n.Webhooks {
+		if !webhook.Matches(e) {
+			continue
+		}
+
+		deliveryErr := n.deliver(ctx, webhook, payload.Event, body)
+		if deliveryErr == nil {
+			continue
+		}
+
+		if n.DeadLetterFile == "" {
+			failures = append(failures, fmt.Sprintf("%s: %v", webhook.URL, deliveryErr))
+			continue
+		}
+
+		err = n.deadLetter(&DeadLetter{URL: webhook.URL, Error: deliveryErr.Error(), Time: time.Now().UTC(), Payload: payload})
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v (unable to write dead letter: %v)", webhook.URL, deliveryErr, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("delivery failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (n *Notifier) deliver(ctx context.Context, webhook *Webhook, event string, body []byte) error {
+	delay := n.RetryDelay
+	var err error
+	for attempt := 0; attempt <= n.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		err = n.post(ctx, webhook, event, body)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// httpClient returns the client shared by every delivery
+func (n *Notifier) httpClient() *resty.Client {
+	n.clientOnce.Do(func() {
+		timeout := n.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		n.client = resty.New().SetTimeout(timeout)
+	})
+	return n.client
+}
+
+func (n *Notifier) post(ctx context.Context, webhook *Webhook, event string, body []byte) error {
+	request := n.httpClient().R().SetContext(ctx)
+	request.SetHeader("Content-Type", "application/json")
+	request.SetHeader(EventHeader, event)
+	if webhook.Secret != "" {
+		request.SetHeader(SignatureHeader, Sign(webhook.Secret, body))
+	}
+	request.SetBody(body)
+
+	response, err := request.Execute(http.MethodPost, webhook.URL)
+	if err != nil {
+		return fmt.Errorf("unable to post to webhook: %v", err)
+	}
+
+	if response.StatusCode() < 200 || response.StatusCode() >= 300 {
+		return fmt.Errorf("webhook returned unexpected status: %s", response.Status())
+	}
+	return nil
+}
+
+func (n *Notifier) deadLetter(letter *DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	n.deadLetterLock.Lock()
+	defer n.deadLetterLock.Unlock()
+
+	f, err := os.OpenFile(n.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func TestChangedFields(t *testing.T) {
+	old := &types.Network{Name: "testnet", MTU: 1500}
+	new := &types.Network{Name: "testnet", MTU: 9000, Domain: "example.com"}
+
+	changes, err := ChangedFields(old, new)
+	if err != nil {
+		t.Fatalf("unable to compute changes: %v", err)
+	}
+
+	expected := []string{"Domain", "MTU"}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("got wrong changes: expected %v, got %v", expected, changes)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"event":"node.added"}`)
+	signature := Sign("secret", body)
+	if !VerifySignature("secret", body, signature) {
+		t.Errorf("unable to verify signature")
+	}
+	if VerifySignature("other", body, signature) {
+		t.Errorf("signature verified with the wrong secret")
+	}
+}
+
+func TestWebhookMatches(t *testing.T) {
+	w := &Webhook{Kinds: []client.ObjectKind{client.KindIPReservation}, Types: []client.EventType{client.Deleted}}
+
+	if !w.Matches(client.Event{Type: client.Deleted, Kind: client.KindIPReservation}) {
+		t.Errorf("expected reservation deletion to match")
+	}
+	if w.Matches(client.Event{Type: client.Added, Kind: client.KindIPReservation}) {
+		t.Errorf("expected reservation creation not to match")
+	}
+	if w.Matches(client.Event{Type: client.Deleted, Kind: client.KindNode}) {
+		t.Errorf("expected node deletion not to match")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	e := client.Event{Type: client.Modified, Kind: client.KindNetwork, ID: "testnet",
+		Old: &types.Network{Name: "testnet", MTU: 1500},
+		New: &types.Network{Name: "testnet", MTU: 9000},
+	}
+
+	received := make(chan *Payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(EventHeader) != "network.modified" || !VerifySignature("secret", body, r.Header.Get(SignatureHeader)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payload := &Payload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	n := NewNotifier(&Webhook{URL: server.URL, Secret: "secret"})
+	n.MaxRetries = 0
+	err := n.Notify(context.Background(), e)
+	if err != nil {
+		t.Fatalf("unable to notify: %v", err)
+	}
+
+	payload := <-received
+	if !reflect.DeepEqual(payload.Changes, []string{"MTU"}) {
+		t.Errorf("got wrong changes: %v", payload.Changes)
+	}
+	if payload.Summary != "network testnet modified: MTU" {
+		t.Errorf("got wrong summary: %s", payload.Summary)
+	}
+}
+
+func TestNotifyDeadLetter(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	gock.New("https://hooks.local").Post("/inventory").Times(2).Reply(http.StatusInternalServerError)
+
+	n := NewNotifier(&Webhook{URL: "https://hooks.local/inventory"})
+	n.MaxRetries = 1
+	n.RetryDelay = 0
+	n.DeadLetterFile = filepath.Join(dir, "deadletter.json")
+
+	err = n.Notify(context.Background(), client.Event{Type: client.Added, Kind: client.KindNode, ID: "test-000", New: &types.Node{InventoryID: "test-000"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("webhook not retried: %d pending", len(gock.Pending()))
+	}
+
+	data, err := ioutil.ReadFile(n.DeadLetterFile)
+	if err != nil {
+		t.Fatalf("unable to read dead letter file: %v", err)
+	}
+
+	letter := &DeadLetter{}
+	err = json.Unmarshal(data, letter)
+	if err != nil {
+		t.Fatalf("unable to unmarshal dead letter: %v", err)
+	}
+
+	if letter.URL != "https://hooks.local/inventory" || letter.Payload.ID != "test-000" {
+		t.Errorf("dead letter doesn't match failed delivery: %v", letter)
+	}
+}
+
+func TestRunSkipsInitialEvents(t *testing.T) {
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(EventHeader)
+	}))
+	defer server.Close()
+
+	events := make(chan client.Event, 2)
+	events <- client.Event{Type: client.Added, Kind: client.KindNode, ID: "test-000", New: &types.Node{InventoryID: "test-000"}, Initial: true}
+	events <- client.Event{Type: client.Deleted, Kind: client.KindNode, ID: "test-000", Old: &types.Node{InventoryID: "test-000"}}
+	close(events)
+
+	n := NewNotifier(&Webhook{URL: server.URL})
+	n.Run(context.Background(), events)
+
+	close(received)
+	names := []string{}
+	for name := range received {
+		names = append(names, name)
+	}
+	if !reflect.DeepEqual(names, []string{"node.deleted"}) {
+		t.Errorf("got unexpected deliveries: %v", names)
+	}
+}
+
+func TestNotifyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	n := NewNotifier(&Webhook{URL: server.URL})
+	n.MaxRetries = 0
+	n.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	err := n.Notify(context.Background(), client.Event{Type: client.Deleted, Kind: client.KindNode, ID: "test-000", Old: &types.Node{InventoryID: "test-000"}})
+	if err == nil {
+		t.Errorf("expected delivery to a hung webhook to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("delivery wasn't limited by the timeout: %s", elapsed)
+	}
+}
@@ -0,0 +1,410 @@
+package printer
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// Column describes a single field of an object shown in table and csv output
+type Column struct {
+	// Name is used to select the column, eg. "hostname"
+	Name   string
+	Header string
+
+	// Wide columns are only shown by default in wide output
+	Wide  bool
+	Value func(obj interface{}) string
+}
+
+var (
+	NodeColumns = []*Column{
+		nodeColumn("hostname", "HOSTNAME", false, func(n *types.Node) string { return n.Hostname() }),
+		nodeColumn("inventoryid", "INVENTORY ID", false, func(n *types.Node) string { return n.InventoryID }),
+		nodeColumn("system", "SYSTEM", false, func(n *types.Node) string { return n.System }),
+		nodeColumn("role", "ROLE", false, func(n *types.Node) string { return n.Role }),
+		nodeColumn("environment", "ENVIRONMENT", true, func(n *types.Node) string { return n.Environment }),
+		nodeColumn("location", "LOCATION", false, func(n *types.Node) string { return n.Location() }),
+		nodeColumn("macs", "MACS", true, func(n *types.Node) string { return join(nodeMACs(n)) }),
+		nodeColumn("tags", "TAGS", true, func(n *types.Node) string { return join(n.Tags) }),
+	}
+
+	InventoryNodeColumns = []*Column{
+		inventoryNodeColumn("hostname", "HOSTNAME", false, func(n *types.InventoryNode) string { return n.Hostname }),
+		inventoryNodeColumn("inventoryid", "INVENTORY ID", false, func(n *types.InventoryNode) string { return n.InventoryID }),
+		inventoryNodeColumn("system", "SYSTEM", false, func(n *types.InventoryNode) string {
+			if n.System == nil {
+				return ""
+			}
+			return n.System.ID()
+		}),
+		inventoryNodeColumn("role", "ROLE", false, func(n *types.InventoryNode) string { return n.Role }),
+		inventoryNodeColumn("location", "LOCATION", false, func(n *types.InventoryNode) string { return n.LocationString }),
+		inventoryNodeColumn("macs", "MACS", true, func(n *types.InventoryNode) string { return join(inventoryNodeMACs(n)) }),
+		inventoryNodeColumn("ips", "IPS", false, func(n *types.InventoryNode) string { return join(inventoryNodeIPs(n)) }),
+		inventoryNodeColumn("tags", "TAGS", true, func(n *types.InventoryNode) string { return join(n.Tags) }),
+	}
+
+	NetworkColumns = []*Column{
+		networkColumn("name", "NAME", false, func(n *types.Network) string { return n.Name }),
+		networkColumn("mtu", "MTU", false, func(n *types.Network) string { return fmt.Sprintf("%d", n.MTU) }),
+		networkColumn("domain", "DOMAIN", false, func(n *types.Network) string { return n.Domain }),
+		networkColumn("subnets", "SUBNETS", false, func(n *types.Network) string {
+			subnets := []string{}
+			for _, s := range n.Subnets {
+				if s.Cidr != nil {
+					subnets = append(subnets, s.Cidr.String())
+				}
+			}
+			return join(subnets)
+		}),
+		networkColumn("gateways", "GATEWAYS", true, func(n *types.Network) string {
+			gateways := []string{}
+			for _, s := range n.Subnets {
+				if s.Gateway != nil {
+					gateways = append(gateways, s.Gateway.String())
+				}
+			}
+			return join(gateways)
+		}),
+	}
+
+	SystemColumns = []*Column{
+		systemColumn("name", "NAME", false, func(s *types.System) string { return s.Name }),
+		systemColumn("shortname", "SHORT NAME", false, func(s *types.System) string { return s.ShortName }),
+		systemColumn("roles", "ROLES", false, func(s *types.System) string { return join(s.Roles) }),
+		systemColumn("environments", "ENVIRONMENTS", true, func(s *types.System) string {
+			environments := make([]string, 0, len(s.Environments))
+			for name := range s.Environments {
+				environments = append(environments, name)
+			}
+			sort.Strings(environments)
+			return join(environments)
+		}),
+	}
+
+	IPReservationColumns = []*Column{
+		reservationColumn("ip", "IP", false, func(r *types.IPReservation) string {
+			if r.IP == nil {
+				return ""
+			}
+			return r.IP.String()
+		}),
+		reservationColumn("mac", "MAC", false, func(r *types.IPReservation) string { return r.MAC.String() }),
+		reservationColumn("hostinformation", "HOST", false, func(r *types.IPReservation) string { return r.HostInformation }),
+		reservationColumn("type", "TYPE", false, func(r *types.IPReservation) string {
+			if r.Static() {
+				return "static"
+			}
+			return "dynamic"
+		}),
+		reservationColumn("start", "START", true, func(r *types.IPReservation) string { return formatTime(r.Start) }),
+		reservationColumn("end", "END", false, func(r *types.IPReservation) string { return formatTime(r.End) }),
+		reservationColumn("gateway", "GATEWAY", true, func(r *types.IPReservation) string {
+			if r.Gateway == nil {
+				return ""
+			}
+			return r.Gateway.String()
+		}),
+		reservationColumn("dns", "DNS", true, func(r *types.IPReservation) string { return join(ipStrings(r.DNS)) }),
+	}
+
+	SubnetUsageColumns = []*Column{
+		usageColumn("network", "NETWORK", false, func(u *client.SubnetUsage) string { return u.Network }),
+		usageColumn("subnet", "SUBNET", true, func(u *client.SubnetUsage) string { return u.Subnet }),
+		usageColumn("cidr", "CIDR", false, func(u *client.SubnetUsage) string { return u.Cidr }),
+		usageColumn("total", "TOTAL", false, func(u *client.SubnetUsage) string { return fmt.Sprintf("%d", u.Total) }),
+		usageColumn("reserved", "RESERVED", false, func(u *client.SubnetUsage) string { return fmt.Sprintf("%d", u.Reserved) }),
+		usageColumn("static", "STATIC", true, func(u *client.SubnetUsage) string { return fmt.Sprintf("%d", u.Static) }),
+		usageColumn("dynamic", "DYNAMIC", false, func(u *client.SubnetUsage) string { return fmt.Sprintf("%d", u.Dynamic) }),
+		usageColumn("expired", "EXPIRED", false, func(u *client.SubnetUsage) string { return fmt.Sprintf("%d", u.Expired) }),
+		usageColumn("free", "FREE", false, func(u *client.SubnetUsage) string { return fmt.Sprintf("%d", u.Free) }),
+		usageColumn("utilization", "USED", false, func(u *client.SubnetUsage) string { return fmt.Sprintf("%.1f%%", u.Utilization) }),
+		usageColumn("overthreshold", "OVER", false, func(u *client.SubnetUsage) string {
+			if u.OverThreshold {
+				return "yes"
+			}
+			return ""
+		}),
+		usageColumn("nextfree", "NEXT FREE", true, func(u *client.SubnetUsage) string { return join(ipStrings(u.NextFree)) }),
+	}
+
+	GCCandidateColumns = []*Column{
+		gcColumn("ip", "IP", false, func(c *client.GCCandidate) string {
+			if c.Reservation.IP == nil {
+				return ""
+			}
+			return c.Reservation.IP.String()
+		}),
+		gcColumn("mac", "MAC", false, func(c *client.GCCandidate) string { return c.Reservation.MAC.String() }),
+		gcColumn("hostinformation", "HOST", true, func(c *client.GCCandidate) string { return c.Reservation.HostInformation }),
+		gcColumn("reason", "REASON", false, func(c *client.GCCandidate) string { return string(c.Reason) }),
+		gcColumn("end", "END", false, func(c *client.GCCandidate) string { return formatTime(c.Reservation.End) }),
+		gcColumn("status", "STATUS", false, func(c *client.GCCandidate) string {
+			switch {
+			case c.Deleted:
+				return "deleted"
+			case c.Error != "":
+				return "failed: " + c.Error
+			}
+			return "pending"
+		}),
+	}
+
+	ReconcileFindingColumns = []*Column{
+		findingColumn("status", "STATUS", false, func(f *client.ReconcileFinding) string { return string(f.Status) }),
+		findingColumn("node", "NODE", false, func(f *client.ReconcileFinding) string { return f.Node }),
+		findingColumn("network", "NETWORK", false, func(f *client.ReconcileFinding) string { return f.Network }),
+		findingColumn("subnet", "SUBNET", true, func(f *client.ReconcileFinding) string { return f.Subnet }),
+		findingColumn("mac", "MAC", false, func(f *client.ReconcileFinding) string { return f.MAC }),
+		findingColumn("expected", "EXPECTED", false, func(f *client.ReconcileFinding) string {
+			if f.ExpectedIP == nil {
+				return ""
+			}
+			return f.ExpectedIP.String()
+		}),
+		findingColumn("reservation", "RESERVATION", false, func(f *client.ReconcileFinding) string {
+			if f.Reservation == nil || f.Reservation.IP == nil {
+				return ""
+			}
+			return f.Reservation.IP.String()
+		}),
+		findingColumn("result", "RESULT", false, func(f *client.ReconcileFinding) string {
+			switch {
+			case f.Fixed:
+				return "fixed"
+			case f.Error != "":
+				return "failed: " + f.Error
+			case f.Skipped != "":
+				return "skipped: " + f.Skipped
+			}
+			return ""
+		}),
+	}
+)
+
+func nodeColumn(name, header string, wide bool, f func(*types.Node) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*types.Node)) }}
+}
+
+func inventoryNodeColumn(name, header string, wide bool, f func(*types.InventoryNode) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*types.InventoryNode)) }}
+}
+
+func networkColumn(name, header string, wide bool, f func(*types.Network) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*types.Network)) }}
+}
+
+func systemColumn(name, header string, wide bool, f func(*types.System) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*types.System)) }}
+}
+
+func reservationColumn(name, header string, wide bool, f func(*types.IPReservation) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*types.IPReservation)) }}
+}
+
+func usageColumn(name, header string, wide bool, f func(*client.SubnetUsage) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*client.SubnetUsage)) }}
+}
+
+func gcColumn(name, header string, wide bool, f func(*client.GCCandidate) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*client.GCCandidate)) }}
+}
+
+func findingColumn(name, header string, wide bool, f func(*client.ReconcileFinding) string) *Column {
+	return &Column{Name: name, Header: header, Wide: wide, Value: func(obj interface{}) string { return f(obj.(*client.ReconcileFinding)) }}
+}
+
+// rows returns the individual objects to print along with their columns
+func rows(obj interface{}) ([]interface{}, []*Column, error) {
+	items := []interface{}{}
+	switch o := obj.(type) {
+	case *types.Node:
+		return append(items, o), NodeColumns, nil
+	case []*types.Node:
+		for _, n := range o {
+			items = append(items, n)
+		}
+		return items, NodeColumns, nil
+	case *types.InventoryNode:
+		return append(items, o), InventoryNodeColumns, nil
+	case []*types.InventoryNode:
+		for _, n := range o {
+			items = append(items, n)
+		}
+		return items, InventoryNodeColumns, nil
+	case *types.Network:
+		return append(items, o), NetworkColumns, nil
+	case []*types.Network:
+		for _, n := range o {
+			items = append(items, n)
+		}
+		return items, NetworkColumns, nil
+	case *types.System:
+		return append(items, o), SystemColumns, nil
+	case []*types.System:
+		for _, s := range o {
+			items = append(items, s)
+		}
+		return items, SystemColumns, nil
+	case *types.IPReservation:
+		return append(items, o), IPReservationColumns, nil
+	case types.IPReservationList:
+		for _, r := range o {
+			items = append(items, r)
+		}
+		return items, IPReservationColumns, nil
+	case *client.SubnetUsage:
+		return append(items, o), SubnetUsageColumns, nil
+	case []*client.SubnetUsage:
+		for _, u := range o {
+			items = append(items, u)
+		}
+		return items, SubnetUsageColumns, nil
+	case *client.UtilizationReport:
+		return rows(o.Subnets)
+	case []*client.GCCandidate:
+		for _, c := range o {
+			items = append(items, c)
+		}
+		return items, GCCandidateColumns, nil
+	case *client.GCReport:
+		return rows(o.Candidates)
+	case []*client.ReconcileFinding:
+		for _, f := range o {
+			items = append(items, f)
+		}
+		return items, ReconcileFindingColumns, nil
+	case *client.ReconcileReport:
+		return rows(o.Findings)
+	default:
+		return nil, nil, fmt.Errorf("unable to print objects of type %T as a table", obj)
+	}
+}
+
+// selectColumns picks the named columns from those available.  If no names are
+// given the default columns are returned, including wide columns if requested.
+func selectColumns(available []*Column, names []string, wide bool) ([]*Column, error) {
+	if len(names) == 0 {
+		selected := []*Column{}
+		for _, c := range available {
+			if wide || !c.Wide {
+				selected = append(selected, c)
+			}
+		}
+		return selected, nil
+	}
+
+	selected := make([]*Column, 0, len(names))
+	for _, name := range names {
+		c := findColumn(available, name)
+		if c == nil {
+			valid := make([]string, 0, len(available))
+			for _, c := range available {
+				valid = append(valid, c.Name)
+			}
+			return nil, fmt.Errorf("unknown column '%s', valid columns are: %s", name, strings.Join(valid, ", "))
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+func findColumn(available []*Column, name string) *Column {
+	name = normalizeColumnName(name)
+	for _, c := range available {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func normalizeColumnName(name string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(name))
+}
+
+func join(values []string) string {
+	return strings.Join(values, ",")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func ipStrings(ips []net.IP) []string {
+	result := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		result = append(result, ip.String())
+	}
+	return result
+}
+
+func nodeMACs(n *types.Node) []string {
+	networks := make([]string, 0, len(n.Networks))
+	for name := range n.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+
+	macs := []string{}
+	for _, name := range networks {
+		if n.Networks[name] == nil {
+			continue
+		}
+		for _, mac := range n.Networks[name].NICs {
+			macs = append(macs, mac.String())
+		}
+	}
+	return macs
+}
+
+func sortedNICInstances(n *types.InventoryNode) []*types.NICInstance {
+	networks := make([]string, 0, len(n.Networks))
+	for name := range n.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+
+	instances := make([]*types.NICInstance, 0, len(networks))
+	for _, name := range networks {
+		if n.Networks[name] != nil {
+			instances = append(instances, n.Networks[name])
+		}
+	}
+	return instances
+}
+
+func inventoryNodeMACs(n *types.InventoryNode) []string {
+	macs := []string{}
+	for _, nic := range sortedNICInstances(n) {
+		for _, mac := range nic.Interface.NICs {
+			macs = append(macs, mac.String())
+		}
+	}
+	return macs
+}
+
+// inventoryNodeIPs returns the configured addresses of the node without their
+// prefix lengths
+func inventoryNodeIPs(n *types.InventoryNode) []string {
+	ips := []string{}
+	for _, nic := range sortedNICInstances(n) {
+		for _, cidr := range nic.Config.IP {
+			if ip, _, err := net.ParseCIDR(cidr); err == nil {
+				ips = append(ips, ip.String())
+			} else {
+				ips = append(ips, cidr)
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,290 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a parsed JSONPath template, eg. "{range [*]}{.InventoryID}{'\n'}{end}".
+// Expressions are evaluated against the JSON representation of an object.
+// Supported expressions are field access (.field or ['field']), array
+// indexing ([n], negative indexes count from the end), wildcards ([*] or .*),
+// the current object (@ or .), quoted string literals and range/end blocks.
+type JSONPath struct {
+	nodes []jsonPathNode
+}
+
+type jsonPathNode struct {
+	text     string
+	path     []pathSegment
+	isPath   bool
+	children []jsonPathNode
+	isRange  bool
+}
+
+type pathSegment struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// ParseJSONPath parses a JSONPath template
+func ParseJSONPath(template string) (*JSONPath, error) {
+	nodes, _, closed, err := parseJSONPathNodes(template)
+	if err != nil {
+		return nil, err
+	}
+	if closed {
+		return nil, fmt.Errorf("unexpected {end} in jsonpath template")
+	}
+	return &JSONPath{nodes: nodes}, nil
+}
+
+// parseJSONPathNodes parses template until the end of the string or an {end}.
+// If an {end} was found closed is set and the unparsed remainder is returned.
+func parseJSONPathNodes(template string) (nodes []jsonPathNode, rest string, closed bool, err error) {
+	nodes = []jsonPathNode{}
+	for len(template) > 0 {
+		open := strings.Index(template, "{")
+		if open < 0 {
+			nodes = append(nodes, jsonPathNode{text: template})
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jsonPathNode{text: template[:open]})
+		}
+
+		end := indexUnquoted(template[open:], '}')
+		if end < 0 {
+			return nil, "", false, fmt.Errorf("unclosed action in jsonpath template")
+		}
+		expr := strings.TrimSpace(template[open+1 : open+end])
+		template = template[open+end+1:]
+
+		switch {
+		case expr == "end":
+			return nodes, template, true, nil
+		case strings.HasPrefix(expr, "range "):
+			path, err := parsePath(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, "", false, err
+			}
+			children, rest, closed, err := parseJSONPathNodes(template)
+			if err != nil {
+				return nil, "", false, err
+			}
+			if !closed {
+				return nil, "", false, fmt.Errorf("range without matching {end} in jsonpath template")
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isRange: true, children: children})
+			template = rest
+		case len(expr) >= 2 && (expr[0] == '\'' || expr[0] == '"') && expr[len(expr)-1] == expr[0]:
+			text, err := strconv.Unquote(`"` + strings.Replace(expr[1:len(expr)-1], `"`, `\"`, -1) + `"`)
+			if err != nil {
+				return nil, "", false, fmt.Errorf("invalid string literal %s: %v", expr, err)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+		default:
+			path, err := parsePath(expr)
+			if err != nil {
+				return nil, "", false, err
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isPath: true})
+		}
+	}
+
+	return nodes, "", false, nil
+}
+
+// indexUnquoted returns the index of the first c in s that isn't inside a
+// quoted string, or -1 if there is none
+func indexUnquoted(s string, c byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == '\\':
+			i++
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+func parsePath(expr string) ([]pathSegment, error) {
+	expr = strings.TrimPrefix(expr, "$")
+	expr = strings.TrimPrefix(expr, "@")
+
+	segments := []pathSegment{}
+	for len(expr) > 0 {
+		switch expr[0] {
+		case '.':
+			expr = expr[1:]
+			end := strings.IndexAny(expr, ".[")
+			if end < 0 {
+				end = len(expr)
+			}
+			field := expr[:end]
+			expr = expr[end:]
+			switch field {
+			case "":
+				continue
+			case "*":
+				segments = append(segments, pathSegment{wildcard: true})
+			default:
+				segments = append(segments, pathSegment{field: field})
+			}
+		case '[':
+			end := indexUnquoted(expr, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in jsonpath expression")
+			}
+			selector := strings.TrimSpace(expr[1:end])
+			expr = expr[end+1:]
+			switch {
+			case selector == "*":
+				segments = append(segments, pathSegment{wildcard: true})
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				segments = append(segments, pathSegment{field: selector[1 : len(selector)-1]})
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("invalid array index '%s' in jsonpath expression", selector)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected '%c' in jsonpath expression", expr[0])
+		}
+	}
+	return segments, nil
+}
+
+// Execute renders the template against obj
+func (j *JSONPath) Execute(w io.Writer, obj interface{}) error {
+	generic, err := toGeneric(obj)
+	if err != nil {
+		return err
+	}
+	return executeJSONPathNodes(w, j.nodes, generic)
+}
+
+func executeJSONPathNodes(w io.Writer, nodes []jsonPathNode, current interface{}) error {
+	for _, node := range nodes {
+		switch {
+		case node.isRange:
+			values, err := evalPath(node.path, current)
+			if err != nil {
+				return err
+			}
+			if len(values) == 1 {
+				if l, ok := values[0].([]interface{}); ok {
+					values = l
+				}
+			}
+			for _, v := range values {
+				if err := executeJSONPathNodes(w, node.children, v); err != nil {
+					return err
+				}
+			}
+		case node.isPath:
+			values, err := evalPath(node.path, current)
+			if err != nil {
+				return err
+			}
+			formatted := make([]string, 0, len(values))
+			for _, v := range values {
+				s, err := formatValue(v)
+				if err != nil {
+					return err
+				}
+				formatted = append(formatted, s)
+			}
+			if _, err := io.WriteString(w, strings.Join(formatted, " ")); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(w, node.text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func evalPath(path []pathSegment, current interface{}) ([]interface{}, error) {
+	values := []interface{}{current}
+	for _, segment := range path {
+		next := []interface{}{}
+		for _, v := range values {
+			switch {
+			case segment.wildcard:
+				switch value := v.(type) {
+				case []interface{}:
+					next = append(next, value...)
+				case map[string]interface{}:
+					keys := make([]string, 0, len(value))
+					for k := range value {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, value[k])
+					}
+				}
+			case segment.isIndex:
+				l, ok := v.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("unable to index non-array value")
+				}
+				index := segment.index
+				if index < 0 {
+					index += len(l)
+				}
+				if index < 0 || index >= len(l) {
+					return nil, fmt.Errorf("array index %d out of range", segment.index)
+				}
+				next = append(next, l[index])
+			default:
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("unable to find field '%s' in non-object value", segment.field)
+				}
+				value, ok := m[segment.field]
+				if !ok {
+					return nil, fmt.Errorf("field '%s' not found", segment.field)
+				}
+				next = append(next, value)
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func formatValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		return string(data), err
+	default:
+		return fmt.Sprintf("%v", value), nil
+	}
+}
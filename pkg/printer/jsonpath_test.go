@@ -0,0 +1,64 @@
+package printer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	obj := map[string]interface{}{
+		"Name": "testnet",
+		"Subnets": []interface{}{
+			map[string]interface{}{"Cidr": "10.0.0.0/24", "DNS": []interface{}{"10.0.0.2", "10.0.0.3"}},
+			map[string]interface{}{"Cidr": "10.0.1.0/24"},
+		},
+		"Metadata": map[string]interface{}{"vlan": 100, "site": "a", "a}b": "brace", "size": 1000000, "ratio": 0.25},
+	}
+
+	tests := map[string]string{
+		`{.Name}`:                          "testnet",
+		`name={$.Name}`:                    "name=testnet",
+		`{.Subnets[*].Cidr}`:               "10.0.0.0/24 10.0.1.0/24",
+		`{.Subnets[-1].Cidr}`:              "10.0.1.0/24",
+		`{.Subnets[0].DNS}`:                `["10.0.0.2","10.0.0.3"]`,
+		`{.Metadata['vlan']}`:              "100",
+		`{.Metadata.*}`:                    "brace 0.25 a 1000000 100",
+		`{.Metadata['a}b']}`:               "brace",
+		`{.Metadata.size}`:                 "1000000",
+		`{'}'}{"{"}`:                       "}{",
+		`{range .Subnets[*]}{.Cidr};{end}`: "10.0.0.0/24;10.0.1.0/24;",
+		`{range .Subnets}[{@.Cidr}]{end}`:  "[10.0.0.0/24][10.0.1.0/24]",
+	}
+
+	for template, expected := range tests {
+		j, err := ParseJSONPath(template)
+		if err != nil {
+			t.Errorf("unable to parse %s: %v", template, err)
+			continue
+		}
+
+		buf := &bytes.Buffer{}
+		err = j.Execute(buf, obj)
+		if err != nil {
+			t.Errorf("unable to execute %s: %v", template, err)
+			continue
+		}
+
+		if buf.String() != expected {
+			t.Errorf("got wrong output for %s: expected %s, got %s", template, expected, buf.String())
+		}
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	for _, template := range []string{`{.Name`, `{range .Subnets}`, `{end}`, `{.Subnets[x]}`} {
+		if _, err := ParseJSONPath(template); err == nil {
+			t.Errorf("expected parse error for %s", template)
+		}
+	}
+
+	j, _ := ParseJSONPath(`{.Missing}`)
+	if err := j.Execute(&bytes.Buffer{}, map[string]interface{}{}); err == nil {
+		t.Errorf("expected error for missing field")
+	}
+}
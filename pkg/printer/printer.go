@@ -0,0 +1,216 @@
+// Package printer renders inventory objects for people and scripts in a
+// variety of formats.
+package printer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+const (
+	FormatTable      = "table"
+	FormatWide       = "wide"
+	FormatJSON       = "json"
+	FormatYAML       = "yaml"
+	FormatCSV        = "csv"
+	FormatJSONPath   = "jsonpath"
+	FormatGoTemplate = "go-template"
+)
+
+// Formats lists the supported output formats.  JSONPath and Go template
+// formats take their template as an argument, eg. "jsonpath={.Name}".
+var Formats = []string{FormatTable, FormatWide, FormatJSON, FormatYAML, FormatCSV, FormatJSONPath + "=...", FormatGoTemplate + "=..."}
+
+// Printer writes objects to w
+type Printer interface {
+	Print(w io.Writer, obj interface{}) error
+}
+
+// Options selects the format used to print objects
+type Options struct {
+	// Format is one of Formats, defaulting to FormatTable
+	Format string
+
+	// Columns selects the columns shown in table, wide and csv output
+	Columns []string
+
+	// NoHeaders omits the header row from table and csv output
+	NoHeaders bool
+}
+
+// New returns a printer for the options given
+func New(opts *Options) (Printer, error) {
+	format, arg := opts.Format, ""
+	if i := strings.Index(format, "="); i >= 0 {
+		format, arg = format[:i], format[i+1:]
+	}
+
+	switch format {
+	case "", FormatTable:
+		return &TablePrinter{Columns: opts.Columns, NoHeaders: opts.NoHeaders}, nil
+	case FormatWide:
+		return &TablePrinter{Columns: opts.Columns, NoHeaders: opts.NoHeaders, Wide: true}, nil
+	case FormatCSV:
+		return &CSVPrinter{Columns: opts.Columns, NoHeaders: opts.NoHeaders}, nil
+	case FormatJSON:
+		return &JSONPrinter{}, nil
+	case FormatYAML:
+		return &YAMLPrinter{}, nil
+	case FormatJSONPath:
+		if arg == "" {
+			return nil, fmt.Errorf("jsonpath format requires a template, eg. jsonpath={.Name}")
+		}
+		j, err := ParseJSONPath(arg)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONPathPrinter{JSONPath: j}, nil
+	case FormatGoTemplate:
+		if arg == "" {
+			return nil, fmt.Errorf("go-template format requires a template, eg. go-template={{.Name}}")
+		}
+		t, err := template.New("output").Funcs(TemplateFuncs).Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse template: %v", err)
+		}
+		return &TemplatePrinter{Template: t}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format '%s', valid formats are: %s", opts.Format, strings.Join(Formats, ", "))
+	}
+}
+
+// TablePrinter prints objects as aligned columns
+type TablePrinter struct {
+	Columns   []string
+	NoHeaders bool
+	Wide      bool
+}
+
+func (p *TablePrinter) Print(w io.Writer, obj interface{}) error {
+	items, available, err := rows(obj)
+	if err != nil {
+		return err
+	}
+
+	columns, err := selectColumns(available, p.Columns, p.Wide)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	if !p.NoHeaders {
+		headers := make([]string, 0, len(columns))
+		for _, c := range columns {
+			headers = append(headers, c.Header)
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	}
+
+	for _, item := range items {
+		values := make([]string, 0, len(columns))
+		for _, c := range columns {
+			values = append(values, c.Value(item))
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+	return tw.Flush()
+}
+
+// CSVPrinter prints objects as comma separated values
+type CSVPrinter struct {
+	Columns   []string
+	NoHeaders bool
+}
+
+func (p *CSVPrinter) Print(w io.Writer, obj interface{}) error {
+	items, available, err := rows(obj)
+	if err != nil {
+		return err
+	}
+
+	columns, err := selectColumns(available, p.Columns, true)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if !p.NoHeaders {
+		headers := make([]string, 0, len(columns))
+		for _, c := range columns {
+			headers = append(headers, c.Name)
+		}
+		if err := cw.Write(headers); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		values := make([]string, 0, len(columns))
+		for _, c := range columns {
+			values = append(values, c.Value(item))
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// JSONPrinter prints objects as indented JSON
+type JSONPrinter struct{}
+
+func (p *JSONPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// YAMLPrinter prints objects as YAML
+type YAMLPrinter struct{}
+
+func (p *YAMLPrinter) Print(w io.Writer, obj interface{}) error {
+	data, err := ToYAML(obj)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// JSONPathPrinter prints the result of a JSONPath template
+type JSONPathPrinter struct {
+	JSONPath *JSONPath
+}
+
+func (p *JSONPathPrinter) Print(w io.Writer, obj interface{}) error {
+	return p.JSONPath.Execute(w, obj)
+}
+
+// TemplateFuncs are available to Go templates in addition to the builtins
+var TemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(obj interface{}) (string, error) {
+		data, err := json.Marshal(obj)
+		return string(data), err
+	},
+}
+
+// TemplatePrinter executes a Go template against the object.  Unlike JSONPath
+// the template sees the typed object, so methods such as Hostname are
+// available.
+type TemplatePrinter struct {
+	Template *template.Template
+}
+
+func (p *TemplatePrinter) Print(w io.Writer, obj interface{}) error {
+	return p.Template.Execute(w, obj)
+}
@@ -0,0 +1,139 @@
+package printer
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func testNodes() []*types.Node {
+	mac, _ := net.ParseMAC("00:01:02:03:04:05")
+	return []*types.Node{
+		{
+			InventoryID:     "sample-0001",
+			ChassisLocation: &types.ChassisLocation{Rack: "xx01", BottomU: 3},
+			System:          "tsys",
+			Role:            "worker",
+			Networks:        types.NICInfoMap{"provisioning": &types.NetworkInterface{NICs: []net.HardwareAddr{mac}}},
+		},
+		{InventoryID: "sample-0002", System: "tsys", Role: "login"},
+	}
+}
+
+func printString(t *testing.T, opts *Options, obj interface{}) string {
+	p, err := New(opts)
+	if err != nil {
+		t.Fatalf("unable to create printer: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = p.Print(buf, obj)
+	if err != nil {
+		t.Fatalf("unable to print: %v", err)
+	}
+	return buf.String()
+}
+
+func TestTablePrinter(t *testing.T) {
+	output := printString(t, &Options{}, testNodes())
+
+	expected := `HOSTNAME           INVENTORY ID   SYSTEM   ROLE     LOCATION
+tsys-xx01-03       sample-0001    tsys     worker   xx01-03
+tsys-sample-0002   sample-0002    tsys     login    
+`
+	if output != expected {
+		t.Errorf("got unexpected output:\n%s", output)
+	}
+}
+
+func TestWidePrinter(t *testing.T) {
+	output := printString(t, &Options{Format: FormatWide}, testNodes())
+	if !strings.Contains(output, "MACS") || !strings.Contains(output, "00:01:02:03:04:05") {
+		t.Errorf("wide output missing macs:\n%s", output)
+	}
+}
+
+func TestTablePrinterColumns(t *testing.T) {
+	output := printString(t, &Options{Columns: []string{"inventory-id", "macs"}, NoHeaders: true}, testNodes())
+
+	expected := `sample-0001   00:01:02:03:04:05
+sample-0002   
+`
+	if output != expected {
+		t.Errorf("got unexpected output:\n%s", output)
+	}
+
+	p, _ := New(&Options{Columns: []string{"ips"}})
+	if err := p.Print(&bytes.Buffer{}, testNodes()); err == nil {
+		t.Errorf("expected error for unknown column")
+	}
+}
+
+func TestCSVPrinter(t *testing.T) {
+	output := printString(t, &Options{Format: FormatCSV, Columns: []string{"hostname", "role"}}, testNodes())
+
+	expected := "hostname,role\ntsys-xx01-03,worker\ntsys-sample-0002,login\n"
+	if output != expected {
+		t.Errorf("got unexpected output:\n%s", output)
+	}
+}
+
+func TestYAMLPrinter(t *testing.T) {
+	output := printString(t, &Options{Format: FormatYAML}, testNodes()[0])
+	if !strings.Contains(output, `- "00:01:02:03:04:05"`) {
+		t.Errorf("mac not rendered as a string:\n%s", output)
+	}
+}
+
+func TestJSONPathPrinter(t *testing.T) {
+	output := printString(t, &Options{Format: `jsonpath={range [*]}{.InventoryID}{"\t"}{.Role}{"\n"}{end}`}, testNodes())
+
+	expected := "sample-0001\tworker\nsample-0002\tlogin\n"
+	if output != expected {
+		t.Errorf("got unexpected output:\n%s", output)
+	}
+}
+
+func TestGoTemplatePrinter(t *testing.T) {
+	output := printString(t, &Options{Format: `go-template={{range .}}{{.Hostname}} {{end}}`}, testNodes())
+
+	expected := "tsys-xx01-03 tsys-sample-0002 "
+	if output != expected {
+		t.Errorf("got unexpected output: %s", output)
+	}
+}
+
+func TestReservationTable(t *testing.T) {
+	mac, _ := net.ParseMAC("00:01:02:03:04:05")
+	ip, cidr, _ := net.ParseCIDR("10.0.0.2/24")
+	cidr.IP = ip
+	reservations := types.IPReservationList{{IP: cidr, MAC: mac, HostInformation: "sample-0001"}}
+
+	output := printString(t, &Options{NoHeaders: true}, reservations)
+	if !strings.HasPrefix(output, "10.0.0.2/24   00:01:02:03:04:05   sample-0001   static") {
+		t.Errorf("got unexpected output: %s", output)
+	}
+}
+
+func TestUtilizationTable(t *testing.T) {
+	report := &client.UtilizationReport{Threshold: 80, Subnets: []*client.SubnetUsage{
+		{Network: "provisioning", Cidr: "10.0.0.0/30", Total: 2, Reserved: 2, Static: 2, Utilization: 100, OverThreshold: true},
+	}}
+
+	output := printString(t, &Options{NoHeaders: true, Columns: []string{"network", "cidr", "free", "utilization", "overthreshold"}}, report)
+	if output != "provisioning   10.0.0.0/30   0   100.0%   yes\n" {
+		t.Errorf("got unexpected output: %q", output)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	for _, format := range []string{"xml", "jsonpath", "jsonpath={.Name", "go-template={{.Name"} {
+		if _, err := New(&Options{Format: format}); err == nil {
+			t.Errorf("expected error for format %s", format)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ToYAML renders obj as YAML.  The object is marshaled to JSON first so that
+// custom JSON marshalers, such as those for IP and MAC addresses, are honored.
+func ToYAML(obj interface{}) ([]byte, error) {
+	generic, err := toGeneric(obj)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// FromYAML decodes YAML or JSON data into obj using obj's JSON unmarshaler
+func FromYAML(data []byte, obj interface{}) error {
+	var generic interface{}
+	err := yaml.Unmarshal(data, &generic)
+	if err != nil {
+		return fmt.Errorf("unable to parse yaml: %v", err)
+	}
+
+	jsonData, err := json.Marshal(stringKeys(generic))
+	if err != nil {
+		return fmt.Errorf("unable to convert yaml to json: %v", err)
+	}
+	return json.Unmarshal(jsonData, obj)
+}
+
+// toGeneric converts obj to the maps, slices and scalars produced by
+// unmarshaling its JSON representation
+func toGeneric(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal object: %v", err)
+	}
+
+	var generic interface{}
+	err = json.Unmarshal(data, &generic)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal object: %v", err)
+	}
+	return generic, nil
+}
+
+// stringKeys replaces the map[interface{}]interface{} values produced by the
+// yaml decoder with map[string]interface{} so they can be marshaled to JSON
+func stringKeys(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			m[fmt.Sprintf("%v", k)] = stringKeys(item)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(value))
+		for i, item := range value {
+			l[i] = stringKeys(item)
+		}
+		return l
+	default:
+		return v
+	}
+}
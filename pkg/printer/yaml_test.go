@@ -0,0 +1,56 @@
+package printer
+
+import (
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+	data := []byte(`
+InventoryID: sample-0001
+Networks:
+  provisioning:
+    nics:
+      - "00:01:02:03:04:05"
+Metadata:
+  cpus: 32
+`)
+
+	node := &types.Node{}
+	err := FromYAML(data, node)
+	if err != nil {
+		t.Fatalf("unable to parse yaml: %v", err)
+	}
+
+	if node.Networks["provisioning"].NICs[0].String() != "00:01:02:03:04:05" {
+		t.Errorf("mac not parsed correctly: %v", node.Networks["provisioning"].NICs)
+	}
+
+	out, err := ToYAML(node)
+	if err != nil {
+		t.Fatalf("unable to render yaml: %v", err)
+	}
+
+	parsed := &types.Node{}
+	err = FromYAML(out, parsed)
+	if err != nil {
+		t.Fatalf("unable to parse rendered yaml: %v", err)
+	}
+
+	if parsed.InventoryID != "sample-0001" || parsed.Metadata["cpus"] != float64(32) {
+		t.Errorf("node not preserved: %v", parsed)
+	}
+}
+
+func TestFromYAMLAcceptsJSON(t *testing.T) {
+	network := &types.Network{}
+	err := FromYAML([]byte(`{"Name": "testnet", "MTU": 9000}`), network)
+	if err != nil {
+		t.Fatalf("unable to parse json: %v", err)
+	}
+
+	if network.Name != "testnet" || network.MTU != 9000 {
+		t.Errorf("network not parsed correctly: %v", network)
+	}
+}
@@ -0,0 +1,48 @@
+package render
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/spf13/viper"
+)
+
+// Config describes the templates kept up to date by a renderer
+type Config struct {
+	Profile   string
+	Interval  time.Duration
+	Templates []*Template
+}
+
+// LoadConfig reads a template renderer configuration file
+func LoadConfig(path string) (*Config, error) {
+	cfg := viper.New()
+	cfg.SetConfigFile(path)
+	cfg.SetDefault("interval", DefaultInterval)
+
+	err := cfg.ReadInConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error reading template configuration file: %v", err)
+	}
+
+	renderConfig := &Config{}
+	err = cfg.Unmarshal(renderConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling template configuration: %v", err)
+	}
+
+	for _, t := range renderConfig.Templates {
+		if t.Source == "" || t.Destination == "" {
+			return nil, fmt.Errorf("all templates must specify a source and destination")
+		}
+	}
+	return renderConfig, nil
+}
+
+// Renderer returns a Renderer for the configured templates
+func (c *Config) Renderer(inv *client.InventoryApi) *Renderer {
+	r := NewRenderer(inv, c.Templates...)
+	r.Interval = c.Interval
+	return r
+}
@@ -0,0 +1,63 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "template.yml")
+	err := ioutil.WriteFile(configFile, []byte(`
+profile: prod
+templates:
+  - source: /etc/inventory/templates/hosts.tmpl
+    destination: /etc/hosts
+    perms: 0600
+    command: systemctl reload dnsmasq
+    command_timeout: 5s
+`), 0644)
+	if err != nil {
+		t.Fatalf("unable to write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("unable to load config: %v", err)
+	}
+
+	if cfg.Profile != "prod" || cfg.Interval != DefaultInterval {
+		t.Errorf("got unexpected settings: %s, %s", cfg.Profile, cfg.Interval)
+	}
+	if len(cfg.Templates) != 1 {
+		t.Fatalf("templates not loaded correctly: %v", cfg.Templates)
+	}
+	tmpl := cfg.Templates[0]
+	if tmpl.Destination != "/etc/hosts" || tmpl.Perms != 0600 || tmpl.Command != "systemctl reload dnsmasq" || tmpl.CommandTimeout != 5*time.Second {
+		t.Errorf("template not loaded correctly: %+v", tmpl)
+	}
+
+	if r := cfg.Renderer(nil); r.Interval != DefaultInterval || len(r.Templates) != 1 {
+		t.Errorf("got unexpected renderer: %+v", r)
+	}
+}
+
+func TestLoadConfigMissingDestination(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "template.yml")
+	err := ioutil.WriteFile(configFile, []byte("templates:\n  - source: hosts.tmpl\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write config: %v", err)
+	}
+
+	if _, err := LoadConfig(configFile); err == nil {
+		t.Errorf("expected error for template without a destination")
+	}
+}
@@ -0,0 +1,240 @@
+// Package render renders Go text templates against the contents of the
+// inventory, rewriting the output files whenever the inventory changes.
+package render
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/cidr"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+// Funcs returns the helper functions available to templates rendered from s,
+// which are described in the README.  Reservations are only available if they
+// were loaded into the snapshot.  Subnet arguments may be strings, *net.IPNet
+// or *types.Subnet, and address arguments strings, net.IP, *net.IPNet or
+// *export.Address.
+func Funcs(s *export.Snapshot) template.FuncMap {
+	byFilter := func(f *export.NodeFilter) []*types.InventoryNode {
+		return f.Nodes(s)
+	}
+
+	return template.FuncMap{
+		"nodes": func() []*types.InventoryNode { return s.Nodes },
+		"nodesBySystem": func(name string) []*types.InventoryNode {
+			return byFilter(&export.NodeFilter{Systems: []string{name}})
+		},
+		"nodesByRole": func(role string) []*types.InventoryNode {
+			return byFilter(&export.NodeFilter{Roles: []string{role}})
+		},
+		"nodesByTag": func(tag string) []*types.InventoryNode {
+			return byFilter(&export.NodeFilter{Tags: []string{tag}})
+		},
+		"filterNodes": func(expr string) ([]*types.InventoryNode, error) {
+			f, err := export.ParseNodeFilter(expr)
+			if err != nil {
+				return nil, err
+			}
+			return byFilter(f), nil
+		},
+		"node": func(hostname string) *types.InventoryNode {
+			return export.FindNode(s.Nodes, hostname)
+		},
+		"network": s.Network,
+		"nics": func(network string) []*export.Address {
+			result := []*export.Address{}
+			for _, a := range s.Addresses() {
+				if a.Network.Name == network {
+					result = append(result, a)
+				}
+			}
+			return result
+		},
+		"nodeNics": func(hostname string) []*export.Address {
+			result := []*export.Address{}
+			for _, a := range s.Addresses() {
+				if a.Hostname == hostname {
+					result = append(result, a)
+				}
+			}
+			return result
+		},
+		"reservations": func(subnet interface{}) (types.IPReservationList, error) {
+			n, err := toIPNet(subnet)
+			if err != nil {
+				return nil, err
+			}
+			now := time.Now()
+			result := types.IPReservationList{}
+			for _, r := range s.Reservations {
+				if r.IP != nil && n.Contains(r.IP.IP) && r.ValidAt(now) {
+					result = append(result, r)
+				}
+			}
+			return result, nil
+		},
+		"hostlist": export.Hostlist,
+		"hostnames": func(nodes []*types.InventoryNode) []string {
+			names := make([]string, 0, len(nodes))
+			for _, node := range nodes {
+				names = append(names, node.Hostname)
+			}
+			return names
+		},
+		"cidr": toIPNet,
+		"cidrFirst": func(subnet interface{}) (net.IP, error) {
+			n, err := toIPNet(subnet)
+			if err != nil {
+				return nil, err
+			}
+			return cidr.First(n), nil
+		},
+		"cidrLast": func(subnet interface{}) (net.IP, error) {
+			n, err := toIPNet(subnet)
+			if err != nil {
+				return nil, err
+			}
+			return cidr.Last(n), nil
+		},
+		"cidrHost": cidrHost,
+		"cidrHostCount": func(subnet interface{}) (uint64, error) {
+			n, err := toIPNet(subnet)
+			if err != nil {
+				return 0, err
+			}
+			return cidr.HostCount(n), nil
+		},
+		"cidrNetmask": func(subnet interface{}) (string, error) {
+			n, err := toIPNet(subnet)
+			if err != nil {
+				return "", err
+			}
+			if !cidr.IsV4(n) {
+				return "", fmt.Errorf("%s is not an ipv4 subnet", n)
+			}
+			return net.IP(n.Mask).String(), nil
+		},
+		"cidrPrefixLen": func(subnet interface{}) (int, error) {
+			n, err := toIPNet(subnet)
+			if err != nil {
+				return 0, err
+			}
+			ones, _ := n.Mask.Size()
+			return ones, nil
+		},
+		"cidrContains": func(subnet, address interface{}) (bool, error) {
+			n, err := toIPNet(subnet)
+			if err != nil {
+				return false, err
+			}
+			ip, err := toIP(address)
+			if err != nil {
+				return false, err
+			}
+			return n.Contains(ip), nil
+		},
+		"ipNext": func(address interface{}) (net.IP, error) {
+			ip, err := toIP(address)
+			if err != nil {
+				return nil, err
+			}
+			return cidr.Next(ip), nil
+		},
+		"ipPrev": func(address interface{}) (net.IP, error) {
+			ip, err := toIP(address)
+			if err != nil {
+				return nil, err
+			}
+			return cidr.Prev(ip), nil
+		},
+		"reverseName": func(address interface{}) (string, error) {
+			ip, err := toIP(address)
+			if err != nil {
+				return "", err
+			}
+			return export.ReverseName(ip), nil
+		},
+		"join":  func(sep string, list []string) string { return strings.Join(list, sep) },
+		"split": func(sep, s string) []string { return strings.Split(s, sep) },
+	}
+}
+
+// cidrHost returns the nth address of subnet, counting back from the last
+// address if n is negative
+func cidrHost(n int, subnet interface{}) (net.IP, error) {
+	ipNet, err := toIPNet(subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := cidr.First(ipNet), cidr.Last(ipNet)
+	base, offset := first, big.NewInt(int64(n))
+	if n < 0 {
+		base, offset = last, offset.Add(offset, big.NewInt(1))
+	}
+
+	value := new(big.Int).Add(new(big.Int).SetBytes(base), offset)
+	if value.Cmp(new(big.Int).SetBytes(first)) < 0 || value.Cmp(new(big.Int).SetBytes(last)) > 0 {
+		return nil, fmt.Errorf("host %d is outside %s", n, ipNet)
+	}
+
+	ip := make(net.IP, len(first))
+	b := value.Bytes()
+	copy(ip[len(ip)-len(b):], b)
+	return ip, nil
+}
+
+// toIPNet converts a template argument to a subnet
+func toIPNet(v interface{}) (*net.IPNet, error) {
+	switch n := v.(type) {
+	case string:
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse cidr: %v", err)
+		}
+		return ipNet, nil
+	case *net.IPNet:
+		if n != nil {
+			return n, nil
+		}
+	case net.IPNet:
+		return &n, nil
+	case *types.Subnet:
+		if n != nil && n.Cidr != nil {
+			return n.Cidr, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to use %v as a subnet", v)
+}
+
+// toIP converts a template argument to an address
+func toIP(v interface{}) (net.IP, error) {
+	switch a := v.(type) {
+	case string:
+		if ip, _, err := net.ParseCIDR(a); err == nil {
+			return ip, nil
+		}
+		if ip := net.ParseIP(a); ip != nil {
+			return ip, nil
+		}
+	case net.IP:
+		if a != nil {
+			return a, nil
+		}
+	case *net.IPNet:
+		if a != nil {
+			return a.IP, nil
+		}
+	case *export.Address:
+		if a != nil {
+			return a.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to use %v as an address", v)
+}
@@ -0,0 +1,103 @@
+package render
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+func testSnapshot() *export.Snapshot {
+	_, prov, _ := net.ParseCIDR("10.0.0.0/24")
+	provNetwork := &types.Network{Name: "prov", Domain: "prov.example.com", Subnets: types.SubnetList{{Name: "prov", Cidr: prov}}}
+	system := &types.System{Name: "tsys"}
+
+	node := func(hostname, role, ip string, tags ...string) *types.InventoryNode {
+		return &types.InventoryNode{
+			Hostname: hostname,
+			Role:     role,
+			Tags:     tags,
+			System:   system,
+			Networks: map[string]*types.NICInstance{
+				"provisioning": {Network: *provNetwork, Config: types.NicConfig{IP: []string{ip + "/24"}}},
+			},
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	_, switchIP, _ := net.ParseCIDR("10.0.0.20/24")
+	_, otherIP, _ := net.ParseCIDR("10.9.0.20/24")
+	return &export.Snapshot{
+		Networks: []*types.Network{provNetwork},
+		Nodes: []*types.InventoryNode{
+			node("node-000", "worker", "10.0.0.10", "gpu"),
+			node("node-001", "worker", "10.0.0.11"),
+			node("node-002", "login", "10.0.0.12"),
+		},
+		Reservations: types.IPReservationList{
+			{HostInformation: "switch-01", IP: switchIP, Start: &start},
+			{HostInformation: "elsewhere", IP: otherIP, Start: &start},
+		},
+	}
+}
+
+func execute(t *testing.T, text string) string {
+	s := testSnapshot()
+	tmpl, err := template.New("test").Funcs(Funcs(s)).Parse(text)
+	if err != nil {
+		t.Fatalf("unable to parse %s: %v", text, err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, s); err != nil {
+		t.Fatalf("unable to execute %s: %v", text, err)
+	}
+	return buf.String()
+}
+
+func TestFuncs(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		expected string
+	}{
+		{`{{ range nodesByRole "worker" }}{{ .Hostname }} {{ end }}`, "node-000 node-001 "},
+		{`{{ nodesBySystem "tsys" | hostnames | hostlist }}`, "node-[000-002]"},
+		{`{{ nodesByTag "gpu" | hostnames | join "," }}`, "node-000"},
+		{`{{ filterNodes "role=worker,host=*1" | hostnames | join "," }}`, "node-001"},
+		{`{{ (node "node-002.prov.example.com").Role }}`, "login"},
+		{`{{ range nics "prov" }}{{ .FQDN }}={{ .IP }} {{ end }}`, "node-000.prov.example.com=10.0.0.10 node-001.prov.example.com=10.0.0.11 node-002.prov.example.com=10.0.0.12 "},
+		{`{{ range nodeNics "node-001" }}{{ .Interface }} {{ .Subnet.Name }}{{ end }}`, "provisioning prov"},
+		{`{{ range reservations (index (network "prov").Subnets 0) }}{{ .HostInformation }}{{ end }}`, "switch-01"},
+		{`{{ cidrFirst "10.0.0.0/24" }} {{ cidrLast "10.0.0.0/24" }} {{ cidrHostCount "10.0.0.0/24" }}`, "10.0.0.0 10.0.0.255 254"},
+		{`{{ cidrHost 1 "10.0.0.0/24" }} {{ cidrHost -2 "10.0.0.0/24" }} {{ cidrHost 16 "2001:db8::/64" }}`, "10.0.0.1 10.0.0.254 2001:db8::10"},
+		{`{{ cidrNetmask "10.0.0.0/22" }} {{ cidrPrefixLen "10.0.0.0/22" }}`, "255.255.252.0 22"},
+		{`{{ cidrContains "10.0.0.0/24" "10.0.0.9" }} {{ cidrContains "10.0.0.0/24" "10.0.1.9" }}`, "true false"},
+		{`{{ ipNext "10.0.0.255" }} {{ ipPrev "10.0.1.0/24" }}`, "10.0.1.0 10.0.0.255"},
+		{`{{ reverseName "10.0.0.10" }}`, "10.0.0.10.in-addr.arpa."},
+		{`{{ split "," "a,b" | join " " }}`, "a b"},
+	} {
+		if got := execute(t, tc.text); got != tc.expected {
+			t.Errorf("got unexpected output for %s: expected %q, got %q", tc.text, tc.expected, got)
+		}
+	}
+}
+
+func TestFuncErrors(t *testing.T) {
+	s := testSnapshot()
+	for _, text := range []string{
+		`{{ cidrHost 256 "10.0.0.0/24" }}`,
+		`{{ cidrHost -257 "10.0.0.0/24" }}`,
+		`{{ cidrNetmask "2001:db8::/64" }}`,
+		`{{ cidrFirst "10.0.0.0" }}`,
+		`{{ ipNext "host" }}`,
+		`{{ filterNodes "colour=blue" }}`,
+	} {
+		tmpl := template.Must(template.New("test").Funcs(Funcs(s)).Parse(text))
+		if err := tmpl.Execute(&bytes.Buffer{}, s); err == nil {
+			t.Errorf("expected error executing %s", text)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/export"
+	"github.com/PolarGeospatialCenter/inventory/pkg/inventory/types"
+)
+
+const (
+	// DefaultInterval is the time between polls of the inventory
+	DefaultInterval = time.Minute
+
+	// DefaultCommandTimeout limits how long a reload command may run
+	DefaultCommandTimeout = 30 * time.Second
+
+	// DefaultPerms is the mode of rendered files
+	DefaultPerms os.FileMode = 0644
+)
+
+// Template renders a single template file to its destination
+type Template struct {
+	// Source is the template file, which is read again on every render so
+	// changes are picked up without a restart
+	Source      string
+	Destination string
+
+	// Perms is the mode of the destination file, DefaultPerms if zero
+	Perms os.FileMode
+
+	// Command is run with sh -c after the destination changes
+	Command        string
+	CommandTimeout time.Duration `mapstructure:"command_timeout"`
+
+	// reloadPending is set while the last reload command failed
+	reloadPending bool
+}
+
+// ParseTemplate parses a source:destination[:command] template specification
+func ParseTemplate(spec string) (*Template, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid template %s, expected source:destination[:command]", spec)
+	}
+	t := &Template{Source: parts[0], Destination: parts[1]}
+	if len(parts) == 3 {
+		t.Command = parts[2]
+	}
+	return t, nil
+}
+
+// Render executes the template with the snapshot as its data and the
+// helpers returned by Funcs
+func (t *Template) Render(s *export.Snapshot) ([]byte, error) {
+	return t.render(s, Funcs(s))
+}
+
+func (t *Template) render(data interface{}, funcs template.FuncMap) ([]byte, error) {
+	text, err := ioutil.ReadFile(t.Source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read template: %v", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(t.Source)).Funcs(funcs).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("unable to execute template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Write renders the template and atomically replaces the destination,
+// running the reload command if its contents changed.  It returns true if
+// the destination changed.  The destination is left in place if the reload
+// command fails, and the command is retried on the next write.
+func (t *Template) Write(ctx context.Context, s *export.Snapshot) (bool, error) {
+	return t.write(ctx, s, Funcs(s))
+}
+
+func (t *Template) write(ctx context.Context, templateData interface{}, funcs template.FuncMap) (bool, error) {
+	data, err := t.render(templateData, funcs)
+	if err != nil {
+		return false, err
+	}
+
+	perms := t.Perms
+	if perms == 0 {
+		perms = DefaultPerms
+	}
+	changed, err := export.WriteFileAtomic(t.Destination, data, perms)
+	if err != nil {
+		return false, fmt.Errorf("unable to write %s: %v", t.Destination, err)
+	}
+
+	if changed || t.reloadPending {
+		err = t.Reload(ctx)
+		t.reloadPending = err != nil
+	}
+	return changed, err
+}
+
+// Reload runs the reload command, if there is one
+func (t *Template) Reload(ctx context.Context) error {
+	if t.Command == "" {
+		return nil
+	}
+
+	timeout := t.CommandTimeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "/bin/sh", "-c", t.Command).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("reload command for %s failed: %v: %s", t.Destination, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// Renderer keeps a set of templates up to date with the inventory
+type Renderer struct {
+	Inventory *client.InventoryApi
+	Templates []*Template
+
+	// Interval is the time between polls made by Run
+	Interval time.Duration
+
+	// OnRender is called after each template is written, and OnRefresh
+	// after each refresh made by Run, if set
+	OnRender  func(t *Template, changed bool, err error)
+	OnRefresh func(err error)
+}
+
+// NewRenderer returns a renderer with the default interval
+func NewRenderer(inv *client.InventoryApi, templates ...*Template) *Renderer {
+	return &Renderer{Inventory: inv, Templates: templates, Interval: DefaultInterval}
+}
+
+// Load fetches a snapshot of the inventory.  IP reservations are loaded by
+// Render when a template first uses them.
+func (r *Renderer) Load() (*export.Snapshot, error) {
+	return export.LoadSnapshot(r.Inventory)
+}
+
+// reservationLoader loads the snapshot's reservations the first time a
+// template uses them, since listing them enumerates every subnet
+type reservationLoader struct {
+	inv      *client.InventoryApi
+	snapshot *export.Snapshot
+	loaded   bool
+	err      error
+}
+
+func (l *reservationLoader) load() {
+	if l.loaded {
+		return
+	}
+	l.loaded = true
+	if err := l.snapshot.LoadReservations(l.inv); err != nil {
+		l.err = fmt.Errorf("unable to load reservations: %v", err)
+	}
+}
+
+// lazySnapshot is the template data used by Render, whose Reservations
+// method shadows the snapshot field and loads them on first use
+type lazySnapshot struct {
+	*export.Snapshot
+	loader *reservationLoader
+}
+
+func (s *lazySnapshot) Reservations() types.IPReservationList {
+	s.loader.load()
+	return s.Snapshot.Reservations
+}
+
+// Render writes every template from the snapshot.  A failing template
+// doesn't stop the others from being written.  The error of each template is
+// passed to OnRender, and the returned error only counts the failures.
+// Reservations missing from the snapshot are loaded the first time a template
+// uses them; if that fails, the templates are rendered without the missing
+// reservations and the error is returned.
+func (r *Renderer) Render(ctx context.Context, s *export.Snapshot) error {
+	var data interface{} = s
+	funcs := Funcs(s)
+	var loader *reservationLoader
+	if s.Reservations == nil && r.Inventory != nil {
+		loader = &reservationLoader{inv: r.Inventory, snapshot: s}
+		data = &lazySnapshot{Snapshot: s, loader: loader}
+		reservations := funcs["reservations"].(func(interface{}) (types.IPReservationList, error))
+		funcs["reservations"] = func(subnet interface{}) (types.IPReservationList, error) {
+			loader.load()
+			return reservations(subnet)
+		}
+	}
+
+	failed := 0
+	for _, t := range r.Templates {
+		changed, err := t.write(ctx, data, funcs)
+		if r.OnRender != nil {
+			r.OnRender(t, changed, err)
+		}
+		if err != nil {
+			failed++
+		}
+	}
+
+	errs := []string{}
+	if loader != nil && loader.err != nil {
+		errs = append(errs, loader.err.Error())
+	}
+	if failed > 0 {
+		errs = append(errs, fmt.Sprintf("%d of %d templates failed", failed, len(r.Templates)))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Refresh loads the inventory and writes every template.  Nothing is written
+// if the inventory can't be loaded.
+func (r *Renderer) Refresh(ctx context.Context) error {
+	s, err := r.Load()
+	if err != nil {
+		return err
+	}
+	return r.Render(ctx, s)
+}
+
+// Run refreshes the templates every interval until ctx is cancelled.  Since
+// files are only replaced when their contents change, reload commands only
+// run when the inventory data used by a template changes.
+func (r *Renderer) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		err := r.Refresh(ctx)
+		if r.OnRefresh != nil {
+			r.OnRefresh(err)
+		}
+	}
+}
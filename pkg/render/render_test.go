@@ -0,0 +1,226 @@
+package render
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PolarGeospatialCenter/inventory-client/pkg/api/client"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	gock "gopkg.in/h2non/gock.v1"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "render")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	return dir
+}
+
+func writeTemplate(t *testing.T, filename, text string) {
+	if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+		t.Fatalf("unable to write template: %v", err)
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate("hosts.tmpl:/etc/hosts:systemctl reload dnsmasq:now")
+	if err != nil {
+		t.Fatalf("unable to parse template: %v", err)
+	}
+	if tmpl.Source != "hosts.tmpl" || tmpl.Destination != "/etc/hosts" || tmpl.Command != "systemctl reload dnsmasq:now" {
+		t.Errorf("got unexpected template: %+v", tmpl)
+	}
+
+	for _, spec := range []string{"hosts.tmpl", ":/etc/hosts", "hosts.tmpl:"} {
+		if _, err := ParseTemplate(spec); err == nil {
+			t.Errorf("expected error parsing %s", spec)
+		}
+	}
+}
+
+func TestTemplateWrite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "workers.tmpl")
+	writeTemplate(t, source, `{{ nodesByRole "worker" | hostnames | hostlist }}`+"\n")
+	reloads := filepath.Join(dir, "reloads")
+	tmpl := &Template{Source: source, Destination: filepath.Join(dir, "workers"), Perms: 0600, Command: "echo reload >> " + reloads}
+
+	for i, expectChanged := range []bool{true, false} {
+		changed, err := tmpl.Write(context.Background(), testSnapshot())
+		if err != nil {
+			t.Fatalf("unable to write template: %v", err)
+		}
+		if changed != expectChanged {
+			t.Errorf("write %d: expected changed to be %t", i, expectChanged)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(tmpl.Destination)
+	if string(data) != "node-[000-001]\n" {
+		t.Errorf("got unexpected output: %s", data)
+	}
+	if info, err := os.Stat(tmpl.Destination); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("got unexpected destination mode: %v, %v", info, err)
+	}
+	if data, _ := ioutil.ReadFile(reloads); string(data) != "reload\n" {
+		t.Errorf("expected a single reload, got: %q", data)
+	}
+}
+
+func TestTemplateReloadRetried(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "nodes.tmpl")
+	writeTemplate(t, source, `{{ len .Nodes }}`)
+	marker := filepath.Join(dir, "ready")
+	tmpl := &Template{Source: source, Destination: filepath.Join(dir, "nodes"), Command: "test -e " + marker}
+
+	if changed, err := tmpl.Write(context.Background(), testSnapshot()); !changed || err == nil {
+		t.Fatalf("expected failed reload after change, got %t, %v", changed, err)
+	}
+
+	writeTemplate(t, marker, "")
+	if changed, err := tmpl.Write(context.Background(), testSnapshot()); changed || err != nil {
+		t.Errorf("expected successful reload retry without change, got %t, %v", changed, err)
+	}
+	if tmpl.reloadPending {
+		t.Errorf("reload still pending after success")
+	}
+}
+
+func TestRendererRender(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.tmpl")
+	writeTemplate(t, good, `{{ range nics "prov" }}{{ .IP }} {{ .Hostname }}{{ "\n" }}{{ end }}`)
+	bad := filepath.Join(dir, "bad.tmpl")
+	writeTemplate(t, bad, `{{ cidrHost 1000 "10.0.0.0/24" }}`)
+
+	rendered := map[string]bool{}
+	r := NewRenderer(nil,
+		&Template{Source: bad, Destination: filepath.Join(dir, "bad")},
+		&Template{Source: good, Destination: filepath.Join(dir, "good")},
+	)
+	r.OnRender = func(tmpl *Template, changed bool, err error) {
+		rendered[tmpl.Source] = changed && err == nil
+	}
+
+	if err := r.Render(context.Background(), testSnapshot()); err == nil {
+		t.Errorf("expected error from failing template")
+	}
+	if len(rendered) != 2 || rendered[bad] || !rendered[good] {
+		t.Errorf("got unexpected render results: %v", rendered)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad")); !os.IsNotExist(err) {
+		t.Errorf("failing template should not be written: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "good")); string(data) != "10.0.0.10 node-000\n10.0.0.11 node-001\n10.0.0.12 node-002\n" {
+		t.Errorf("got unexpected output: %s", data)
+	}
+}
+
+func TestRendererRefresh(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).Get("nodeconfig").Reply(http.StatusOK).
+		BodyString(`[{"Hostname": "node-000"}]`)
+	gock.New(testBaseUrl.String()).Get("network").Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24"}]}]`)
+	gock.New(testBaseUrl.String()).Get("ipam/ip").MatchParam("network", "prov").Reply(http.StatusOK).
+		BodyString(`[{"IP": "10.0.0.20/24", "HostInformation": "switch-01", "Start": "2019-01-01T00:00:00Z"}]`)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "reservations.tmpl")
+	writeTemplate(t, source, `{{ range reservations "10.0.0.0/24" }}{{ .HostInformation }}{{ end }}`)
+	field := filepath.Join(dir, "field.tmpl")
+	writeTemplate(t, field, `{{ len .Reservations }}`)
+
+	// the reservations are listed once, and shared by both templates
+	inv := client.NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")})
+	r := NewRenderer(inv,
+		&Template{Source: source, Destination: filepath.Join(dir, "reservations")},
+		&Template{Source: field, Destination: filepath.Join(dir, "field")},
+	)
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatalf("unable to refresh: %v", err)
+	}
+
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "reservations")); string(data) != "switch-01" {
+		t.Errorf("got unexpected output: %s", data)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "field")); string(data) != "1" {
+		t.Errorf("got unexpected output: %s", data)
+	}
+}
+
+func TestRendererRefreshWithoutReservations(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	// no template uses reservations, so ipam isn't queried even though the
+	// word appears in the template
+	gock.New(testBaseUrl.String()).Get("nodeconfig").Reply(http.StatusOK).
+		BodyString(`[{"Hostname": "node-000"}]`)
+	gock.New(testBaseUrl.String()).Get("network").Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24"}]}]`)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "nodes.tmpl")
+	writeTemplate(t, source, `{{/* no reservations here */}}{{ hostnames nodes | join "," }}{{ if false }}{{ "reservations" }}{{ end }}`)
+
+	inv := client.NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")})
+	r := NewRenderer(inv, &Template{Source: source, Destination: filepath.Join(dir, "nodes")})
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatalf("unable to refresh: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "nodes")); string(data) != "node-000" {
+		t.Errorf("got unexpected output: %s", data)
+	}
+}
+
+func TestRendererRefreshReservationsFailed(t *testing.T) {
+	gock.DisableNetworking()
+	defer gock.EnableNetworking()
+	defer gock.Off()
+	testBaseUrl, _ := url.Parse("https://inventory.api.local/v0/")
+
+	gock.New(testBaseUrl.String()).Get("nodeconfig").Reply(http.StatusOK).
+		BodyString(`[{"Hostname": "node-000"}]`)
+	gock.New(testBaseUrl.String()).Get("network").Reply(http.StatusOK).
+		BodyString(`[{"Name": "prov", "Subnets": [{"Name": "prov", "Cidr": "10.0.0.0/24"}]}]`)
+	gock.New(testBaseUrl.String()).Get("ipam/ip").MatchParam("network", "prov").Reply(http.StatusInternalServerError).
+		BodyString(`{"status": "Internal Server Error", "error": "oops"}`)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "reservations.tmpl")
+	writeTemplate(t, source, `{{ hostnames nodes | join "," }}:{{ len (reservations "10.0.0.0/24") }}`)
+
+	inv := client.NewInventoryApi(testBaseUrl, &aws.Config{Credentials: credentials.NewStaticCredentials("id", "secret", "token")})
+	r := NewRenderer(inv, &Template{Source: source, Destination: filepath.Join(dir, "reservations")})
+	if err := r.Refresh(context.Background()); err == nil || !strings.Contains(err.Error(), "unable to load reservations") {
+		t.Errorf("expected reservations error, got %v", err)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "reservations")); string(data) != "node-000:0" {
+		t.Errorf("got unexpected output: %s", data)
+	}
+}